@@ -9,8 +9,8 @@
     # Add the specific Go version and linter here
     pkgs.go_1_24
     pkgs.golangci-lint
-    # rsync is a runtime dependency required for the core file-slicing
-    # logic and for the integration tests to pass.
+    # rsync backs the optional rsync engine and lets the integration tests
+    # compare it with the native engine.
     pkgs.rsync
   ];
   # Sets environment variables in the workspace
@@ -0,0 +1,88 @@
+// file: cmd/repo-slice/commit.go
+package main
+
+import (
+	"flag"
+	"fmt"
+	"path"
+
+	"github.com/AlienHeadwars/repo-slice/internal/gitrepo"
+	"github.com/AlienHeadwars/repo-slice/internal/provenance"
+)
+
+// defaultCommitMessage is the message of a slice's commit, unless given.
+const defaultCommitMessage = "chore: Update repository slice"
+
+// commitOptions holds the options of the commit command.
+type commitOptions struct {
+	Slice  string // The slice directory to commit.
+	Source string // A directory in the repository to commit to.
+	// ChangedOutput is a file to append a "changed=true" or "changed=false"
+	// line to, in the format of a GitHub Actions output file.
+	ChangedOutput string
+	gitrepo.CommitOptions
+}
+
+// runCommit implements the commit command, which commits a slice directory to
+// a branch of the source repository without touching its working tree, so
+// that the branch can be pushed as it is.
+func runCommit(args []string, slicer Slicer) error {
+	opts, err := parseCommitArgs(args)
+	if err != nil {
+		return err
+	}
+	commit, changed, err := slicer.Commit(opts.Source, opts.Slice, opts.CommitOptions)
+	if err != nil {
+		return fmt.Errorf("failed to commit slice: %w", err)
+	}
+	printCommit(opts.Slice, opts.Branch, commit, changed)
+	if opts.ChangedOutput != "" {
+		return appendOutput(opts.ChangedOutput, "changed", changed)
+	}
+	return nil
+}
+
+// printCommit reports the result of committing the slice directory dir to
+// branch.
+func printCommit(dir, branch, commit string, changed bool) {
+	if changed {
+		fmt.Fprintf(stdout, "Committed %s to branch %s as %s\n", dir, branch, commit)
+	} else {
+		fmt.Fprintf(stdout, "Branch %s already holds %s at %s; nothing to commit\n", branch, dir, commit)
+	}
+}
+
+// parseCommitArgs parses the arguments of the commit command. The single
+// positional argument is the slice directory.
+func parseCommitArgs(args []string) (commitOptions, error) {
+	var opts commitOptions
+	fs := flag.NewFlagSet("repo-slice commit", flag.ContinueOnError)
+
+	fs.StringVar(&opts.Branch, "branch", "", "The branch to commit the slice to (required)")
+	fs.StringVar(&opts.Source, "source", ".", "A directory in the repository to commit to")
+	fs.StringVar(&opts.Message, "message", defaultCommitMessage, "The commit message")
+	fs.BoolVar(&opts.Orphan, "orphan", false, "Make a commit without a parent instead of adding to the branch's history")
+	fs.StringVar(&opts.ChangedOutput, "changed-output", "", "Append changed=true or changed=false to this file, such as $GITHUB_OUTPUT")
+	fs.StringVar(&opts.Ignore, "provenance", provenance.DefaultPath, "Path inside the slice of its provenance record, whose changes alone make no commit, or 'off'")
+
+	if err := fs.Parse(args); err != nil {
+		return commitOptions{}, err
+	}
+	if opts.Branch == "" {
+		return commitOptions{}, fmt.Errorf("--branch is required")
+	}
+	if fs.NArg() != 1 {
+		return commitOptions{}, fmt.Errorf("commit takes exactly one slice directory")
+	}
+	opts.Slice = fs.Arg(0)
+	// The record changes on every run, recording the new source commit and
+	// time, so comparing it would make a commit even when nothing else did.
+	if opts.Ignore == provenance.Off {
+		opts.Ignore = ""
+	} else if err := provenance.CheckPath(opts.Ignore); err != nil {
+		return commitOptions{}, err
+	} else {
+		opts.Ignore = path.Clean(opts.Ignore)
+	}
+	return opts, nil
+}
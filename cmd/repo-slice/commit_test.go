@@ -0,0 +1,186 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/AlienHeadwars/repo-slice/internal/gitrepo"
+	"github.com/AlienHeadwars/repo-slice/internal/provenance"
+)
+
+func TestRunCommit(t *testing.T) {
+	testCases := []struct {
+		name     string
+		args     []string
+		slicer   *mockSlicer
+		wantRepo []string
+		wantOpts gitrepo.CommitOptions
+		want     string
+		wantErr  bool
+	}{
+		{
+			name:     "Defaults",
+			args:     []string{"commit", "--branch", "context/dev", "out"},
+			slicer:   &mockSlicer{},
+			wantRepo: []string{".", "out"},
+			wantOpts: gitrepo.CommitOptions{Branch: "context/dev", Message: defaultCommitMessage, Ignore: provenance.DefaultPath},
+			want:     "Committed out to branch context/dev as 0123abcd\n",
+		},
+		{
+			name:     "Unchanged",
+			args:     []string{"commit", "--branch", "context/dev", "out"},
+			slicer:   &mockSlicer{unchanged: true},
+			wantRepo: []string{".", "out"},
+			wantOpts: gitrepo.CommitOptions{Branch: "context/dev", Message: defaultCommitMessage, Ignore: provenance.DefaultPath},
+			want:     "Branch context/dev already holds out at 0123abcd; nothing to commit\n",
+		},
+		{
+			name:     "All options",
+			args:     []string{"commit", "--branch", "ai", "--source", "repo", "--message", "Refresh", "--orphan", "--provenance", "./meta/record.json", "out"},
+			slicer:   &mockSlicer{},
+			wantRepo: []string{"repo", "out"},
+			wantOpts: gitrepo.CommitOptions{Branch: "ai", Message: "Refresh", Orphan: true, Ignore: "meta/record.json"},
+			want:     "Committed out to branch ai as 0123abcd\n",
+		},
+		{
+			name:     "Provenance off",
+			args:     []string{"commit", "--branch", "ai", "--provenance", "off", "out"},
+			slicer:   &mockSlicer{},
+			wantRepo: []string{".", "out"},
+			wantOpts: gitrepo.CommitOptions{Branch: "ai", Message: defaultCommitMessage},
+			want:     "Committed out to branch ai as 0123abcd\n",
+		},
+		{"Invalid provenance", []string{"commit", "--branch", "ai", "--provenance", "../record.json", "out"}, &mockSlicer{}, nil, gitrepo.CommitOptions{}, "", true},
+		{"Missing branch", []string{"commit", "out"}, &mockSlicer{}, nil, gitrepo.CommitOptions{}, "", true},
+		{"Missing slice", []string{"commit", "--branch", "ai"}, &mockSlicer{}, nil, gitrepo.CommitOptions{}, "", true},
+		{"Commit fails", []string{"commit", "--branch", "ai", "out"}, &mockSlicer{commitErr: errors.New("not a repository")}, []string{".", "out"}, gitrepo.CommitOptions{Branch: "ai", Message: defaultCommitMessage, Ignore: provenance.DefaultPath}, "", true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var out bytes.Buffer
+			stdout = &out
+			t.Cleanup(func() { stdout = os.Stdout })
+
+			err := run(tc.args, &mockFS{}, tc.slicer, &mockRemapper{})
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("run() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if !reflect.DeepEqual(tc.slicer.committed, tc.wantRepo) || tc.slicer.commitOpts != tc.wantOpts {
+				t.Errorf("Commit() called with %v, %+v; want %v, %+v", tc.slicer.committed, tc.slicer.commitOpts, tc.wantRepo, tc.wantOpts)
+			}
+			if out.String() != tc.want {
+				t.Errorf("output = %q, want %q", out.String(), tc.want)
+			}
+		})
+	}
+}
+
+func TestRunCommitChangedOutput(t *testing.T) {
+	var out bytes.Buffer
+	stdout = &out
+	t.Cleanup(func() { stdout = os.Stdout })
+	name := filepath.Join(t.TempDir(), "github_output")
+	if err := os.WriteFile(name, []byte("tokens=42\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	args := []string{"commit", "--branch", "ai", "--changed-output", name, "out"}
+	for _, s := range []*mockSlicer{{}, {unchanged: true}} {
+		if err := run(args, &mockFS{}, s, &mockRemapper{}); err != nil {
+			t.Fatalf("run() returned an unexpected error: %v", err)
+		}
+	}
+	if got, _ := os.ReadFile(name); string(got) != "tokens=42\nchanged=true\nchanged=false\n" {
+		t.Errorf("changed output = %q, want both results appended", got)
+	}
+	if err := run([]string{"commit", "--branch", "ai", "--changed-output", t.TempDir(), "out"}, &mockFS{}, &mockSlicer{}, &mockRemapper{}); err == nil {
+		t.Error("run() did not report a changed output it could not write")
+	}
+}
+
+// TestRunCommitIntegration slices a repository and commits the slice to a
+// branch of it, leaving its working tree as it was, then slices it again and
+// leaves the branch alone.
+func TestRunCommitIntegration(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	for _, kv := range [][2]string{{"GIT_CONFIG_GLOBAL", os.DevNull}, {"GIT_CONFIG_NOSYSTEM", "1"}, {"GIT_AUTHOR_NAME", "test"}, {"GIT_AUTHOR_EMAIL", "test@example.com"}, {"GIT_COMMITTER_NAME", "test"}, {"GIT_COMMITTER_EMAIL", "test@example.com"}} {
+		t.Setenv(kv[0], kv[1])
+	}
+	repo := t.TempDir()
+	git := func(args ...string) string {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repo
+		out, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("git %s: %v", strings.Join(args, " "), err)
+		}
+		return strings.TrimSpace(string(out))
+	}
+	git("init", "--quiet", "--initial-branch=main")
+	for name, data := range map[string]string{"src/app.go": "package app\n", "notes.txt": "notes\n", "manifest.txt": "+ src/\n+ *.go\n- *\n"} {
+		file := filepath.Join(repo, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	git("add", ".")
+	git("commit", "--quiet", "-m", "Initial commit")
+
+	var out bytes.Buffer
+	stdout = &out
+	t.Cleanup(func() { stdout = os.Stdout })
+	// publish slices the repository and commits the slice, returning the
+	// commit command's output.
+	publish := func() string {
+		t.Helper()
+		output := filepath.Join(t.TempDir(), "slice")
+		args := []string{flagManifest, filepath.Join(repo, "manifest.txt"), flagSource, repo, flagOutput, output}
+		if err := run(args, &liveFS{}, &liveSlicer{}, &liveRemapper{}); err != nil {
+			t.Fatalf("run() failed: %v", err)
+		}
+		out.Reset()
+		if err := run([]string{"commit", "--branch", "context/app", "--source", repo, "--orphan", output}, &liveFS{}, &liveSlicer{}, &liveRemapper{}); err != nil {
+			t.Fatalf("run() commit failed: %v", err)
+		}
+		return out.String()
+	}
+
+	if got := publish(); !strings.HasPrefix(got, "Committed ") {
+		t.Errorf("first commit printed %q", got)
+	}
+	first := git("rev-parse", "context/app")
+	if got := git("ls-tree", "-r", "--name-only", "context/app"); got != provenance.DefaultPath+"\nsrc/app.go" {
+		t.Errorf("branch holds %q, want the record and src/app.go", got)
+	}
+
+	// A new source commit changes the record, but not the sliced files.
+	if err := os.WriteFile(filepath.Join(repo, "notes.txt"), []byte("more notes\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	git("commit", "--quiet", "-am", "Update notes")
+	if got := publish(); !strings.Contains(got, "nothing to commit") {
+		t.Errorf("unchanged slice printed %q", got)
+	}
+	if got := git("rev-parse", "context/app"); got != first {
+		t.Errorf("unchanged slice moved the branch from %s to %s", first, got)
+	}
+	if got := git("log", "-1", "--format=%s", "context/app"); got != defaultCommitMessage {
+		t.Errorf("commit message = %q, want %q", got, defaultCommitMessage)
+	}
+	if status := git("status", "--porcelain"); status != "" {
+		t.Errorf("commit changed the working tree:\n%s", status)
+	}
+}
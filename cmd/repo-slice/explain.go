@@ -0,0 +1,81 @@
+// file: cmd/repo-slice/explain.go
+package main
+
+import (
+	"flag"
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/AlienHeadwars/repo-slice/internal/filter"
+)
+
+// runExplain implements the explain command, which reports the manifest rule
+// that decides whether each path is part of the slice.
+func runExplain(args []string, fsys FileSystem, slicer Slicer) error {
+	cfg, paths, err := parseExplainArgs(args)
+	if err != nil {
+		return err
+	}
+
+	if err := fsys.ValidateInputs(validationConfig(cfg)); err != nil {
+		return err
+	}
+
+	decisions, err := slicer.Explain(sliceOptions(cfg), paths)
+	if err != nil {
+		return fmt.Errorf("failed to explain manifest: %w", err)
+	}
+
+	tw := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	for _, d := range decisions {
+		fmt.Fprintln(tw, formatDecision(d))
+	}
+	return tw.Flush()
+}
+
+// formatDecision renders a decision as a tab-separated line of outcome, path
+// and reason, suitable for a tabwriter.
+func formatDecision(d filter.Decision) string {
+	outcome := "-"
+	if d.Included {
+		outcome = "+"
+	}
+	name := d.Path
+	if d.IsDir {
+		name += "/"
+	}
+
+	var reason string
+	switch {
+	case d.Parent != "":
+		reason = fmt.Sprintf("parent %s/ excluded by %s: %s", d.Parent, d.Rule.Location(), d.Rule.Text)
+		if d.Own != nil && d.Own.Action == filter.Include {
+			reason += fmt.Sprintf(" (would be included by %s: %s; include its parent directories, e.g. with \"+ **/\")", d.Own.Location(), d.Own.Text)
+		}
+	case d.Rule != nil:
+		reason = fmt.Sprintf("%s: %s", d.Rule.Location(), d.Rule.Text)
+	default:
+		reason = "no rule matched; included by default"
+	}
+	return fmt.Sprintf("%s %s\t%s", outcome, name, reason)
+}
+
+// parseExplainArgs parses the arguments of the explain command. Any positional
+// arguments are the paths to explain, relative to the source directory.
+func parseExplainArgs(args []string) (Config, []string, error) {
+	var cfg Config
+	fs := flag.NewFlagSet("repo-slice explain", flag.ContinueOnError)
+
+	fs.StringVar(&cfg.ManifestPath, "manifest", "", "Path to manifest file (required)")
+	fs.StringVar(&cfg.SourcePath, "source", ".", "Source directory")
+	fs.StringVar(&cfg.ManifestFormat, "manifest-format", "", manifestFormatUsage)
+	fs.BoolVar(&cfg.Gitignore, "gitignore", false, gitignoreUsage)
+	fs.BoolVar(&cfg.TrackedOnly, "tracked-only", false, trackedOnlyUsage)
+	fs.StringVar(&cfg.Ref, "ref", "", refUsage)
+
+	if err := fs.Parse(args); err != nil {
+		return Config{}, nil, err
+	}
+
+	return cfg, fs.Args(), nil
+}
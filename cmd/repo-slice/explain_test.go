@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/AlienHeadwars/repo-slice/internal/filter"
+)
+
+func TestRunExplainUnit(t *testing.T) {
+	validArgs := []string{"explain", flagManifest, "m.txt", flagSource, "s"}
+
+	testCases := []struct {
+		name    string
+		args    []string
+		fs      FileSystem
+		slicer  Slicer
+		wantErr bool
+	}{
+		{"Argument parsing fails", []string{"explain", "--bad-flag"}, &mockFS{}, &mockSlicer{}, true},
+		{"Validation fails", validArgs, &mockFS{validateErr: errors.New("validation failed")}, &mockSlicer{}, true},
+		{"Explain fails", validArgs, &mockFS{}, &mockSlicer{explainErr: errors.New("explain failed")}, true},
+		{"Successful explain", validArgs, &mockFS{}, &mockSlicer{}, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := run(tc.args, tc.fs, tc.slicer, &mockRemapper{})
+			if (err != nil) != tc.wantErr {
+				t.Errorf("run() error = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestFormatDecision(t *testing.T) {
+	include := &filter.Rule{Action: filter.Include, File: "m.txt", Line: 1, Text: "+ *.go"}
+	exclude := &filter.Rule{Action: filter.Exclude, File: "m.txt", Line: 2, Text: "- *"}
+
+	testCases := []struct {
+		name     string
+		decision filter.Decision
+		want     string
+	}{
+		{"Included by rule", filter.Decision{Path: "main.go", Included: true, Rule: include}, "+ main.go\tm.txt:1: + *.go"},
+		{"Excluded directory", filter.Decision{Path: "docs", IsDir: true, Rule: exclude}, "- docs/\tm.txt:2: - *"},
+		{"Default include", filter.Decision{Path: "notes", Included: true}, "+ notes\tno rule matched; included by default"},
+		{"Blocked by parent", filter.Decision{Path: "src/a.go", Rule: exclude, Parent: "src", Own: include},
+			"- src/a.go\tparent src/ excluded by m.txt:2: - * (would be included by m.txt:1: + *.go; include its parent directories, e.g. with \"+ **/\")"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := formatDecision(tc.decision); got != tc.want {
+				t.Errorf("formatDecision() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+// TestRunExplainIntegration explains a nested file hidden by a missing
+// directory traversal rule in a real source tree.
+func TestRunExplainIntegration(t *testing.T) {
+	var out bytes.Buffer
+	stdout = &out
+	t.Cleanup(func() { stdout = os.Stdout })
+
+	rootDir := t.TempDir()
+	sourceDir := filepath.Join(rootDir, "source")
+	if err := os.MkdirAll(filepath.Join(sourceDir, "src"), 0755); err != nil {
+		t.Fatalf("failed to create source dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(sourceDir, "src", "app.go"), []byte(""), 0644); err != nil {
+		t.Fatalf("failed to create app.go: %v", err)
+	}
+	manifestPath := filepath.Join(rootDir, "manifest.txt")
+	if err := os.WriteFile(manifestPath, []byte("+ *.go\n- *"), 0644); err != nil {
+		t.Fatalf("failed to create manifest file: %v", err)
+	}
+
+	args := []string{"explain", flagManifest, manifestPath, flagSource, sourceDir, "src/app.go"}
+	if err := run(args, &liveFS{}, &liveSlicer{}, &liveRemapper{}); err != nil {
+		t.Fatalf("run() failed on explain: %v", err)
+	}
+
+	got := out.String()
+	if !strings.HasPrefix(got, "- src/app.go") || !strings.Contains(got, "parent src/ excluded by "+manifestPath+":2") {
+		t.Errorf("unexpected explain output:\n%s", got)
+	}
+}
@@ -0,0 +1,126 @@
+// file: cmd/repo-slice/inspect.go
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/AlienHeadwars/repo-slice/internal/provenance"
+)
+
+// inspectOptions holds the options of the inspect command.
+type inspectOptions struct {
+	Target     string // A slice directory, a record file, or "-" for standard input.
+	Provenance string // The record's path inside a slice directory.
+	JSON       bool
+}
+
+// runInspect implements the inspect command, which reports how a slice was
+// made from the provenance record written into it.
+func runInspect(args []string) error {
+	opts, err := parseInspectArgs(args)
+	if err != nil {
+		return err
+	}
+	rec, err := readProvenance(opts)
+	if err != nil {
+		return err
+	}
+
+	if opts.JSON {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(rec)
+	}
+
+	commit := rec.Source.Commit
+	switch {
+	case commit == "":
+		commit = "unknown (not a git repository)"
+	case rec.Source.Dirty:
+		commit += " (with uncommitted changes)"
+	}
+	manifest := "sha256:" + rec.Manifest.SHA256
+	if rec.Manifest.Path != "" {
+		manifest = rec.Manifest.Path + " (" + manifest + ")"
+	}
+
+	tw := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "Source commit:\t%s\n", commit)
+	if rec.Source.Ref != "" {
+		fmt.Fprintf(tw, "Source ref:\t%s\n", rec.Source.Ref)
+	}
+	fmt.Fprintf(tw, "Manifest:\t%s\n", manifest)
+	if len(rec.ExtensionMap) > 0 {
+		fmt.Fprintf(tw, "Extension map:\t%s\n", formatExtensionMap(rec.ExtensionMap))
+	}
+	fmt.Fprintf(tw, "Files:\t%d files, %d bytes\n", rec.Files, rec.Bytes)
+	if rec.Created.IsZero() {
+		fmt.Fprintf(tw, "Created by:\trepo-slice %s\n", rec.Version)
+	} else {
+		fmt.Fprintf(tw, "Created:\t%s by repo-slice %s\n", rec.Created.Format(time.RFC3339), rec.Version)
+	}
+	return tw.Flush()
+}
+
+// readProvenance reads the record the options name.
+func readProvenance(opts inspectOptions) (provenance.Record, error) {
+	if opts.Target == stdoutPath {
+		return provenance.Read(stdin)
+	}
+	name := opts.Target
+	if info, err := os.Stat(name); err == nil && info.IsDir() {
+		name = filepath.Join(name, filepath.FromSlash(opts.Provenance))
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		return provenance.Record{}, fmt.Errorf("failed to read provenance: %w", err)
+	}
+	defer f.Close()
+	rec, err := provenance.Read(f)
+	if err != nil {
+		return provenance.Record{}, fmt.Errorf("%s: %w", name, err)
+	}
+	return rec, nil
+}
+
+// formatExtensionMap renders an extension map in the form the --extension-map
+// flag accepts.
+func formatExtensionMap(extMap map[string]string) string {
+	pairs := make([]string, 0, len(extMap))
+	for from, to := range extMap {
+		pairs = append(pairs, strings.TrimPrefix(from, ".")+":"+strings.TrimPrefix(to, "."))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// parseInspectArgs parses the arguments of the inspect command. The optional
+// positional argument is the slice, or record, to inspect.
+func parseInspectArgs(args []string) (inspectOptions, error) {
+	var opts inspectOptions
+	fs := flag.NewFlagSet("repo-slice inspect", flag.ContinueOnError)
+
+	fs.StringVar(&opts.Provenance, "provenance", provenance.DefaultPath, "Path of the record inside the slice directory")
+	fs.BoolVar(&opts.JSON, "json", false, "Print the record as JSON")
+
+	if err := fs.Parse(args); err != nil {
+		return inspectOptions{}, err
+	}
+	switch fs.NArg() {
+	case 0:
+		opts.Target = "."
+	case 1:
+		opts.Target = fs.Arg(0)
+	default:
+		return inspectOptions{}, fmt.Errorf("inspect takes at most one slice directory or provenance file")
+	}
+	return opts, nil
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AlienHeadwars/repo-slice/internal/provenance"
+)
+
+func TestRunInspect(t *testing.T) {
+	rec := provenance.Record{
+		Schema:       provenance.Schema,
+		Version:      "v1.2.5",
+		Created:      time.Unix(1700000000, 0).UTC(),
+		Source:       provenance.Source{Commit: "0123456789abcdef0123456789abcdef01234567", Dirty: true, Ref: "origin/main"},
+		Manifest:     provenance.Manifest{Path: "allow-list.txt", SHA256: "abc123"},
+		ExtensionMap: map[string]string{".tsx": ".ts", ".jsx": ".js"},
+		Files:        3,
+		Bytes:        1024,
+	}
+	dir := t.TempDir()
+	if err := provenance.Write(dir, provenance.DefaultPath, rec); err != nil {
+		t.Fatal(err)
+	}
+	if err := provenance.Write(dir, "meta/slice.json", provenance.Record{Schema: provenance.Schema, Manifest: provenance.Manifest{SHA256: "def456"}}); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, ".repo-slice", "provenance.json"))
+
+	want := "Source commit:  0123456789abcdef0123456789abcdef01234567 (with uncommitted changes)\n" +
+		"Source ref:     origin/main\n" +
+		"Manifest:       allow-list.txt (sha256:abc123)\n" +
+		"Extension map:  jsx:js,tsx:ts\n" +
+		"Files:          3 files, 1024 bytes\n" +
+		"Created:        2023-11-14T22:13:20Z by repo-slice v1.2.5\n"
+
+	testCases := []struct {
+		name     string
+		args     []string
+		want     string
+		contains string
+		wantErr  bool
+	}{
+		{"Slice directory", []string{"inspect", dir}, want, "", false},
+		{"Record file", []string{"inspect", filepath.Join(dir, ".repo-slice", "provenance.json")}, want, "", false},
+		{"Standard input", []string{"inspect", "-"}, want, "", false},
+		{"Custom path", []string{"inspect", "--provenance", "meta/slice.json", dir}, "", "sha256:def456", false},
+		{"Unknown creation time", []string{"inspect", "--provenance", "meta/slice.json", dir}, "", "Created by:", false},
+		{"JSON", []string{"inspect", "--json", dir}, "", `"commit": "0123456789abcdef0123456789abcdef01234567"`, false},
+		{"Missing record", []string{"inspect", t.TempDir()}, "", "", true},
+		{"Too many arguments", []string{"inspect", dir, dir}, "", "", true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var out bytes.Buffer
+			stdout, stdin = &out, bytes.NewReader(data)
+			t.Cleanup(func() { stdout, stdin = os.Stdout, os.Stdin })
+
+			err := run(tc.args, &mockFS{}, &mockSlicer{}, &mockRemapper{})
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("run() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if tc.want != "" && out.String() != tc.want {
+				t.Errorf("inspect output = %q, want %q", out.String(), tc.want)
+			}
+			if !strings.Contains(out.String(), tc.contains) {
+				t.Errorf("inspect output = %q, want it to contain %q", out.String(), tc.contains)
+			}
+		})
+	}
+}
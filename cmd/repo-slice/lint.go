@@ -0,0 +1,109 @@
+// file: cmd/repo-slice/lint.go
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/AlienHeadwars/repo-slice/internal/filter"
+	"github.com/AlienHeadwars/repo-slice/internal/lint"
+	"github.com/AlienHeadwars/repo-slice/internal/slicer"
+)
+
+// Output formats supported by the lint command.
+const (
+	lintFormatText   = "text"
+	lintFormatJSON   = "json"
+	lintFormatGitHub = "github"
+)
+
+// lintConfig holds the options of the lint command.
+type lintConfig struct {
+	Config
+	Format string
+	Strict bool
+}
+
+// runLint implements the lint command, which reports common manifest mistakes
+// without creating a slice.
+func runLint(args []string, fsys FileSystem) error {
+	cfg, err := parseLintArgs(args)
+	if err != nil {
+		return err
+	}
+
+	if err := fsys.ValidateInputs(validationConfig(cfg.Config)); err != nil {
+		return err
+	}
+
+	diags, err := lint.Manifest(cfg.ManifestPath, cfg.SourcePath, filter.Format(cfg.ManifestFormat), filter.LiveFS{}, slicer.NewDirFS(cfg.SourcePath))
+	if err != nil {
+		return fmt.Errorf("failed to lint manifest: %w", err)
+	}
+	if err := writeDiagnostics(stdout, cfg.Format, diags); err != nil {
+		return err
+	}
+
+	if cfg.Strict && len(diags) > 0 {
+		return fmt.Errorf("manifest %s has %d warning(s)", cfg.ManifestPath, len(diags))
+	}
+	return nil
+}
+
+// writeDiagnostics renders the diagnostics in the requested format.
+func writeDiagnostics(w io.Writer, format string, diags []lint.Diagnostic) error {
+	switch format {
+	case lintFormatText:
+		for _, d := range diags {
+			fmt.Fprintln(w, d)
+		}
+	case lintFormatJSON:
+		if diags == nil {
+			diags = []lint.Diagnostic{}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(diags)
+	case lintFormatGitHub:
+		for _, d := range diags {
+			fmt.Fprintf(w, "::warning file=%s,line=%d,title=%s::%s\n",
+				escapeProperty(d.File), d.Line, escapeProperty(d.Check), escapeData(d.Message))
+		}
+	default:
+		return fmt.Errorf("unknown lint format %q: must be %s, %s or %s", format, lintFormatText, lintFormatJSON, lintFormatGitHub)
+	}
+	return nil
+}
+
+// escapeData escapes a workflow command message as the GitHub Actions runner
+// expects, so that multi-line or percent-bearing messages survive intact.
+func escapeData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+// escapeProperty escapes a workflow command property value, which must also
+// not contain the separators used between properties.
+func escapeProperty(s string) string {
+	return strings.NewReplacer(":", "%3A", ",", "%2C").Replace(escapeData(s))
+}
+
+// parseLintArgs parses the arguments of the lint command.
+func parseLintArgs(args []string) (lintConfig, error) {
+	var cfg lintConfig
+	fs := flag.NewFlagSet("repo-slice lint", flag.ContinueOnError)
+
+	fs.StringVar(&cfg.ManifestPath, "manifest", "", "Path to manifest file (required)")
+	fs.StringVar(&cfg.SourcePath, "source", ".", "Source directory")
+	fs.StringVar(&cfg.ManifestFormat, "manifest-format", "", manifestFormatUsage)
+	fs.StringVar(&cfg.Format, "format", lintFormatText, "Output format: text, json or github")
+	fs.BoolVar(&cfg.Strict, "strict", false, "Exit with an error if any warning is reported")
+
+	if err := fs.Parse(args); err != nil {
+		return lintConfig{}, err
+	}
+
+	return cfg, nil
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/AlienHeadwars/repo-slice/internal/lint"
+)
+
+func TestWriteDiagnostics(t *testing.T) {
+	diags := []lint.Diagnostic{
+		{File: "m.txt", Line: 2, Check: lint.CheckNoMatch, Message: "pattern \"*.rs\" matches 100% of nothing"},
+	}
+
+	testCases := []struct {
+		name    string
+		format  string
+		diags   []lint.Diagnostic
+		want    string
+		wantErr bool
+	}{
+		{"Text", lintFormatText, diags, "m.txt:2: pattern \"*.rs\" matches 100% of nothing [no-match]\n", false},
+		{"GitHub", lintFormatGitHub, diags, "::warning file=m.txt,line=2,title=no-match::pattern \"*.rs\" matches 100%25 of nothing\n", false},
+		{"JSON", lintFormatJSON, diags, "[\n  {\n    \"file\": \"m.txt\",\n    \"line\": 2,\n    \"check\": \"no-match\",\n    \"message\": \"pattern \\\"*.rs\\\" matches 100% of nothing\"\n  }\n]\n", false},
+		{"Empty JSON", lintFormatJSON, nil, "[]\n", false},
+		{"Unknown format", "xml", diags, "", true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var out bytes.Buffer
+			err := writeDiagnostics(&out, tc.format, tc.diags)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("writeDiagnostics() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if out.String() != tc.want {
+				t.Errorf("writeDiagnostics() = %q, want %q", out.String(), tc.want)
+			}
+		})
+	}
+}
+
+func TestEscapeProperty(t *testing.T) {
+	if got, want := escapeProperty("C:\\a,b\n"), "C%3A\\a%2Cb%0A"; got != want {
+		t.Errorf("escapeProperty() = %q, want %q", got, want)
+	}
+}
+
+func TestRunLint(t *testing.T) {
+	var out bytes.Buffer
+	stdout = &out
+	t.Cleanup(func() { stdout = os.Stdout })
+
+	rootDir := t.TempDir()
+	sourceDir := filepath.Join(rootDir, "source")
+	if err := os.MkdirAll(filepath.Join(sourceDir, "src"), 0755); err != nil {
+		t.Fatalf("failed to create source dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(sourceDir, "src", "app.go"), []byte(""), 0644); err != nil {
+		t.Fatalf("failed to create app.go: %v", err)
+	}
+	manifestPath := filepath.Join(rootDir, "manifest.txt")
+	if err := os.WriteFile(manifestPath, []byte("+ *.go\n- *"), 0644); err != nil {
+		t.Fatalf("failed to create manifest file: %v", err)
+	}
+	baseArgs := []string{"lint", flagManifest, manifestPath, flagSource, sourceDir}
+
+	testCases := []struct {
+		name    string
+		args    []string
+		fs      FileSystem
+		wantErr bool
+	}{
+		{"Argument parsing fails", []string{"lint", "--bad-flag"}, &liveFS{}, true},
+		{"Validation fails", baseArgs, &mockFS{validateErr: errors.New("validation failed")}, true},
+		{"Missing manifest", []string{"lint", flagManifest, filepath.Join(rootDir, "nope"), flagSource, sourceDir}, &mockFS{}, true},
+		{"Unknown format", append(baseArgs, "--format", "xml"), &liveFS{}, true},
+		{"Warnings are reported", baseArgs, &liveFS{}, false},
+		{"Strict mode fails on warnings", append(baseArgs, "--strict"), &liveFS{}, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			out.Reset()
+			err := run(tc.args, tc.fs, &mockSlicer{}, &mockRemapper{})
+			if (err != nil) != tc.wantErr {
+				t.Errorf("run() error = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+
+	out.Reset()
+	if err := run(baseArgs, &liveFS{}, &mockSlicer{}, &mockRemapper{}); err != nil {
+		t.Fatalf("run() returned an unexpected error: %v", err)
+	}
+	if !strings.Contains(out.String(), manifestPath+":1:") || !strings.Contains(out.String(), lint.CheckMissingTraversal) {
+		t.Errorf("unexpected lint output:\n%s", out.String())
+	}
+}
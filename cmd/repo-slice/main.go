@@ -12,35 +12,76 @@ package main
 import (
 	"flag"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
+	"path/filepath"
+	"runtime/debug"
+	"sort"
+	"strconv"
+	"strings"
 
+	"github.com/AlienHeadwars/repo-slice/internal/archive"
+	"github.com/AlienHeadwars/repo-slice/internal/binary"
+	"github.com/AlienHeadwars/repo-slice/internal/cache"
+	"github.com/AlienHeadwars/repo-slice/internal/config"
+	"github.com/AlienHeadwars/repo-slice/internal/filter"
+	"github.com/AlienHeadwars/repo-slice/internal/gitrepo"
+	"github.com/AlienHeadwars/repo-slice/internal/mirror"
+	"github.com/AlienHeadwars/repo-slice/internal/pack"
+	"github.com/AlienHeadwars/repo-slice/internal/priority"
+	"github.com/AlienHeadwars/repo-slice/internal/provenance"
 	"github.com/AlienHeadwars/repo-slice/internal/remapper"
+	"github.com/AlienHeadwars/repo-slice/internal/secrets"
 	"github.com/AlienHeadwars/repo-slice/internal/slicer"
+	"github.com/AlienHeadwars/repo-slice/internal/tokens"
+	"github.com/AlienHeadwars/repo-slice/internal/tokens/builtin"
+	"github.com/AlienHeadwars/repo-slice/internal/truncate"
 	"github.com/AlienHeadwars/repo-slice/internal/validate"
 )
 
-// Config holds the configuration options for the repo-slice tool.
+// Config holds the configuration options for the repo-slice tool. The slice
+// settings are the same ones a configuration file holds for each named slice;
+// the remaining fields only affect a single invocation.
 type Config struct {
-	ManifestPath string
-	SourcePath   string
-	OutputPath   string
-	ExtensionMap string
+	config.Slice
+	DryRun     bool
+	ShowTokens bool
+	// TokensOutput is a file to append a "tokens=N" line to, in the format of
+	// a GitHub Actions output file.
+	TokensOutput string
 }
 
 // FileSystem defines an interface for file system operations needed by run.
 type FileSystem interface {
 	ValidateInputs(cfg validate.Config) error
+	ValidateSlice(dir string, limits validate.Limits) error
+	CountTokens(dir string, est tokens.Estimator) (tokens.Report, error)
+	RemoveFiles(dir string, paths []string) error
+	ScanSecrets(dir string, mode secrets.Mode) ([]secrets.Finding, error)
+	HashFile(name string) (string, error)
+	WriteProvenance(dir, name string, rec provenance.Record) error
+	CheckMirror(dir, marker string) (*provenance.Record, error)
+	Prune(dir string, keep []string) ([]string, error)
+	LoadCache(name, key string) (*cache.Cache, error)
+	SaveCache(name, dir, key string, files map[string]cache.File) error
 }
 
 // Slicer defines an interface for the core application logic.
 type Slicer interface {
-	Slice(source, output, manifestPath string) error
+	Slice(opts slicer.Options) ([]slicer.Entry, error)
+	List(opts slicer.Options) ([]slicer.Entry, error)
+	Explain(opts slicer.Options, paths []string) ([]filter.Decision, error)
+	Revision(opts slicer.Options) (slicer.Revision, error)
+	Commit(repoDir, dir string, opts gitrepo.CommitOptions) (string, bool, error)
 }
 
 // Remapper defines an interface for the file remapping logic.
 type Remapper interface {
 	ParseExtensionMap(mapStr string) (map[string]string, error)
 	RemapExtensions(dir string, extMap map[string]string) error
+	RemapPath(path string, extMap map[string]string) string
+	RemapFiles(dir string, paths []string, extMap map[string]string) error
 }
 
 // liveFS is a concrete implementation of the FileSystem interface.
@@ -50,12 +91,90 @@ func (fs *liveFS) ValidateInputs(cfg validate.Config) error {
 	return validate.ValidateInputs(cfg, &validate.LiveFS{})
 }
 
+func (fs *liveFS) ValidateSlice(dir string, limits validate.Limits) error {
+	return validate.ValidateSlice(dir, limits, &validate.LiveFS{})
+}
+
+func (fs *liveFS) CountTokens(dir string, est tokens.Estimator) (tokens.Report, error) {
+	return tokens.Count(os.DirFS(dir), est)
+}
+
+// RemoveFiles deletes the files at the slash-separated paths beneath dir,
+// along with any directories left empty. Files that are already gone are
+// ignored.
+func (fs *liveFS) RemoveFiles(dir string, paths []string) error {
+	for _, p := range paths {
+		name := filepath.Join(dir, filepath.FromSlash(p))
+		if err := os.Remove(name); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		for parent := filepath.Dir(name); parent != filepath.Clean(dir); parent = filepath.Dir(parent) {
+			if os.Remove(parent) != nil {
+				break // Not empty.
+			}
+		}
+	}
+	return nil
+}
+
+func (fs *liveFS) ScanSecrets(dir string, mode secrets.Mode) ([]secrets.Finding, error) {
+	return secrets.Apply(dir, mode)
+}
+
+func (fs *liveFS) HashFile(name string) (string, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return "", err
+	}
+	return provenance.Hash(data), nil
+}
+
+func (fs *liveFS) WriteProvenance(dir, name string, rec provenance.Record) error {
+	return provenance.Write(dir, name, rec)
+}
+
+func (fs *liveFS) CheckMirror(dir, marker string) (*provenance.Record, error) {
+	return mirror.Check(dir, marker)
+}
+
+func (fs *liveFS) Prune(dir string, keep []string) ([]string, error) {
+	return mirror.Prune(dir, keep)
+}
+
+func (fs *liveFS) LoadCache(name, key string) (*cache.Cache, error) {
+	return cache.Load(name, key)
+}
+
+func (fs *liveFS) SaveCache(name, dir, key string, files map[string]cache.File) error {
+	c, err := cache.Build(dir, key, files)
+	if err != nil {
+		return fmt.Errorf("failed to update cache: %w", err)
+	}
+	return c.Save(name)
+}
+
 // liveSlicer is a concrete implementation of the Slicer interface.
 type liveSlicer struct{}
 
-func (s *liveSlicer) Slice(source, output, manifestPath string) error {
+func (s *liveSlicer) Slice(opts slicer.Options) ([]slicer.Entry, error) {
 	executor := &slicer.CmdExecutor{}
-	return slicer.Slice(source, output, manifestPath, executor)
+	return slicer.Slice(opts, executor)
+}
+
+func (s *liveSlicer) List(opts slicer.Options) ([]slicer.Entry, error) {
+	return slicer.List(opts, &slicer.CmdExecutor{})
+}
+
+func (s *liveSlicer) Explain(opts slicer.Options, paths []string) ([]filter.Decision, error) {
+	return slicer.Explain(opts, paths, &slicer.CmdExecutor{})
+}
+
+func (s *liveSlicer) Revision(opts slicer.Options) (slicer.Revision, error) {
+	return slicer.SourceRevision(opts, &slicer.CmdExecutor{})
+}
+
+func (s *liveSlicer) Commit(repoDir, dir string, opts gitrepo.CommitOptions) (string, bool, error) {
+	return gitrepo.Commit(repoDir, dir, opts, &slicer.CmdExecutor{})
 }
 
 // liveRemapper is a concrete implementation of the Remapper interface.
@@ -68,6 +187,36 @@ func (r *liveRemapper) RemapExtensions(dir string, extMap map[string]string) err
 	fsys := &remapper.LiveFS{}
 	return remapper.RemapExtensions(dir, extMap, fsys)
 }
+func (r *liveRemapper) RemapPath(path string, extMap map[string]string) string {
+	return remapper.RemapPath(path, extMap)
+}
+func (r *liveRemapper) RemapFiles(dir string, paths []string, extMap map[string]string) error {
+	return remapper.RemapFiles(dir, paths, extMap, &remapper.LiveFS{})
+}
+
+// stdout is where user-facing results are written. It is a variable so tests
+// can capture the output.
+var stdout io.Writer = os.Stdout
+
+// stdin is where the inspect command reads a record named "-". It is a
+// variable so tests can supply one.
+var stdin io.Reader = os.Stdin
+
+// version is the version of repo-slice, set at release time with
+// -ldflags "-X main.version=...".
+var version = "dev"
+
+// toolVersion returns the version of repo-slice, falling back to the module
+// version for binaries built with go install.
+func toolVersion() string {
+	if version != "dev" {
+		return version
+	}
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+	return version
+}
 
 func main() {
 	if err := run(os.Args[1:], &liveFS{}, &liveSlicer{}, &liveRemapper{}); err != nil {
@@ -78,38 +227,764 @@ func main() {
 
 // run executes the main logic of the application.
 func run(args []string, fsys FileSystem, slicer Slicer, remapper Remapper) (err error) {
+	if len(args) > 0 {
+		switch args[0] {
+		case "commit":
+			return runCommit(args[1:], slicer)
+		case "explain":
+			return runExplain(args[1:], fsys, slicer)
+		case "inspect":
+			return runInspect(args[1:])
+		case "lint":
+			return runLint(args[1:], fsys)
+		case "run":
+			return runConfig(args[1:], fsys, slicer, remapper)
+		}
+	}
+
 	cfg, err := parseArgs(args)
 	if err != nil {
 		return err
 	}
 
-	validationCfg := validate.Config{
-		SourcePath:   cfg.SourcePath,
-		ManifestPath: cfg.ManifestPath,
+	summary, err := createSlice(cfg, fsys, slicer, remapper)
+	if err != nil {
+		return err
+	}
+	// The success message would corrupt a file written to standard output.
+	if !cfg.DryRun && cfg.OutputPath != stdoutPath {
+		fmt.Fprintf(stdout, "Successfully created repository slice in %s\n", cfg.OutputPath)
+		printSummary(summary, cfg.ShowTokens)
+	}
+	if !cfg.DryRun && cfg.TokensOutput != "" {
+		return appendOutput(cfg.TokensOutput, "tokens", summary.Tokens.Total)
+	}
+	return nil
+}
+
+// appendOutput appends a "key=value" line to the output file name, so that a
+// script can read a result without parsing the printed summary, which may be
+// reworded.
+func appendOutput(name, key string, value any) error {
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to write %s output: %w", key, err)
+	}
+	if _, err := fmt.Fprintf(f, "%s=%v\n", key, value); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write %s output: %w", key, err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write %s output: %w", key, err)
+	}
+	return nil
+}
+
+// sliceSummary describes a slice that was created.
+type sliceSummary struct {
+	Tokens    tokens.Report
+	Dropped   []priority.File // Files dropped to fit the budget, by source path.
+	Truncated []slicer.Entry  // Files cut short to the per-file limits.
+	// Secrets found in the slice, by source path, and what was done to them.
+	Secrets    []secrets.Finding
+	SecretMode secrets.Mode
+	Removed    []string // Stale paths removed from a mirrored output.
+	// Incremental reports that the slice was made using a cache of the
+	// previous one, from which Reused files were kept and to which Copied
+	// files were copied. Deleted lists the copies of files no longer selected.
+	Incremental    bool
+	Reused, Copied int
+	Deleted        []string
+}
+
+// printSummary reports the size of a slice, how much of it was reused from a
+// previous slice, any secrets removed from it, any stale paths removed from
+// its output, any files truncated or dropped to fit its budget and, if
+// requested, the estimated tokens in each of its files, largest first.
+func printSummary(summary sliceSummary, perFile bool) {
+	report := summary.Tokens
+	fmt.Fprintf(stdout, "Slice contains %d files, %d bytes and an estimated %d tokens\n", len(report.Files), report.Bytes, report.Total)
+	switch {
+	case len(summary.Secrets) == 0:
+	case summary.SecretMode == secrets.ModeRedact:
+		fmt.Fprintf(stdout, "Redacted %d possible secrets:\n", len(summary.Secrets))
+	case summary.SecretMode == secrets.ModeExclude:
+		fmt.Fprintf(stdout, "Excluded %d files containing possible secrets:\n", countPaths(summary.Secrets))
+	}
+	for _, f := range summary.Secrets {
+		fmt.Fprintf(stdout, "  %s\n", f)
+	}
+	if summary.Incremental {
+		fmt.Fprintf(stdout, "Reused %d unchanged files from the previous slice, copied %d and deleted %d no longer selected\n", summary.Reused, summary.Copied, len(summary.Deleted))
+	}
+	if len(summary.Removed) > 0 {
+		fmt.Fprintf(stdout, "Removed %d paths the manifest no longer selects from the output:\n", len(summary.Removed))
+		for _, name := range summary.Removed {
+			fmt.Fprintf(stdout, "  %s\n", name)
+		}
+	}
+	if len(summary.Truncated) > 0 {
+		fmt.Fprintf(stdout, "Truncated %d files to the per-file limits, so only their start is included:\n", len(summary.Truncated))
+		for _, e := range summary.Truncated {
+			fmt.Fprintf(stdout, "%10d  %s (truncated from %d bytes)\n", len(e.Content), e.Path, e.Size)
+		}
+	}
+	if len(summary.Dropped) > 0 {
+		fmt.Fprintf(stdout, "Dropped %d files to fit the budget:\n", len(summary.Dropped))
+		fmt.Fprintf(stdout, "%10s  %10s  %10s  %s\n", "PRIORITY", "BYTES", "TOKENS", "PATH")
+		for _, f := range summary.Dropped {
+			fmt.Fprintf(stdout, "%10d  %10d  %10d  %s\n", f.Priority, f.Size, f.Tokens, f.Path)
+		}
+	}
+	if perFile {
+		for _, f := range report.Files {
+			fmt.Fprintf(stdout, "%10d  %s\n", f.Tokens, f.Path)
+		}
+	}
+}
+
+// countPaths returns the number of distinct files among the findings.
+func countPaths(findings []secrets.Finding) int {
+	paths := make(map[string]bool)
+	for _, f := range findings {
+		paths[f.Path] = true
+	}
+	return len(paths)
+}
+
+// Overflow policies select what happens when a slice is over its budget.
+const (
+	overflowFail = "fail" // Fail the run.
+	overflowDrop = "drop" // Drop the lowest-priority files until it fits.
+)
+
+// createSlice validates a single slice configuration and then either creates
+// the slice or, for a dry run, lists what it would contain.
+func createSlice(cfg Config, fsys FileSystem, slicer Slicer, remapper Remapper) (summary sliceSummary, err error) {
+	if err := fsys.ValidateInputs(validationConfig(cfg)); err != nil {
+		return summary, err
+	}
+	limits, err := sliceLimits(cfg)
+	if err != nil {
+		return summary, err
+	}
+	if cfg.MaxTokens < 0 {
+		return summary, fmt.Errorf("invalid maximum tokens: %d must not be negative", cfg.MaxTokens)
+	}
+	est, err := tokenEstimator(cfg)
+	if err != nil {
+		return summary, err
+	}
+	priorities, err := loadPriorities(cfg)
+	if err != nil {
+		return summary, err
+	}
+	opts := sliceOptions(cfg)
+	if opts.MaxFileSize, err = maxFileSize(cfg); err != nil {
+		return summary, err
+	}
+	secretMode := secrets.Mode(cfg.Secrets)
+	if secretMode == "" {
+		secretMode = secrets.ModeOff
+	}
+	if !secretMode.Valid() {
+		return summary, fmt.Errorf("invalid secrets mode %q: must be off, fail, redact or exclude", cfg.Secrets)
+	}
+	provenancePath := cfg.Provenance
+	switch provenancePath {
+	case "":
+		provenancePath = provenance.DefaultPath
+	case provenance.Off:
+		provenancePath = ""
+	default:
+		if err := provenance.CheckPath(provenancePath); err != nil {
+			return summary, err
+		}
+	}
+	if cfg.Mirror {
+		if err := checkMirrorOptions(cfg, provenancePath); err != nil {
+			return summary, err
+		}
+	}
+	if cfg.Cache != "" {
+		if err := checkCacheOptions(cfg); err != nil {
+			return summary, err
+		}
+	}
+
+	write, err := outputWriter(cfg.OutputFormat)
+	if err != nil {
+		return summary, err
+	}
+	if write == nil && cfg.OutputPath == stdoutPath && !cfg.DryRun {
+		return summary, fmt.Errorf("writing a slice to standard output requires --output-format")
+	}
+
+	if cfg.DryRun {
+		return summary, listSlice(cfg, opts, slicer, remapper)
+	}
+
+	// A single-file output is built from a complete slice in a temporary
+	// directory, so that remapping and validation work exactly as they do for
+	// a directory.
+	if write != nil {
+		dir, err := os.MkdirTemp("", "repo-slice-archive-*")
+		if err != nil {
+			return summary, fmt.Errorf("failed to create temporary directory: %w", err)
+		}
+		defer os.RemoveAll(dir)
+		opts.Output = dir
+	}
+
+	// Nothing is deleted from a directory that does not hold a previous slice.
+	var previous *provenance.Record
+	if cfg.Mirror {
+		if previous, err = fsys.CheckMirror(opts.Output, provenancePath); err != nil {
+			return summary, err
+		}
+	}
+
+	extMap, err := parseExtensionMap(cfg, remapper)
+	if err != nil {
+		return summary, err
+	}
+	var cacheKey string
+	if cfg.Cache != "" {
+		if cacheKey, err = sliceCacheKey(cfg, secretMode, fsys); err != nil {
+			return summary, err
+		}
+		if opts.Cache, err = fsys.LoadCache(cfg.Cache, cacheKey); err != nil {
+			return summary, err
+		}
+	}
+
+	entries, err := slicer.Slice(opts)
+	if err != nil {
+		return summary, fmt.Errorf("failed to execute slice operation: %w", err)
+	}
+	if opts.Cache != nil {
+		summary.Incremental = true
+		for _, e := range entries {
+			switch {
+			case e.Reused:
+				summary.Reused++
+			case e.Hash != "":
+				summary.Copied++
+			}
+		}
+		summary.Deleted = staleFiles(opts.Cache, entries, extMap, remapper)
+		if err := fsys.RemoveFiles(opts.Output, summary.Deleted); err != nil {
+			return summary, fmt.Errorf("failed to delete files no longer selected: %w", err)
+		}
+	}
+	if cfg.Mirror {
+		// A failed run must leave an output that can be mirrored into again:
+		// the previous record is put back, or an output that was empty is
+		// emptied again.
+		defer func() {
+			switch {
+			case err == nil:
+			case previous != nil:
+				fsys.WriteProvenance(opts.Output, provenancePath, *previous)
+			default:
+				fsys.Prune(opts.Output, nil)
+			}
+		}()
+		if summary.Removed, err = mirrorOutput(opts.Output, entries, extMap, provenancePath, fsys, remapper); err != nil {
+			return summary, err
+		}
+	} else if provenancePath != "" {
+		// A record left in the output by a previous slice would otherwise be
+		// counted against the limits below, or even dropped to meet them.
+		if err := fsys.RemoveFiles(opts.Output, []string{provenancePath}); err != nil {
+			return summary, fmt.Errorf("failed to remove the previous provenance record: %w", err)
+		}
+	}
+	for _, e := range entries {
+		if e.Oversized && !e.Skip {
+			summary.Truncated = append(summary.Truncated, e)
+		}
+	}
+
+	// Secrets are dealt with before anything else reads the slice, and while
+	// its paths still match the source.
+	if secretMode != secrets.ModeOff {
+		findings, err := fsys.ScanSecrets(opts.Output, secretMode)
+		if err != nil {
+			return summary, err
+		}
+		if len(findings) > 0 && secretMode == secrets.ModeFail {
+			return summary, secretsError(findings)
+		}
+		summary.Secrets, summary.SecretMode = findings, secretMode
+	}
+
+	// Files are dropped before remapping, so that priorities match the paths
+	// in the source.
+	if cfg.OnOverflow == overflowDrop {
+		budget := priority.Budget{MaxFiles: limits.MaxFiles, MaxSize: limits.MaxSize, MaxTokens: cfg.MaxTokens}
+		if summary.Dropped, err = dropOverflow(opts.Output, budget, priorities, est, fsys); err != nil {
+			return summary, err
+		}
+	}
+
+	// Remap extensions if a map is provided. Files reused from a previous
+	// slice were remapped when they were copied.
+	if cfg.ExtensionMap != "" {
+		if opts.Cache != nil {
+			err = remapper.RemapFiles(opts.Output, copiedPaths(entries), extMap)
+		} else {
+			err = remapper.RemapExtensions(opts.Output, extMap)
+		}
+		if err != nil {
+			return summary, fmt.Errorf("failed to remap extensions: %w", err)
+		}
+	}
+
+	if err := fsys.ValidateSlice(opts.Output, limits); err != nil {
+		return summary, fmt.Errorf("slice exceeds its limits: %w", err)
+	}
+	if summary.Tokens, err = fsys.CountTokens(opts.Output, est); err != nil {
+		return summary, err
+	}
+	if err := summary.Tokens.CheckBudget(cfg.MaxTokens); err != nil {
+		return summary, fmt.Errorf("slice exceeds its token budget: %w", err)
+	}
+
+	// The record is written last, so that it is left out of every limit and
+	// count, and describes the slice exactly as it is shipped.
+	if provenancePath != "" {
+		rec, err := sliceProvenance(cfg, opts, summary.Tokens, fsys, slicer, remapper)
+		if err != nil {
+			return summary, err
+		}
+		if err := fsys.WriteProvenance(opts.Output, provenancePath, rec); err != nil {
+			return summary, err
+		}
+	}
+	if opts.Cache != nil {
+		if err := fsys.SaveCache(cfg.Cache, opts.Output, cacheKey, cachedFiles(entries, extMap, remapper)); err != nil {
+			return summary, err
+		}
+	}
+
+	if write != nil {
+		return summary, writeOutput(opts.Output, cfg.OutputPath, write)
 	}
-	if err := fsys.ValidateInputs(validationCfg); err != nil {
+	return summary, nil
+}
+
+// checkMirrorOptions reports whether the slice described by cfg, whose record
+// is written at provenancePath, can be mirrored into its output.
+func checkMirrorOptions(cfg Config, provenancePath string) error {
+	switch {
+	case cfg.OutputFormat != "":
+		return fmt.Errorf("--mirror needs an output directory and cannot be used with --output-format")
+	case provenancePath == "":
+		return fmt.Errorf("--mirror needs the provenance record that marks the output as a slice, so it cannot be used with --provenance=off")
+	case slicer.Engine(cfg.Engine) == slicer.EngineRsync:
+		return fmt.Errorf("the rsync engine cannot mirror the output; use the native engine")
+	}
+	return nil
+}
+
+// mirrorOutput removes everything from the output dir that is not part of the
+// slice of entries, and returns the paths that are gone for good. Copied files
+// are kept under their names in the source, as they are only remapped later,
+// so a stale copy with a remapped name is removed and then replaced; such
+// paths, and the provenance record, which is rewritten, are not reported.
+func mirrorOutput(dir string, entries []slicer.Entry, extMap map[string]string, provenancePath string, fsys FileSystem, remapper Remapper) ([]string, error) {
+	var keep []string
+	replaced := map[string]bool{provenancePath: true}
+	for _, e := range entries {
+		if e.Skip {
+			continue
+		}
+		final := remapper.RemapPath(e.SlicePath(), extMap)
+		if e.Reused {
+			keep = append(keep, final)
+		} else {
+			keep = append(keep, e.SlicePath())
+		}
+		replaced[final] = true
+	}
+	removed, err := fsys.Prune(dir, keep)
+	if err != nil {
+		return nil, fmt.Errorf("failed to mirror the output: %w", err)
+	}
+	stale := removed[:0]
+	for _, name := range removed {
+		isRecordDir := strings.HasSuffix(name, "/") && strings.HasPrefix(provenancePath, name)
+		if !replaced[name] && !isRecordDir {
+			stale = append(stale, name)
+		}
+	}
+	return stale, nil
+}
+
+// checkCacheOptions reports whether the slice described by cfg can be made
+// incrementally.
+func checkCacheOptions(cfg Config) error {
+	if cfg.OutputFormat != "" {
+		return fmt.Errorf("--cache needs an output directory and cannot be used with --output-format")
+	}
+	output, err := filepath.Abs(cfg.OutputPath)
+	if err != nil {
 		return err
 	}
+	name, err := filepath.Abs(cfg.Cache)
+	if err != nil {
+		return err
+	}
+	if rel, err := filepath.Rel(output, name); err == nil && filepath.IsLocal(rel) {
+		return fmt.Errorf("invalid cache %q: must be outside the output directory", cfg.Cache)
+	}
+	return nil
+}
+
+// sliceCacheKey returns the cache key of the slice described by cfg, which
+// covers every setting that shapes the content of the files in a slice,
+// including the secrets mode, as the cache describes the copies after secrets
+// were redacted or excluded. Those that only decide whether a file is
+// selected are left out, as every file is selected afresh.
+func sliceCacheKey(cfg Config, secretMode secrets.Mode, fsys FileSystem) (string, error) {
+	hash, err := fsys.HashFile(cfg.ManifestPath) // For its transforms.
+	if err != nil {
+		return "", fmt.Errorf("failed to hash manifest: %w", err)
+	}
+	return cache.Key(toolVersion(), hash, cfg.ExtensionMap, cfg.Binary, cfg.MaxFileSize, strconv.Itoa(cfg.MaxFileLines), cfg.Oversized, string(secretMode)), nil
+}
+
+// staleFiles returns the copies that the cache c lists, but no file in the new
+// slice of entries will be copied to, in lexical order.
+func staleFiles(c *cache.Cache, entries []slicer.Entry, extMap map[string]string, remapper Remapper) []string {
+	current := make(map[string]bool)
+	for _, e := range entries {
+		if !e.Skip && !e.IsDir() {
+			current[remapper.RemapPath(e.SlicePath(), extMap)] = true
+		}
+	}
+	var stale []string
+	for _, f := range c.Files {
+		if !current[f.Output] {
+			stale = append(stale, f.Output)
+		}
+	}
+	sort.Strings(stale)
+	return stale
+}
+
+// copiedPaths returns the paths in the slice of the entries that were copied
+// rather than reused.
+func copiedPaths(entries []slicer.Entry) []string {
+	var paths []string
+	for _, e := range entries {
+		if !e.Skip && !e.Reused && !e.IsDir() {
+			paths = append(paths, e.SlicePath())
+		}
+	}
+	return paths
+}
 
-	if err := slicer.Slice(cfg.SourcePath, cfg.OutputPath, cfg.ManifestPath); err != nil {
-		return fmt.Errorf("failed to execute slice operation: %w", err)
+// cachedFiles returns the cache records of the files in the slice of entries,
+// by their path in the source.
+func cachedFiles(entries []slicer.Entry, extMap map[string]string, remapper Remapper) map[string]cache.File {
+	files := make(map[string]cache.File)
+	for _, e := range entries {
+		if e.Hash == "" {
+			continue
+		}
+		files[e.Path] = cache.File{Size: e.Size, ModTime: e.ModTime, SHA256: e.Hash, Output: remapper.RemapPath(e.SlicePath(), extMap)}
 	}
+	return files
+}
 
-	// Remap extensions if a map is provided.
+// parseExtensionMap returns the extension map of cfg, which is empty if it has
+// none.
+func parseExtensionMap(cfg Config, remapper Remapper) (map[string]string, error) {
+	if cfg.ExtensionMap == "" {
+		return map[string]string{}, nil
+	}
+	extMap, err := remapper.ParseExtensionMap(cfg.ExtensionMap)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse extension map: %w", err)
+	}
+	return extMap, nil
+}
+
+// sliceProvenance describes the slice created from cfg, whose files report
+// counted.
+func sliceProvenance(cfg Config, opts slicer.Options, report tokens.Report, fsys FileSystem, slicer Slicer, remapper Remapper) (provenance.Record, error) {
+	rev, err := slicer.Revision(opts)
+	if err != nil {
+		return provenance.Record{}, fmt.Errorf("failed to identify the source commit: %w", err)
+	}
+	hash, err := fsys.HashFile(cfg.ManifestPath)
+	if err != nil {
+		return provenance.Record{}, fmt.Errorf("failed to hash manifest: %w", err)
+	}
+	manifest := provenance.Manifest{Path: cfg.ManifestPath, SHA256: hash}
+	if cfg.Rules != "" {
+		manifest.Path = "" // A temporary file holding the inline rules.
+	}
+	var extMap map[string]string
 	if cfg.ExtensionMap != "" {
-		extMap, err := remapper.ParseExtensionMap(cfg.ExtensionMap)
+		if extMap, err = remapper.ParseExtensionMap(cfg.ExtensionMap); err != nil {
+			return provenance.Record{}, fmt.Errorf("failed to parse extension map: %w", err)
+		}
+	}
+	created, err := provenance.Created(rev.Time)
+	if err != nil {
+		return provenance.Record{}, err
+	}
+	return provenance.Record{
+		Schema:       provenance.Schema,
+		Version:      toolVersion(),
+		Created:      created,
+		Source:       provenance.Source{Commit: rev.Commit, Dirty: rev.Dirty, Ref: cfg.Ref},
+		Manifest:     manifest,
+		ExtensionMap: extMap,
+		Files:        len(report.Files),
+		Bytes:        report.Bytes,
+	}, nil
+}
+
+// secretsError reports the secrets found in a slice by location and kind,
+// never by value.
+func secretsError(findings []secrets.Finding) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "slice contains %d possible secrets:", len(findings))
+	for _, f := range findings {
+		fmt.Fprintf(&b, "\n  %s", f)
+	}
+	b.WriteString("\nremove them, mark each line with " + secrets.Allow + " if it is safe, or use --secrets=redact or --secrets=exclude")
+	return fmt.Errorf("%s", b.String())
+}
+
+// loadPriorities reads the priority list named in the configuration, after
+// checking the overflow policy that uses it.
+func loadPriorities(cfg Config) (priority.List, error) {
+	switch cfg.OnOverflow {
+	case "", overflowFail, overflowDrop:
+	default:
+		return nil, fmt.Errorf("invalid overflow policy %q: must be %s or %s", cfg.OnOverflow, overflowFail, overflowDrop)
+	}
+	if cfg.Priority == "" {
+		return nil, nil
+	}
+	return priority.Load(cfg.Priority, filter.LiveFS{})
+}
+
+// dropOverflow removes files from the slice in dir until it fits the budget,
+// and returns the files it removed.
+func dropOverflow(dir string, budget priority.Budget, priorities priority.List, est tokens.Estimator, fsys FileSystem) ([]priority.File, error) {
+	report, err := fsys.CountTokens(dir, est)
+	if err != nil {
+		return nil, err
+	}
+	files := make([]priority.File, len(report.Files))
+	for i, f := range report.Files {
+		files[i] = priority.File{Path: f.Path, Size: f.Size, Tokens: f.Tokens, Priority: priorities.Of(f.Path)}
+	}
+	dropped := priority.Fit(files, budget)
+	if len(dropped) == 0 {
+		return nil, nil
+	}
+	paths := make([]string, len(dropped))
+	for i, f := range dropped {
+		paths[i] = f.Path
+	}
+	if err := fsys.RemoveFiles(dir, paths); err != nil {
+		return nil, fmt.Errorf("failed to drop files from the slice: %w", err)
+	}
+	return dropped, nil
+}
+
+// tokenEstimator returns the estimator selected by the configuration: a BPE
+// vocabulary if one is named, built in or from a file, and the heuristic
+// otherwise.
+func tokenEstimator(cfg Config) (tokens.Estimator, error) {
+	switch cfg.TokenVocab {
+	case "":
+		return tokens.Heuristic{}, nil
+	case builtin.Name:
+		return builtin.BPE()
+	}
+	return tokens.LoadBPE(cfg.TokenVocab)
+}
+
+// stdoutPath is the output path that stands for standard output.
+const stdoutPath = "-"
+
+// outputFunc writes a slice as a single file.
+type outputFunc func(w io.Writer, src fs.FS) error
+
+// outputWriter returns the function that writes a slice in the named output
+// format, or nil for the default of a directory.
+func outputWriter(format string) (outputFunc, error) {
+	switch {
+	case format == "":
+		return nil, nil
+	case archive.Format(format).Valid():
+		return func(w io.Writer, src fs.FS) error { return archive.Write(w, src, archive.Format(format)) }, nil
+	case pack.Format(format).Valid():
+		return func(w io.Writer, src fs.FS) error { return pack.Write(w, src, pack.Format(format)) }, nil
+	}
+	return nil, fmt.Errorf("invalid output format %q: must be %s, %s, %s or %s",
+		format, archive.FormatTarGz, archive.FormatZip, pack.FormatMarkdown, pack.FormatXML)
+}
+
+// writeOutput writes the slice in dir to the output file, or to standard
+// output. A partly written file is removed.
+func writeOutput(dir, output string, write outputFunc) error {
+	if output == stdoutPath {
+		if err := write(stdout, slicer.NewDirFS(dir)); err != nil {
+			return fmt.Errorf("failed to write output: %w", err)
+		}
+		return nil
+	}
+
+	f, err := os.Create(output)
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %w", err)
+	}
+	err = write(f, slicer.NewDirFS(dir))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(output)
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+	return nil
+}
+
+// sliceLimits converts the configured limits into the form understood by the
+// validate package.
+func sliceLimits(cfg Config) (validate.Limits, error) {
+	if cfg.MaxFiles < 0 {
+		return validate.Limits{}, fmt.Errorf("invalid maximum file count: %d must not be negative", cfg.MaxFiles)
+	}
+	limits := validate.Limits{MaxFiles: cfg.MaxFiles}
+	if cfg.MaxSize != "" {
+		size, err := validate.ParseSize(cfg.MaxSize)
 		if err != nil {
-			return fmt.Errorf("failed to parse extension map: %w", err)
+			return validate.Limits{}, fmt.Errorf("invalid maximum size: %w", err)
 		}
-		if err := remapper.RemapExtensions(cfg.OutputPath, extMap); err != nil {
-			return fmt.Errorf("failed to remap extensions: %w", err)
+		limits.MaxSize = size
+	}
+	return limits, nil
+}
+
+// maxFileSize returns the per-file size limit in bytes, or 0 if there is none.
+func maxFileSize(cfg Config) (int64, error) {
+	if cfg.MaxFileSize == "" {
+		return 0, nil
+	}
+	size, err := validate.ParseSize(cfg.MaxFileSize)
+	if err != nil {
+		return 0, fmt.Errorf("invalid maximum file size: %w", err)
+	}
+	return size, nil
+}
+
+// listSlice prints the files the manifest selects, as they will be named in
+// the slice, without writing anything to the output directory.
+func listSlice(cfg Config, opts slicer.Options, slicer Slicer, remapper Remapper) error {
+	entries, err := slicer.List(opts)
+	if err != nil {
+		return fmt.Errorf("failed to evaluate manifest: %w", err)
+	}
+
+	extMap := map[string]string{}
+	if cfg.ExtensionMap != "" {
+		if extMap, err = remapper.ParseExtensionMap(cfg.ExtensionMap); err != nil {
+			return fmt.Errorf("failed to parse extension map: %w", err)
 		}
 	}
 
-	fmt.Printf("Successfully created repository slice in %s\n", cfg.OutputPath)
+	var files, total int64
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		name := remapper.RemapPath(e.SlicePath(), extMap)
+		switch {
+		case e.Skip && e.Oversized:
+			fmt.Fprintf(stdout, "%10s  %s (skipped: over the per-file limits, %d bytes)\n", "-", name, e.Size)
+			continue
+		case e.Skip:
+			fmt.Fprintf(stdout, "%10s  %s (skipped: %s, %d bytes)\n", "-", name, e.Binary, e.Size)
+			continue
+		case e.Stub:
+			fmt.Fprintf(stdout, "%10d  %s (stub for %s, %d bytes)\n", len(e.Content), name, e.Binary, e.Size)
+			total += int64(len(e.Content))
+		case e.Oversized:
+			fmt.Fprintf(stdout, "%10d  %s (truncated from %d bytes)\n", len(e.Content), name, e.Size)
+			total += int64(len(e.Content))
+		case e.Transform != "":
+			fmt.Fprintf(stdout, "%10d  %s (%s of %d bytes)\n", len(e.Content), name, e.Transform, e.Size)
+			total += int64(len(e.Content))
+		default:
+			fmt.Fprintf(stdout, "%10d  %s\n", e.Size, name)
+			total += e.Size
+		}
+		files++
+	}
+	fmt.Fprintf(stdout, "%d files, %d bytes would be sliced from %s\n", files, total, cfg.SourcePath)
 	return nil
 }
 
+// manifestFormatUsage is the help text of the --manifest-format flag shared by
+// every command that reads a manifest.
+const manifestFormatUsage = "Manifest syntax: rsync or gitignore (default: from the manifest header, otherwise rsync)"
+
+// gitignoreUsage is the help text of the --gitignore flag.
+const gitignoreUsage = "Skip files ignored by the source repository's .gitignore files before applying the manifest"
+
+// trackedOnlyUsage is the help text of the --tracked-only flag.
+const trackedOnlyUsage = "Only consider files tracked by git in the source, as listed by git ls-files"
+
+// mirrorUsage is the help text of the --mirror flag.
+const mirrorUsage = "Delete whatever the output directory holds besides the slice; it must be empty or hold a previous slice"
+
+// cacheUsage is the help text of the --cache flag.
+const cacheUsage = "Slice incrementally, keeping a cache of the previous slice in this file, outside the output directory"
+
+// provenanceUsage is the help text of the --provenance flag.
+const provenanceUsage = "Path inside the slice of the record of how it was made, or off to leave it out"
+
+// refUsage is the help text of the --ref flag.
+const refUsage = "Slice the tree of this git commit, branch or tag instead of the working tree, without checking it out"
+
+// validationConfig selects the settings that are checked before any command
+// reads the manifest.
+func validationConfig(cfg Config) validate.Config {
+	return validate.Config{
+		SourcePath:     cfg.SourcePath,
+		ManifestPath:   cfg.ManifestPath,
+		ManifestFormat: cfg.ManifestFormat,
+	}
+}
+
+// sliceOptions converts the command-line configuration into the options
+// understood by the slicer package.
+func sliceOptions(cfg Config) slicer.Options {
+	return slicer.Options{
+		Source:         cfg.SourcePath,
+		Output:         cfg.OutputPath,
+		ManifestPath:   cfg.ManifestPath,
+		Engine:         slicer.Engine(cfg.Engine),
+		ManifestFormat: filter.Format(cfg.ManifestFormat),
+		Gitignore:      cfg.Gitignore,
+		TrackedOnly:    cfg.TrackedOnly,
+		Ref:            cfg.Ref,
+		Binary:         binary.Mode(cfg.Binary),
+		MaxFileLines:   cfg.MaxFileLines,
+		Oversized:      truncate.Mode(cfg.Oversized),
+	}
+}
+
 // parseArgs parses the command-line arguments.
 func parseArgs(args []string) (Config, error) {
 	var cfg Config
@@ -117,8 +992,31 @@ func parseArgs(args []string) (Config, error) {
 
 	fs.StringVar(&cfg.ManifestPath, "manifest", "", "Path to manifest file (required)")
 	fs.StringVar(&cfg.SourcePath, "source", ".", "Source directory")
-	fs.StringVar(&cfg.OutputPath, "output", "", "Destination directory (required)")
+	fs.StringVar(&cfg.OutputPath, "output", "", "Destination directory, or file with --output-format; - writes the file to standard output (required)")
+	fs.BoolVar(&cfg.Mirror, "mirror", false, mirrorUsage)
+	fs.StringVar(&cfg.Cache, "cache", "", cacheUsage)
+	fs.StringVar(&cfg.OutputFormat, "output-format", "", "Write the slice as one file instead of a directory: tar.gz, zip, markdown or xml")
 	fs.StringVar(&cfg.ExtensionMap, "extension-map", "", "Comma-separated list of old:new extension pairs")
+	fs.StringVar(&cfg.ManifestFormat, "manifest-format", "", manifestFormatUsage)
+	fs.StringVar(&cfg.Engine, "engine", string(slicer.EngineNative), "Slicing backend: native or rsync")
+	fs.BoolVar(&cfg.Gitignore, "gitignore", false, gitignoreUsage)
+	fs.BoolVar(&cfg.TrackedOnly, "tracked-only", false, trackedOnlyUsage)
+	fs.StringVar(&cfg.Ref, "ref", "", refUsage)
+	fs.StringVar(&cfg.Binary, "binary", string(binary.ModeKeep), "What to do with binary files: keep, skip, or stub to replace each with a text file describing it")
+	fs.IntVar(&cfg.MaxFiles, "max-files", 0, "Fail if the slice contains more than this many files (default: no limit)")
+	fs.StringVar(&cfg.MaxSize, "max-size", "", "Fail if the files in the slice add up to more than this size, e.g. 100M (default: no limit)")
+	fs.StringVar(&cfg.Secrets, "secrets", string(secrets.ModeOff), "Scan the slice for credentials and fail, redact them, or exclude their files: off, fail, redact or exclude")
+	fs.StringVar(&cfg.MaxFileSize, "max-file-size", "", "Skip or truncate files larger than this size, e.g. 1M (default: no limit)")
+	fs.IntVar(&cfg.MaxFileLines, "max-file-lines", 0, "Skip or truncate files with more than this many lines (default: no limit)")
+	fs.StringVar(&cfg.Oversized, "oversized", string(truncate.ModeSkip), "What to do with files over --max-file-size or --max-file-lines: skip, or truncate to keep their start")
+	fs.IntVar(&cfg.MaxTokens, "max-tokens", 0, "Fail if the slice contains more than this many estimated tokens (default: no limit)")
+	fs.StringVar(&cfg.TokenVocab, "token-vocab", "", "Count tokens with this tiktoken-format BPE vocabulary instead of the heuristic estimate, or 'builtin' for the embedded one")
+	fs.StringVar(&cfg.OnOverflow, "on-overflow", overflowFail, "What to do when the slice is over a limit: fail, or drop the lowest-priority, largest files until it fits")
+	fs.StringVar(&cfg.Priority, "priority", "", "Path to a priority list of weights and patterns used by --on-overflow=drop")
+	fs.StringVar(&cfg.Provenance, "provenance", provenance.DefaultPath, provenanceUsage)
+	fs.BoolVar(&cfg.ShowTokens, "show-tokens", false, "Print the estimated tokens in each file of the slice, largest first")
+	fs.StringVar(&cfg.TokensOutput, "tokens-output", "", "Append tokens=N, the estimated tokens in the slice, to this file, such as $GITHUB_OUTPUT")
+	fs.BoolVar(&cfg.DryRun, "dry-run", false, "Print the files the manifest selects without writing anything")
 
 	if err := fs.Parse(args); err != nil {
 		return Config{}, err
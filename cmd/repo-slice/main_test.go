@@ -2,11 +2,29 @@
 package main
 
 import (
+	"archive/zip"
+	"bytes"
 	"errors"
+	"fmt"
+	"io/fs"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/AlienHeadwars/repo-slice/internal/binary"
+	"github.com/AlienHeadwars/repo-slice/internal/cache"
+	"github.com/AlienHeadwars/repo-slice/internal/filter"
+	"github.com/AlienHeadwars/repo-slice/internal/gitrepo"
+	"github.com/AlienHeadwars/repo-slice/internal/provenance"
+	"github.com/AlienHeadwars/repo-slice/internal/secrets"
+	"github.com/AlienHeadwars/repo-slice/internal/slicer"
+	"github.com/AlienHeadwars/repo-slice/internal/tokens"
+	"github.com/AlienHeadwars/repo-slice/internal/truncate"
 	"github.com/AlienHeadwars/repo-slice/internal/validate"
 )
 
@@ -19,16 +37,131 @@ const (
 // mockFS is a mock implementation of the FileSystem interface for testing.
 type mockFS struct {
 	validateErr error
+	limitsErr   error
+	limits      validate.Limits
+	report      tokens.Report
+	removed     []string
+	findings    []secrets.Finding
+	secretMode  secrets.Mode
+	provenance  map[string]provenance.Record // Written records, by path.
+	// Mirroring: the record CheckMirror finds, or the error it returns, the
+	// paths Prune was asked to keep and those it reports removed.
+	previous     *provenance.Record
+	mirrorErr    error
+	mirrorMarker string
+	kept         []string
+	pruned       []string
+	// Incremental slicing: the cache loaded, the key it was loaded with and
+	// the files saved to the new cache.
+	cache    *cache.Cache
+	cacheKey string
+	cached   map[string]cache.File
 }
 
 func (m *mockFS) ValidateInputs(cfg validate.Config) error { return m.validateErr }
+func (m *mockFS) ValidateSlice(dir string, limits validate.Limits) error {
+	m.limits = limits
+	return m.limitsErr
+}
+func (m *mockFS) CountTokens(dir string, est tokens.Estimator) (tokens.Report, error) {
+	return m.report, nil
+}
+
+func (m *mockFS) ScanSecrets(dir string, mode secrets.Mode) ([]secrets.Finding, error) {
+	m.secretMode = mode
+	return m.findings, nil
+}
+
+func (m *mockFS) HashFile(name string) (string, error) { return "hash of " + name, nil }
+
+func (m *mockFS) WriteProvenance(dir, name string, rec provenance.Record) error {
+	if m.provenance == nil {
+		m.provenance = make(map[string]provenance.Record)
+	}
+	m.provenance[name] = rec
+	return nil
+}
+
+// CheckMirror records the marker it looks for and returns the configured
+// record of a previous slice.
+func (m *mockFS) CheckMirror(dir, marker string) (*provenance.Record, error) {
+	m.mirrorMarker = marker
+	return m.previous, m.mirrorErr
+}
+
+func (m *mockFS) Prune(dir string, keep []string) ([]string, error) {
+	m.kept = keep
+	return m.pruned, nil
+}
+
+func (m *mockFS) LoadCache(name, key string) (*cache.Cache, error) {
+	m.cacheKey = key
+	if m.cache == nil {
+		return cache.New(key), nil
+	}
+	return m.cache, nil
+}
+
+func (m *mockFS) SaveCache(name, dir, key string, files map[string]cache.File) error {
+	m.cached = files
+	return nil
+}
+
+// RemoveFiles records the removed paths and drops them from the report.
+func (m *mockFS) RemoveFiles(dir string, paths []string) error {
+	m.removed = append(m.removed, paths...)
+	var kept []tokens.FileCount
+	for _, f := range m.report.Files {
+		if !slices.Contains(paths, f.Path) {
+			kept = append(kept, f)
+			continue
+		}
+		m.report.Bytes -= f.Size
+		m.report.Total -= f.Tokens
+	}
+	m.report.Files = kept
+	return nil
+}
 
 // mockSlicer is a mock implementation of the Slicer interface for testing.
 type mockSlicer struct {
-	sliceErr error
+	sliceErr   error
+	listErr    error
+	explainErr error
+	decisions  []filter.Decision
+	entries    []slicer.Entry // Sliced, and listed instead of a single component.tsx.
+	sliced     bool
+	opts       []slicer.Options
+	revision   slicer.Revision
+	commitErr  error
+	unchanged  bool     // Commit reports that the branch already held the slice.
+	committed  []string // The repository and slice of each commit.
+	commitOpts gitrepo.CommitOptions
 }
 
-func (m *mockSlicer) Slice(source, output, manifestPath string) error { return m.sliceErr }
+func (m *mockSlicer) Slice(opts slicer.Options) ([]slicer.Entry, error) {
+	m.sliced = true
+	m.opts = append(m.opts, opts)
+	return m.entries, m.sliceErr
+}
+func (m *mockSlicer) List(opts slicer.Options) ([]slicer.Entry, error) {
+	m.opts = append(m.opts, opts)
+	if m.entries != nil {
+		return m.entries, m.listErr
+	}
+	return []slicer.Entry{{Path: "component.tsx", Size: 3}}, m.listErr
+}
+func (m *mockSlicer) Commit(repoDir, dir string, opts gitrepo.CommitOptions) (string, bool, error) {
+	m.committed = append(m.committed, repoDir, dir)
+	m.commitOpts = opts
+	return "0123abcd", !m.unchanged, m.commitErr
+}
+func (m *mockSlicer) Explain(opts slicer.Options, paths []string) ([]filter.Decision, error) {
+	return m.decisions, m.explainErr
+}
+func (m *mockSlicer) Revision(opts slicer.Options) (slicer.Revision, error) {
+	return m.revision, nil
+}
 
 // mockRemapper is a mock implementation of the Remapper interface for testing.
 type mockRemapper struct {
@@ -42,11 +175,16 @@ func (m *mockRemapper) ParseExtensionMap(mapStr string) (map[string]string, erro
 func (m *mockRemapper) RemapExtensions(dir string, extMap map[string]string) error {
 	return m.remapErr
 }
+func (m *mockRemapper) RemapPath(path string, extMap map[string]string) string { return path }
+func (m *mockRemapper) RemapFiles(dir string, paths []string, extMap map[string]string) error {
+	return m.remapErr
+}
 
 // TestRunUnit tests the error-handling paths of the run function using mocks.
 func TestRunUnit(t *testing.T) {
 	validArgs := []string{flagManifest, "m.txt", flagSource, "s", flagOutput, "o"}
 	remapArgs := []string{flagManifest, "m.txt", flagSource, "s", flagOutput, "o", "--extension-map", "tsx:ts"}
+	dryRunArgs := []string{flagManifest, "m.txt", flagSource, "s", "--dry-run", "--extension-map", "tsx:ts"}
 
 	testCases := []struct {
 		name     string
@@ -63,6 +201,9 @@ func TestRunUnit(t *testing.T) {
 		{"Remap operation fails", remapArgs, &mockFS{}, &mockSlicer{}, &mockRemapper{remapErr: errors.New("remap op failed")}, true},
 		{"Successful run", validArgs, &mockFS{}, &mockSlicer{}, &mockRemapper{}, false},
 		{"Successful run with remap", remapArgs, &mockFS{}, &mockSlicer{}, &mockRemapper{}, false},
+		{"Dry run listing fails", dryRunArgs, &mockFS{}, &mockSlicer{listErr: errors.New("list failed")}, &mockRemapper{}, true},
+		{"Dry run remap parsing fails", dryRunArgs, &mockFS{}, &mockSlicer{}, &mockRemapper{parseErr: errors.New("remap parse failed")}, true},
+		{"Successful dry run", dryRunArgs, &mockFS{}, &mockSlicer{}, &mockRemapper{}, false},
 	}
 
 	for _, tc := range testCases {
@@ -75,6 +216,74 @@ func TestRunUnit(t *testing.T) {
 	}
 }
 
+// TestRunDryRunDoesNotSlice verifies that a dry run only lists the selection.
+func TestRunDryRunDoesNotSlice(t *testing.T) {
+	var out bytes.Buffer
+	stdout = &out
+	t.Cleanup(func() { stdout = os.Stdout })
+
+	s := &mockSlicer{}
+	args := []string{flagManifest, "m.txt", flagSource, "s", "--dry-run"}
+	if err := run(args, &mockFS{}, s, &mockRemapper{}); err != nil {
+		t.Fatalf("run() returned an unexpected error: %v", err)
+	}
+	if s.sliced {
+		t.Error("a dry run must not call Slice")
+	}
+	if !strings.Contains(out.String(), "component.tsx") || !strings.Contains(out.String(), "1 files, 3 bytes") {
+		t.Errorf("unexpected dry run output:\n%s", out.String())
+	}
+}
+
+// TestRunDryRunReportsBinaryFiles verifies that the listing shows which binary
+// files are skipped or stubbed, and counts only what the slice would hold.
+func TestRunDryRunReportsBinaryFiles(t *testing.T) {
+	var out bytes.Buffer
+	stdout = &out
+	t.Cleanup(func() { stdout = os.Stdout })
+
+	s := &mockSlicer{entries: []slicer.Entry{
+		{Path: "main.go", Size: 12},
+		{Path: "logo.png", Size: 2048, Binary: "PNG image", Stub: true, Content: []byte("stub")},
+		{Path: "font.woff2", Size: 4096, Binary: "WOFF2 font", Skip: true},
+	}}
+	args := []string{flagManifest, "m.txt", flagSource, "s", "--dry-run", "--binary", "stub"}
+	if err := run(args, &mockFS{}, s, &mockRemapper{}); err != nil {
+		t.Fatalf("run() returned an unexpected error: %v", err)
+	}
+	if got := s.opts[0].Binary; got != binary.ModeStub {
+		t.Errorf("List() binary mode = %q, want %q", got, binary.ModeStub)
+	}
+	want := "        12  main.go\n" +
+		"         4  logo.png.txt (stub for PNG image, 2048 bytes)\n" +
+		"         -  font.woff2 (skipped: WOFF2 font, 4096 bytes)\n" +
+		"2 files, 16 bytes would be sliced from s\n"
+	if out.String() != want {
+		t.Errorf("dry run output = %q, want %q", out.String(), want)
+	}
+}
+
+func TestRunDryRunReportsTransforms(t *testing.T) {
+	var out bytes.Buffer
+	stdout = &out
+	t.Cleanup(func() { stdout = os.Stdout })
+
+	s := &mockSlicer{entries: []slicer.Entry{
+		{Path: "api.go", Size: 300, Transform: "goapi", Content: []byte("package api\n")},
+		{Path: "README.md", Size: 10},
+	}}
+	args := []string{flagManifest, "m.txt", flagSource, "s", "--dry-run"}
+	if err := run(args, &mockFS{}, s, &mockRemapper{}); err != nil {
+		t.Fatalf("run() returned an unexpected error: %v", err)
+	}
+	want := "        12  api.go (goapi of 300 bytes)\n" +
+		"        10  README.md\n" +
+		"2 files, 22 bytes would be sliced from s\n"
+	if out.String() != want {
+		t.Errorf("dry run output = %q, want %q", out.String(), want)
+	}
+}
+
 // TestRunIntegration is a simple end-to-end test.
 func TestRunIntegration(t *testing.T) {
 	rootDir, err := os.MkdirTemp("", "repo-slice-integration-*")
@@ -114,3 +323,950 @@ func TestRunIntegration(t *testing.T) {
 		t.Error("expected file 'component.ts' was not found in the output directory")
 	}
 }
+
+// TestRunDryRunIntegration verifies that a dry run reports remapped names and
+// sizes from a real source tree without creating the output directory.
+func TestRunDryRunIntegration(t *testing.T) {
+	var out bytes.Buffer
+	stdout = &out
+	t.Cleanup(func() { stdout = os.Stdout })
+
+	rootDir := t.TempDir()
+	sourceDir := filepath.Join(rootDir, "source")
+	if err := os.MkdirAll(filepath.Join(sourceDir, "src"), 0755); err != nil {
+		t.Fatalf("failed to create source dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(sourceDir, "src", "component.tsx"), []byte("export {}"), 0644); err != nil {
+		t.Fatalf("failed to create component.tsx: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(sourceDir, "notes.txt"), []byte("skip"), 0644); err != nil {
+		t.Fatalf("failed to create notes.txt: %v", err)
+	}
+	manifestPath := filepath.Join(rootDir, "manifest.txt")
+	if err := os.WriteFile(manifestPath, []byte("+ **/\n+ *.tsx\n- *"), 0644); err != nil {
+		t.Fatalf("failed to create manifest file: %v", err)
+	}
+	outputPath := filepath.Join(rootDir, "output")
+
+	args := []string{flagManifest, manifestPath, flagSource, sourceDir, flagOutput, outputPath, "--extension-map", "tsx:ts", "--dry-run"}
+	if err := run(args, &liveFS{}, &liveSlicer{}, &liveRemapper{}); err != nil {
+		t.Fatalf("run() failed on dry run: %v", err)
+	}
+
+	want := "         9  src/component.ts\n1 files, 9 bytes would be sliced from " + sourceDir + "\n"
+	if out.String() != want {
+		t.Errorf("dry run output = %q, want %q", out.String(), want)
+	}
+	if _, err := os.Stat(outputPath); !os.IsNotExist(err) {
+		t.Error("a dry run must not create the output directory")
+	}
+}
+
+// TestRunPassesGitOptions verifies that --gitignore, --tracked-only and --ref
+// reach the slicer.
+func TestRunPassesGitOptions(t *testing.T) {
+	s := &mockSlicer{}
+	args := []string{flagManifest, "m.txt", flagSource, "s", flagOutput, "o", "--gitignore", "--tracked-only", "--ref", "v2.3.0"}
+	if err := run(args, &mockFS{}, s, &mockRemapper{}); err != nil {
+		t.Fatalf("run() returned an unexpected error: %v", err)
+	}
+	if len(s.opts) != 1 || !s.opts[0].Gitignore || !s.opts[0].TrackedOnly || s.opts[0].Ref != "v2.3.0" {
+		t.Errorf("Slice() options = %+v, want Gitignore, TrackedOnly and Ref set", s.opts)
+	}
+}
+
+// TestRunOutputFormatErrors verifies that archive settings are checked before
+// anything is sliced.
+func TestRunOutputFormatErrors(t *testing.T) {
+	testCases := []struct {
+		name string
+		args []string
+	}{
+		{"Unknown format", []string{flagManifest, "m.txt", flagOutput, "o.rar", "--output-format", "rar"}},
+		{"Directory to standard output", []string{flagManifest, "m.txt", flagOutput, "-"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := &mockSlicer{}
+			if err := run(tc.args, &mockFS{}, s, &mockRemapper{}); err == nil {
+				t.Error("run() did not return an error")
+			}
+			if s.sliced {
+				t.Error("run() sliced despite an invalid output")
+			}
+		})
+	}
+}
+
+// TestRunArchiveIntegration verifies that an archive holds the remapped slice
+// and its provenance, and that repeated runs produce identical bytes, both in
+// a file and on standard output.
+func TestRunArchiveIntegration(t *testing.T) {
+	t.Setenv("SOURCE_DATE_EPOCH", "1700000000")
+	rootDir := t.TempDir()
+	sourceDir := filepath.Join(rootDir, "source")
+	if err := os.MkdirAll(filepath.Join(sourceDir, "src"), 0755); err != nil {
+		t.Fatalf("failed to create source dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(sourceDir, "src", "component.tsx"), []byte("export {}"), 0644); err != nil {
+		t.Fatalf("failed to create component.tsx: %v", err)
+	}
+	manifestPath := filepath.Join(rootDir, "manifest.txt")
+	if err := os.WriteFile(manifestPath, []byte("+ **/\n+ *.tsx\n- *"), 0644); err != nil {
+		t.Fatalf("failed to create manifest file: %v", err)
+	}
+	archivePath := filepath.Join(rootDir, "slice.zip")
+	args := []string{flagManifest, manifestPath, flagSource, sourceDir, "--extension-map", "tsx:ts", "--output-format", "zip", flagOutput}
+
+	if err := run(append(args, archivePath), &liveFS{}, &liveSlicer{}, &liveRemapper{}); err != nil {
+		t.Fatalf("run() failed: %v", err)
+	}
+	zr, err := zip.OpenReader(archivePath)
+	if err != nil {
+		t.Fatalf("failed to open archive: %v", err)
+	}
+	var names []string
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+	}
+	zr.Close()
+	if want := []string{".repo-slice/", ".repo-slice/provenance.json", "src/", "src/component.ts"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("archive holds %v, want %v", names, want)
+	}
+	first, _ := os.ReadFile(archivePath)
+
+	// Touching the source must not change the archive.
+	later := time.Now().Add(time.Hour)
+	_ = os.Chtimes(filepath.Join(sourceDir, "src", "component.tsx"), later, later)
+
+	var out bytes.Buffer
+	stdout = &out
+	t.Cleanup(func() { stdout = os.Stdout })
+	if err := run(append(args, "-"), &liveFS{}, &liveSlicer{}, &liveRemapper{}); err != nil {
+		t.Fatalf("run() failed writing to standard output: %v", err)
+	}
+	if !bytes.Equal(out.Bytes(), first) {
+		t.Error("archive written to standard output differs from the archive file")
+	}
+}
+
+// TestRunArchiveProvenanceIntegration builds the same archive of a commit
+// twice, with the default provenance record and no SOURCE_DATE_EPOCH, and
+// expects identical bytes and the commit's time as the creation time.
+func TestRunArchiveProvenanceIntegration(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	for _, kv := range [][2]string{{"GIT_CONFIG_GLOBAL", os.DevNull}, {"GIT_CONFIG_NOSYSTEM", "1"}, {"GIT_AUTHOR_NAME", "test"}, {"GIT_AUTHOR_EMAIL", "test@example.com"}, {"GIT_COMMITTER_NAME", "test"}, {"GIT_COMMITTER_EMAIL", "test@example.com"}, {"GIT_COMMITTER_DATE", "@1600000000 +0000"}, {"SOURCE_DATE_EPOCH", ""}} {
+		t.Setenv(kv[0], kv[1])
+	}
+	repo := t.TempDir()
+	for name, data := range map[string]string{"main.go": "package main\n", "manifest.txt": "+ *.go\n- *\n"} {
+		if err := os.WriteFile(filepath.Join(repo, name), []byte(data), 0644); err != nil {
+			t.Fatalf("failed to create %s: %v", name, err)
+		}
+	}
+	for _, args := range [][]string{{"init", "--quiet"}, {"add", "."}, {"commit", "--quiet", "-m", "Initial commit"}} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repo
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+		}
+	}
+
+	var archives [][]byte
+	for range 2 {
+		archivePath := filepath.Join(t.TempDir(), "slice.zip")
+		args := []string{flagManifest, filepath.Join(repo, "manifest.txt"), flagSource, repo, "--output-format", "zip", flagOutput, archivePath}
+		if err := run(args, &liveFS{}, &liveSlicer{}, &liveRemapper{}); err != nil {
+			t.Fatalf("run() failed: %v", err)
+		}
+		data, err := os.ReadFile(archivePath)
+		if err != nil {
+			t.Fatalf("failed to read archive: %v", err)
+		}
+		archives = append(archives, data)
+	}
+	if !bytes.Equal(archives[0], archives[1]) {
+		t.Error("archives of the same commit differ")
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(archives[0]), int64(len(archives[0])))
+	if err != nil {
+		t.Fatalf("failed to open archive: %v", err)
+	}
+	f, err := zr.Open(provenance.DefaultPath)
+	if err != nil {
+		t.Fatalf("archive holds no provenance record: %v", err)
+	}
+	defer f.Close()
+	rec, err := provenance.Read(f)
+	if err != nil {
+		t.Fatalf("failed to read provenance: %v", err)
+	}
+	if want := time.Unix(1600000000, 0).UTC(); !rec.Created.Equal(want) {
+		t.Errorf("record created at %v, want the commit time %v", rec.Created, want)
+	}
+}
+
+// TestRunPackIntegration verifies that a context pack shows the slice after
+// extension remapping.
+func TestRunPackIntegration(t *testing.T) {
+	var out bytes.Buffer
+	stdout = &out
+	t.Cleanup(func() { stdout = os.Stdout })
+
+	rootDir := t.TempDir()
+	sourceDir := filepath.Join(rootDir, "source")
+	if err := os.MkdirAll(filepath.Join(sourceDir, "src"), 0755); err != nil {
+		t.Fatalf("failed to create source dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(sourceDir, "src", "component.tsx"), []byte("export {}\n"), 0644); err != nil {
+		t.Fatalf("failed to create component.tsx: %v", err)
+	}
+	manifestPath := filepath.Join(rootDir, "manifest.txt")
+	if err := os.WriteFile(manifestPath, []byte("+ **/\n+ *.tsx\n- *"), 0644); err != nil {
+		t.Fatalf("failed to create manifest file: %v", err)
+	}
+
+	args := []string{flagManifest, manifestPath, flagSource, sourceDir, "--extension-map", "tsx:ts", "--output-format", "markdown", flagOutput, "-"}
+	if err := run(args, &liveFS{}, &liveSlicer{}, &liveRemapper{}); err != nil {
+		t.Fatalf("run() failed: %v", err)
+	}
+	for _, want := range []string{"└── src/\n    └── component.ts\n", "2. [`src/component.ts`](#srccomponentts)", "```typescript\nexport {}\n```"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("pack does not contain %q:\n%s", want, out.String())
+		}
+	}
+}
+
+// TestRunTokens verifies that the token count is reported and that
+// --max-tokens is enforced.
+func TestRunTokens(t *testing.T) {
+	report := tokens.Report{
+		Files: []tokens.FileCount{{Path: "big.go", Tokens: 900}, {Path: "small.go", Tokens: 100}},
+		Bytes: 4000,
+		Total: 1000,
+	}
+	validArgs := []string{flagManifest, "m.txt", flagSource, "s", flagOutput, "o"}
+
+	testCases := []struct {
+		name    string
+		args    []string
+		want    []string
+		wantErr string
+	}{
+		{"Summary", validArgs, []string{"Slice contains 2 files, 4000 bytes and an estimated 1000 tokens\n"}, ""},
+		{"Per-file counts", append(validArgs, "--show-tokens"), []string{"       900  big.go\n       100  small.go\n"}, ""},
+		{"Within budget", append(validArgs, "--max-tokens", "1000"), nil, ""},
+		{"Over budget", append(validArgs, "--max-tokens", "999"), nil, "1000 tokens exceeds the limit of 999"},
+		{"Negative budget", append(validArgs, "--max-tokens", "-1"), nil, "must not be negative"},
+		{"Built-in vocabulary", append(validArgs, "--token-vocab", "builtin"), []string{"an estimated 1000 tokens\n"}, ""},
+		{"Missing vocabulary", append(validArgs, "--token-vocab", filepath.Join(t.TempDir(), "missing")), nil, "token vocabulary"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var out bytes.Buffer
+			stdout = &out
+			t.Cleanup(func() { stdout = os.Stdout })
+
+			err := run(tc.args, &mockFS{report: report}, &mockSlicer{}, &mockRemapper{})
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("run() error = %v, want it to contain %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("run() returned an unexpected error: %v", err)
+			}
+			for _, want := range tc.want {
+				if !strings.Contains(out.String(), want) {
+					t.Errorf("output does not contain %q:\n%s", want, out.String())
+				}
+			}
+		})
+	}
+}
+
+// TestRunTokensOutput verifies that the token count is appended to the
+// --tokens-output file, except on a dry run.
+func TestRunTokensOutput(t *testing.T) {
+	var out bytes.Buffer
+	stdout = &out
+	t.Cleanup(func() { stdout = os.Stdout })
+	name := filepath.Join(t.TempDir(), "github_output")
+	if err := os.WriteFile(name, []byte("path=o\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	fsys := &mockFS{report: tokens.Report{Total: 1000}}
+	args := []string{flagManifest, "m.txt", flagSource, "s", flagOutput, "o", "--tokens-output", name}
+
+	for _, extra := range [][]string{nil, {"--dry-run"}} {
+		if err := run(append(args, extra...), fsys, &mockSlicer{}, &mockRemapper{}); err != nil {
+			t.Fatalf("run() returned an unexpected error: %v", err)
+		}
+	}
+	if got, _ := os.ReadFile(name); string(got) != "path=o\ntokens=1000\n" {
+		t.Errorf("tokens output = %q, want the count appended once", got)
+	}
+	args[len(args)-1] = t.TempDir()
+	if err := run(args, fsys, &mockSlicer{}, &mockRemapper{}); err == nil {
+		t.Error("run() did not report a tokens output it could not write")
+	}
+}
+
+// TestRunLimits verifies that --max-files and --max-size reach the post-slice
+// validation and that invalid limits are rejected before slicing.
+func TestRunLimits(t *testing.T) {
+	validArgs := []string{flagManifest, "m.txt", flagSource, "s", flagOutput, "o"}
+
+	t.Run("Limits are passed on", func(t *testing.T) {
+		fsys := &mockFS{}
+		if err := run(append(validArgs, "--max-files", "5000", "--max-size", "1.5M"), fsys, &mockSlicer{}, &mockRemapper{}); err != nil {
+			t.Fatalf("run() returned an unexpected error: %v", err)
+		}
+		if want := (validate.Limits{MaxFiles: 5000, MaxSize: 3 << 19}); fsys.limits != want {
+			t.Errorf("ValidateSlice() limits = %+v, want %+v", fsys.limits, want)
+		}
+	})
+
+	t.Run("Limits exceeded", func(t *testing.T) {
+		fsys := &mockFS{limitsErr: errors.New("file count (2) exceeds the maximum allowed (1)")}
+		err := run(append(validArgs, "--max-files", "1"), fsys, &mockSlicer{}, &mockRemapper{})
+		if err == nil || !strings.Contains(err.Error(), "exceeds the maximum allowed") {
+			t.Errorf("run() error = %v, want the limit violation", err)
+		}
+	})
+
+	for _, args := range [][]string{{"--max-files", "-1"}, {"--max-size", "10X"}} {
+		t.Run("Invalid "+args[0], func(t *testing.T) {
+			s := &mockSlicer{}
+			if err := run(append(validArgs, args...), &mockFS{}, s, &mockRemapper{}); err == nil {
+				t.Error("run() did not return an error")
+			}
+			if s.sliced {
+				t.Error("run() sliced despite an invalid limit")
+			}
+		})
+	}
+}
+
+// TestRunOnOverflow verifies that --on-overflow=drop removes the
+// lowest-priority files until the slice fits and reports what was dropped.
+func TestRunOnOverflow(t *testing.T) {
+	dir := t.TempDir()
+	list := filepath.Join(dir, "priority.txt")
+	if err := os.WriteFile(list, []byte("# Keep the command.\n100 /cmd/\n-10 *_test.go\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	newReport := func() tokens.Report {
+		return tokens.Report{
+			Files: []tokens.FileCount{
+				{Path: "cmd/main.go", Size: 400, Tokens: 100},
+				{Path: "lib/lib.go", Size: 300, Tokens: 75},
+				{Path: "lib/lib_test.go", Size: 200, Tokens: 50},
+			},
+			Bytes: 900,
+			Total: 225,
+		}
+	}
+	validArgs := []string{flagManifest, "m.txt", flagSource, "s", flagOutput, "o", "--priority", list, "--provenance", "off"}
+
+	testCases := []struct {
+		name        string
+		args        []string
+		wantRemoved []string
+		wantErr     string
+	}{
+		{"Fits", append(validArgs, "--on-overflow", "drop", "--max-tokens", "225"), nil, ""},
+		{"Drop by priority", append(validArgs, "--on-overflow", "drop", "--max-tokens", "180"), []string{"lib/lib_test.go"}, ""},
+		{"Drop by size", append(validArgs, "--on-overflow", "drop", "--max-size", "450"), []string{"lib/lib_test.go", "lib/lib.go"}, ""},
+		{"Fail by default", append(validArgs, "--max-tokens", "180"), nil, "exceeds the limit of 180"},
+		{"Invalid policy", append(validArgs, "--on-overflow", "shrink"), nil, "invalid overflow policy"},
+		{"Missing list", []string{flagManifest, "m.txt", flagSource, "s", flagOutput, "o", "--priority", filepath.Join(dir, "missing")}, nil, "priority list"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var out bytes.Buffer
+			stdout = &out
+			t.Cleanup(func() { stdout = os.Stdout })
+
+			fsys := &mockFS{report: newReport()}
+			err := run(tc.args, fsys, &mockSlicer{}, &mockRemapper{})
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("run() error = %v, want it to contain %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("run() returned an unexpected error: %v", err)
+			}
+			if !slices.Equal(fsys.removed, tc.wantRemoved) {
+				t.Errorf("removed = %v, want %v", fsys.removed, tc.wantRemoved)
+			}
+			if len(tc.wantRemoved) > 0 && !strings.Contains(out.String(), fmt.Sprintf("Dropped %d files to fit the budget:", len(tc.wantRemoved))) {
+				t.Errorf("output does not report the dropped files:\n%s", out.String())
+			}
+		})
+	}
+}
+
+// TestLiveRemoveFiles verifies that removing files also removes the
+// directories they leave empty, but never the slice root.
+func TestLiveRemoveFiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a/b/c.go", "a/d.go", "e.go"} {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := (&liveFS{}).RemoveFiles(dir, []string{"a/b/c.go", "e.go"}); err != nil {
+		t.Fatalf("RemoveFiles() returned an error: %v", err)
+	}
+	for name, want := range map[string]bool{"a/b": false, "a/d.go": true, "e.go": false, ".": true} {
+		_, err := os.Stat(filepath.Join(dir, filepath.FromSlash(name)))
+		if exists := err == nil; exists != want {
+			t.Errorf("%s exists = %v, want %v", name, exists, want)
+		}
+	}
+}
+
+// TestRunPerFileLimits verifies that the per-file limits reach the slicer and
+// that truncated files are reported, both in a dry run and after slicing.
+func TestRunPerFileLimits(t *testing.T) {
+	entries := []slicer.Entry{
+		{Path: "main.go", Size: 12},
+		{Path: "fixtures/big.json", Size: 20 << 20, Oversized: true, Content: []byte("[\n[truncated]\n")},
+		{Path: "dist/bundle.js", Size: 8 << 20, Oversized: true, Skip: true},
+	}
+	validArgs := []string{flagManifest, "m.txt", flagSource, "s", flagOutput, "o", "--max-file-size", "1M", "--max-file-lines", "500", "--oversized", "truncate"}
+
+	t.Run("Dry run", func(t *testing.T) {
+		var out bytes.Buffer
+		stdout = &out
+		t.Cleanup(func() { stdout = os.Stdout })
+
+		s := &mockSlicer{entries: entries}
+		if err := run(append(validArgs, "--dry-run"), &mockFS{}, s, &mockRemapper{}); err != nil {
+			t.Fatalf("run() returned an unexpected error: %v", err)
+		}
+		opts := s.opts[0]
+		if opts.MaxFileSize != 1<<20 || opts.MaxFileLines != 500 || opts.Oversized != truncate.ModeTruncate {
+			t.Errorf("List() options = %+v, want the per-file limits", opts)
+		}
+		want := "        12  main.go\n" +
+			"        14  fixtures/big.json (truncated from 20971520 bytes)\n" +
+			"         -  dist/bundle.js (skipped: over the per-file limits, 8388608 bytes)\n" +
+			"2 files, 26 bytes would be sliced from s\n"
+		if out.String() != want {
+			t.Errorf("dry run output = %q, want %q", out.String(), want)
+		}
+	})
+
+	t.Run("Summary", func(t *testing.T) {
+		var out bytes.Buffer
+		stdout = &out
+		t.Cleanup(func() { stdout = os.Stdout })
+
+		if err := run(validArgs, &mockFS{}, &mockSlicer{entries: entries}, &mockRemapper{}); err != nil {
+			t.Fatalf("run() returned an unexpected error: %v", err)
+		}
+		want := "Truncated 1 files to the per-file limits, so only their start is included:\n" +
+			"        14  fixtures/big.json (truncated from 20971520 bytes)\n"
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output does not contain %q:\n%s", want, out.String())
+		}
+	})
+
+	t.Run("Invalid size", func(t *testing.T) {
+		s := &mockSlicer{}
+		if err := run([]string{flagManifest, "m.txt", flagSource, "s", flagOutput, "o", "--max-file-size", "big"}, &mockFS{}, s, &mockRemapper{}); err == nil {
+			t.Error("run() did not return an error")
+		}
+		if s.sliced {
+			t.Error("run() sliced despite an invalid limit")
+		}
+	})
+}
+
+// TestRunSecrets verifies that each secrets mode reaches the scan, and that
+// findings are reported by location without their values.
+func TestRunSecrets(t *testing.T) {
+	findings := []secrets.Finding{
+		{Path: ".env", Line: 2, Kind: "environment file value"},
+		{Path: "deploy/id_rsa", Line: 1, Kind: "private key"},
+		{Path: "deploy/id_rsa", Line: 30, Kind: "private key"},
+	}
+	validArgs := []string{flagManifest, "m.txt", flagSource, "s", flagOutput, "o"}
+
+	testCases := []struct {
+		name     string
+		args     []string
+		wantMode secrets.Mode
+		want     string
+		wantErr  string
+	}{
+		{"Off by default", validArgs, "", "", ""},
+		{"Fail", append(validArgs, "--secrets", "fail"), secrets.ModeFail, "", "slice contains 3 possible secrets:\n  .env:2: environment file value\n"},
+		{"Redact", append(validArgs, "--secrets", "redact"), secrets.ModeRedact, "Redacted 3 possible secrets:\n  .env:2: environment file value\n", ""},
+		{"Exclude", append(validArgs, "--secrets", "exclude"), secrets.ModeExclude, "Excluded 2 files containing possible secrets:\n  .env:2: environment file value\n", ""},
+		{"Invalid mode", append(validArgs, "--secrets", "warn"), "", "", "invalid secrets mode"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var out bytes.Buffer
+			stdout = &out
+			t.Cleanup(func() { stdout = os.Stdout })
+
+			fsys := &mockFS{findings: findings}
+			err := run(tc.args, fsys, &mockSlicer{}, &mockRemapper{})
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("run() error = %v, want it to contain %q", err, tc.wantErr)
+				}
+			} else if err != nil {
+				t.Fatalf("run() returned an unexpected error: %v", err)
+			}
+			if fsys.secretMode != tc.wantMode {
+				t.Errorf("ScanSecrets() mode = %q, want %q", fsys.secretMode, tc.wantMode)
+			}
+			if !strings.Contains(out.String(), tc.want) {
+				t.Errorf("output does not contain %q:\n%s", tc.want, out.String())
+			}
+		})
+	}
+}
+
+func TestRunProvenance(t *testing.T) {
+	validArgs := []string{flagManifest, "m.txt", flagSource, "s", flagOutput, "o"}
+	report := tokens.Report{Files: []tokens.FileCount{{Path: "a.go", Size: 40}, {Path: "b.go", Size: 2}}, Bytes: 42}
+	revision := slicer.Revision{Commit: "0123456789abcdef0123456789abcdef01234567", Time: time.Unix(1600000000, 0).UTC(), Dirty: true}
+
+	testCases := []struct {
+		name     string
+		args     []string
+		epoch    string // SOURCE_DATE_EPOCH.
+		wantPath string // "" if no record is written.
+		wantErr  bool
+	}{
+		{"Written by default", validArgs, "1700000000", provenance.DefaultPath, false},
+		{"Created at the commit time", validArgs, "", provenance.DefaultPath, false},
+		{"Custom path", append(validArgs, "--provenance", "meta/slice.json"), "1700000000", "meta/slice.json", false},
+		{"Disabled", append(validArgs, "--provenance", "off"), "1700000000", "", false},
+		{"Path outside the slice", append(validArgs, "--provenance", "../slice.json"), "1700000000", "", true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("SOURCE_DATE_EPOCH", tc.epoch)
+			fsys := &mockFS{report: report}
+			s := &mockSlicer{revision: revision}
+			err := run(tc.args, fsys, s, &mockRemapper{})
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("run() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if tc.wantPath == "" {
+				if len(fsys.provenance) > 0 {
+					t.Errorf("run() wrote provenance %v, want none", fsys.provenance)
+				}
+				return
+			}
+			if !slices.Equal(fsys.removed, []string{tc.wantPath}) {
+				t.Errorf("run() removed %v, want the previous record at %s", fsys.removed, tc.wantPath)
+			}
+			want := provenance.Record{
+				Schema:   provenance.Schema,
+				Version:  toolVersion(),
+				Created:  revision.Time,
+				Source:   provenance.Source{Commit: revision.Commit, Dirty: true},
+				Manifest: provenance.Manifest{Path: "m.txt", SHA256: "hash of m.txt"},
+				Files:    2,
+				Bytes:    42,
+			}
+			if tc.epoch != "" {
+				want.Created = time.Unix(1700000000, 0).UTC()
+			}
+			if got := fsys.provenance[tc.wantPath]; !reflect.DeepEqual(got, want) {
+				t.Errorf("provenance at %s = %+v, want %+v", tc.wantPath, got, want)
+			}
+		})
+	}
+}
+
+// TestRunProvenanceIntegration re-slices into an output that already holds a
+// record, which must not count against the slice's limits.
+func TestRunProvenanceIntegration(t *testing.T) {
+	rootDir := t.TempDir()
+	sourceDir := filepath.Join(rootDir, "source")
+	for _, name := range []string{"a.go", "b.go"} {
+		if err := os.MkdirAll(sourceDir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(sourceDir, name), []byte("package a\n"), 0644); err != nil {
+			t.Fatalf("failed to create %s: %v", name, err)
+		}
+	}
+	manifestPath := filepath.Join(rootDir, "manifest.txt")
+	if err := os.WriteFile(manifestPath, []byte("+ *.go\n- *\n"), 0644); err != nil {
+		t.Fatalf("failed to create manifest file: %v", err)
+	}
+	var out bytes.Buffer
+	stdout = &out
+	t.Cleanup(func() { stdout = os.Stdout })
+
+	for _, extra := range [][]string{nil, {"--cache", filepath.Join(rootDir, "cache.json")}} {
+		outputDir := t.TempDir()
+		args := slices.Concat([]string{flagManifest, manifestPath, flagSource, sourceDir, flagOutput, outputDir, "--max-files", "2", "--max-size", "20"}, extra)
+		for i := range 2 {
+			out.Reset()
+			if err := run(args, &liveFS{}, &liveSlicer{}, &liveRemapper{}); err != nil {
+				t.Fatalf("run %d with %v failed: %v", i+1, extra, err)
+			}
+			if !strings.Contains(out.String(), "Slice contains 2 files, 20 bytes") {
+				t.Errorf("run %d with %v counted the record:\n%s", i+1, extra, out.String())
+			}
+		}
+		if _, err := os.Stat(filepath.Join(outputDir, filepath.FromSlash(provenance.DefaultPath))); err != nil {
+			t.Errorf("the record was not rewritten: %v", err)
+		}
+	}
+}
+
+func TestRunMirror(t *testing.T) {
+	validArgs := []string{flagManifest, "m.txt", flagSource, "s", flagOutput, "o"}
+	mirrorArgs := slices.Concat(validArgs, []string{"--mirror"})
+	entries := []slicer.Entry{
+		{Path: "src", Mode: fs.ModeDir | 0755},
+		{Path: "src/app.go", Mode: 0644},
+		{Path: "assets/logo.png", Skip: true},
+		{Path: "assets/font.woff2", Stub: true},
+	}
+	pruned := []string{".repo-slice/", "docs/", "src/old.go"}
+
+	testCases := []struct {
+		name     string
+		args     []string
+		fs       *mockFS
+		wantKept []string
+		want     string
+		wantErr  string
+	}{
+		{"Off by default", validArgs, &mockFS{pruned: pruned}, nil, "", ""},
+		{"Mirrored", mirrorArgs, &mockFS{pruned: pruned}, []string{"src", "src/app.go", "assets/font.woff2.txt"}, "Removed 2 paths the manifest no longer selects from the output:\n  docs/\n  src/old.go\n", ""},
+		{"Output without a previous slice", mirrorArgs, &mockFS{mirrorErr: errors.New("refusing to mirror into o")}, nil, "", "refusing to mirror"},
+		{"Archive output", slices.Concat(mirrorArgs, []string{"--output-format", "zip"}), &mockFS{}, nil, "", "cannot be used with --output-format"},
+		{"Without provenance", slices.Concat(mirrorArgs, []string{"--provenance", "off"}), &mockFS{}, nil, "", "cannot be used with --provenance=off"},
+		{"Rsync engine", slices.Concat(mirrorArgs, []string{"--engine", "rsync"}), &mockFS{}, nil, "", "the rsync engine cannot mirror"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var out bytes.Buffer
+			stdout = &out
+			t.Cleanup(func() { stdout = os.Stdout })
+
+			err := run(tc.args, tc.fs, &mockSlicer{entries: entries}, &mockRemapper{})
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("run() error = %v, want it to contain %q", err, tc.wantErr)
+				}
+			} else if err != nil {
+				t.Fatalf("run() returned an unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(tc.fs.kept, tc.wantKept) {
+				t.Errorf("Prune() kept %v, want %v", tc.fs.kept, tc.wantKept)
+			}
+			if !strings.Contains(out.String(), tc.want) {
+				t.Errorf("output does not contain %q:\n%s", tc.want, out.String())
+			}
+		})
+	}
+
+	t.Run("Failed run keeps the output marked", func(t *testing.T) {
+		previous := &provenance.Record{Schema: provenance.Schema, Files: 7}
+		fsys := &mockFS{previous: previous, limitsErr: errors.New("too many files")}
+		if err := run(mirrorArgs, fsys, &mockSlicer{entries: entries}, &mockRemapper{}); err == nil {
+			t.Fatal("run() succeeded, want the limits error")
+		}
+		if got := fsys.provenance[provenance.DefaultPath]; got.Files != 7 {
+			t.Errorf("provenance after a failed run = %+v, want the previous record", got)
+		}
+	})
+}
+
+// TestRunMirrorIntegration re-slices into the same output with a narrower
+// manifest, and verifies that only the current selection remains.
+func TestRunMirrorIntegration(t *testing.T) {
+	rootDir := t.TempDir()
+	sourceDir := filepath.Join(rootDir, "source")
+	for _, name := range []string{"src/a.tsx", "src/b.tsx", "docs/guide.md"} {
+		file := filepath.Join(sourceDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatalf("failed to create source dir: %v", err)
+		}
+		if err := os.WriteFile(file, []byte(name), 0644); err != nil {
+			t.Fatalf("failed to create %s: %v", name, err)
+		}
+	}
+	manifestPath := filepath.Join(rootDir, "manifest.txt")
+	outputDir := filepath.Join(rootDir, "output")
+	args := []string{flagManifest, manifestPath, flagSource, sourceDir, flagOutput, outputDir, "--extension-map", "tsx:ts", "--mirror"}
+
+	if err := os.WriteFile(manifestPath, []byte("+ */\n+ *.tsx\n+ *.md\n- *"), 0644); err != nil {
+		t.Fatalf("failed to create manifest file: %v", err)
+	}
+	if err := run(args, &liveFS{}, &liveSlicer{}, &liveRemapper{}); err != nil {
+		t.Fatalf("first run() failed: %v", err)
+	}
+
+	if err := os.WriteFile(manifestPath, []byte("+ src/\n+ *.tsx\n- *"), 0644); err != nil {
+		t.Fatalf("failed to update manifest file: %v", err)
+	}
+	if err := os.Remove(filepath.Join(sourceDir, "src", "b.tsx")); err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	stdout = &out
+	t.Cleanup(func() { stdout = os.Stdout })
+	if err := run(args, &liveFS{}, &liveSlicer{}, &liveRemapper{}); err != nil {
+		t.Fatalf("second run() failed: %v", err)
+	}
+
+	var got []string
+	_ = filepath.WalkDir(outputDir, func(p string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			rel, _ := filepath.Rel(outputDir, p)
+			got = append(got, filepath.ToSlash(rel))
+		}
+		return err
+	})
+	if want := []string{".repo-slice/provenance.json", "src/a.ts"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("output holds %v, want %v", got, want)
+	}
+	if want := "Removed 2 paths the manifest no longer selects from the output:\n  docs/\n  src/b.ts\n"; !strings.Contains(out.String(), want) {
+		t.Errorf("output does not contain %q:\n%s", want, out.String())
+	}
+
+	// A directory that never held a slice is left alone.
+	other := t.TempDir()
+	if err := os.WriteFile(filepath.Join(other, "notes.txt"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	args[5] = other
+	if err := run(args, &liveFS{}, &liveSlicer{}, &liveRemapper{}); err == nil {
+		t.Error("run() mirrored into a directory without a previous slice")
+	}
+	if _, err := os.Stat(filepath.Join(other, "notes.txt")); err != nil {
+		t.Errorf("mirroring removed a file it did not create: %v", err)
+	}
+}
+
+// TestRunMirrorTypeChange re-slices into an output where a file has become a
+// directory and a directory has become a file.
+func TestRunMirrorTypeChange(t *testing.T) {
+	rootDir := t.TempDir()
+	sourceDir := filepath.Join(rootDir, "source")
+	outputDir := filepath.Join(rootDir, "output")
+	manifestPath := filepath.Join(rootDir, "manifest.txt")
+	if err := os.WriteFile(manifestPath, []byte("+ *\n"), 0644); err != nil {
+		t.Fatalf("failed to create manifest file: %v", err)
+	}
+	args := []string{flagManifest, manifestPath, flagSource, sourceDir, flagOutput, outputDir, "--mirror"}
+	// slice replaces the source with files, then slices it.
+	slice := func(files ...string) {
+		t.Helper()
+		if err := os.RemoveAll(sourceDir); err != nil {
+			t.Fatal(err)
+		}
+		for _, name := range files {
+			file := filepath.Join(sourceDir, filepath.FromSlash(name))
+			if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+				t.Fatalf("failed to create source dir: %v", err)
+			}
+			if err := os.WriteFile(file, []byte(name), 0644); err != nil {
+				t.Fatalf("failed to create %s: %v", name, err)
+			}
+		}
+		if err := run(args, &liveFS{}, &liveSlicer{}, &liveRemapper{}); err != nil {
+			t.Fatalf("run() failed: %v", err)
+		}
+	}
+	var out bytes.Buffer
+	stdout = &out
+	t.Cleanup(func() { stdout = os.Stdout })
+
+	slice("foo", "bar/old.go")
+	slice("foo/new.go", "bar")
+
+	for name, want := range map[string]string{"foo/new.go": "foo/new.go", "bar": "bar"} {
+		if got, err := os.ReadFile(filepath.Join(outputDir, filepath.FromSlash(name))); err != nil || string(got) != want {
+			t.Errorf("%s = %q, %v; want %q", name, got, err, want)
+		}
+	}
+}
+
+func TestRunCache(t *testing.T) {
+	validArgs := []string{flagManifest, "m.txt", flagSource, "s", flagOutput, "o", "--provenance", "off"}
+	entries := []slicer.Entry{
+		{Path: "src", Mode: fs.ModeDir | 0755},
+		{Path: "src/app.go", Size: 3, Hash: "abc", Reused: true},
+		{Path: "src/new.go", Size: 4, Hash: "def"},
+		{Path: "assets/logo.png", Skip: true},
+	}
+	previous := cache.New("")
+	previous.Files["src/app.go"] = cache.File{Output: "src/app.go"}
+	previous.Files["src/old.go"] = cache.File{Output: "src/old.go"}
+
+	testCases := []struct {
+		name        string
+		args        []string
+		wantCached  []string
+		wantRemoved []string
+		want        string
+		wantErr     string
+	}{
+		{"Off by default", validArgs, nil, nil, "", ""},
+		{"Incremental", slices.Concat(validArgs, []string{"--cache", "slice-cache.json"}), []string{"src/app.go", "src/new.go"}, []string{"src/old.go"}, "Reused 1 unchanged files from the previous slice, copied 1 and deleted 1 no longer selected\n", ""},
+		{"Archive output", slices.Concat(validArgs, []string{"--cache", "slice-cache.json", "--output-format", "zip"}), nil, nil, "", "cannot be used with --output-format"},
+		{"Cache inside the output", slices.Concat(validArgs, []string{"--cache", "o/cache.json"}), nil, nil, "", "must be outside the output directory"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var out bytes.Buffer
+			stdout = &out
+			t.Cleanup(func() { stdout = os.Stdout })
+
+			fsys := &mockFS{cache: previous}
+			err := run(tc.args, fsys, &mockSlicer{entries: entries}, &mockRemapper{})
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("run() error = %v, want it to contain %q", err, tc.wantErr)
+				}
+			} else if err != nil {
+				t.Fatalf("run() returned an unexpected error: %v", err)
+			}
+			var cached []string
+			for name := range fsys.cached {
+				cached = append(cached, name)
+			}
+			slices.Sort(cached)
+			if !reflect.DeepEqual(cached, tc.wantCached) {
+				t.Errorf("SaveCache() files = %v, want %v", cached, tc.wantCached)
+			}
+			if !reflect.DeepEqual(fsys.removed, tc.wantRemoved) {
+				t.Errorf("RemoveFiles() removed %v, want %v", fsys.removed, tc.wantRemoved)
+			}
+			if !strings.Contains(out.String(), tc.want) {
+				t.Errorf("output does not contain %q:\n%s", tc.want, out.String())
+			}
+		})
+	}
+}
+
+// TestRunCacheSecretsIntegration re-slices with secrets redacted and then
+// with scanning off, which must copy the original files again rather than
+// reuse the redacted copies.
+func TestRunCacheSecretsIntegration(t *testing.T) {
+	rootDir := t.TempDir()
+	sourceDir := filepath.Join(rootDir, "source")
+	if err := os.MkdirAll(sourceDir, 0755); err != nil {
+		t.Fatalf("failed to create source dir: %v", err)
+	}
+	content := "key = " + "AKIA" + "IOSFODNN7EXAMPLE\n"
+	if err := os.WriteFile(filepath.Join(sourceDir, "s.md"), []byte(content), 0644); err != nil {
+		t.Fatalf("failed to create s.md: %v", err)
+	}
+	manifestPath := filepath.Join(rootDir, "manifest.txt")
+	if err := os.WriteFile(manifestPath, []byte("+ *.md\n- *\n"), 0644); err != nil {
+		t.Fatalf("failed to create manifest file: %v", err)
+	}
+	outputDir := filepath.Join(rootDir, "output")
+	args := []string{flagManifest, manifestPath, flagSource, sourceDir, flagOutput, outputDir, "--cache", filepath.Join(rootDir, "cache.json")}
+
+	var out bytes.Buffer
+	stdout = &out
+	t.Cleanup(func() { stdout = os.Stdout })
+	for _, mode := range []string{"redact", "off"} {
+		if err := run(append(args, "--secrets", mode), &liveFS{}, &liveSlicer{}, &liveRemapper{}); err != nil {
+			t.Fatalf("run() with --secrets=%s failed: %v", mode, err)
+		}
+	}
+	if got, _ := os.ReadFile(filepath.Join(outputDir, "s.md")); string(got) != content {
+		t.Errorf("s.md = %q after scanning was turned off, want the original %q", got, content)
+	}
+}
+
+// TestRunIncrementalIntegration re-slices a changing source into the same
+// output, and verifies that only changed files are copied.
+func TestRunIncrementalIntegration(t *testing.T) {
+	rootDir := t.TempDir()
+	sourceDir := filepath.Join(rootDir, "source")
+	writeSource := func(name, data string) {
+		t.Helper()
+		file := filepath.Join(sourceDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatalf("failed to create source dir: %v", err)
+		}
+		if err := os.WriteFile(file, []byte(data), 0644); err != nil {
+			t.Fatalf("failed to create %s: %v", name, err)
+		}
+	}
+	writeSource("src/a.tsx", "a")
+	writeSource("src/b.tsx", "b")
+	writeSource("docs/guide.md", "guide")
+	manifestPath := filepath.Join(rootDir, "manifest.txt")
+	if err := os.WriteFile(manifestPath, []byte("+ */\n+ *.tsx\n+ *.md\n- *"), 0644); err != nil {
+		t.Fatalf("failed to create manifest file: %v", err)
+	}
+	outputDir := filepath.Join(rootDir, "output")
+	args := []string{flagManifest, manifestPath, flagSource, sourceDir, flagOutput, outputDir, "--extension-map", "tsx:ts", "--cache", filepath.Join(rootDir, "cache.json")}
+
+	var out bytes.Buffer
+	stdout = &out
+	t.Cleanup(func() { stdout = os.Stdout })
+	runSlice := func(want string) {
+		t.Helper()
+		out.Reset()
+		if err := run(args, &liveFS{}, &liveSlicer{}, &liveRemapper{}); err != nil {
+			t.Fatalf("run() failed: %v", err)
+		}
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output does not contain %q:\n%s", want, out.String())
+		}
+	}
+
+	runSlice("Reused 0 unchanged files from the previous slice, copied 3 and deleted 0 no longer selected\n")
+	runSlice("Reused 3 unchanged files from the previous slice, copied 0 and deleted 0 no longer selected\n")
+
+	writeSource("src/a.tsx", "a, edited")
+	writeSource("src/c.tsx", "c")
+	if err := os.Remove(filepath.Join(sourceDir, "src", "b.tsx")); err != nil {
+		t.Fatal(err)
+	}
+	runSlice("Reused 1 unchanged files from the previous slice, copied 2 and deleted 1 no longer selected\n")
+
+	got := make(map[string]string)
+	_ = filepath.WalkDir(outputDir, func(p string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() && !strings.HasPrefix(d.Name(), "provenance") {
+			rel, _ := filepath.Rel(outputDir, p)
+			data, _ := os.ReadFile(p)
+			got[filepath.ToSlash(rel)] = string(data)
+		}
+		return err
+	})
+	want := map[string]string{"docs/guide.md": "guide", "src/a.ts": "a, edited", "src/c.ts": "c"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("output holds %v, want %v", got, want)
+	}
+}
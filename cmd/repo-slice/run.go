@@ -0,0 +1,116 @@
+// file: cmd/repo-slice/run.go
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path"
+
+	"github.com/AlienHeadwars/repo-slice/internal/config"
+	"github.com/AlienHeadwars/repo-slice/internal/gitrepo"
+	"github.com/AlienHeadwars/repo-slice/internal/provenance"
+)
+
+// runConfigOptions holds the options of the run command.
+type runConfigOptions struct {
+	ConfigPath string
+	DryRun     bool
+	Names      []string
+}
+
+// runConfig implements the run command, which creates every slice described
+// in a configuration file, or only the named ones.
+func runConfig(args []string, fsys FileSystem, slicer Slicer, remapper Remapper) error {
+	opts, err := parseRunArgs(args)
+	if err != nil {
+		return err
+	}
+
+	file, err := config.Load(opts.ConfigPath, config.LiveFS{})
+	if err != nil {
+		return err
+	}
+	slices, err := file.Select(opts.Names)
+	if err != nil {
+		return err
+	}
+
+	for _, s := range slices {
+		if opts.DryRun {
+			fmt.Fprintf(stdout, "Slice %s:\n", s.Name)
+		}
+		summary, err := runConfigSlice(Config{Slice: s, DryRun: opts.DryRun}, fsys, slicer, remapper)
+		if err != nil {
+			return fmt.Errorf("slice %s: %w", s.Name, err)
+		}
+		if opts.DryRun || s.OutputPath == stdoutPath {
+			continue
+		}
+		fmt.Fprintf(stdout, "Successfully created slice %s in %s\n", s.Name, s.OutputPath)
+		printSummary(summary, false)
+		if s.Branch != "" {
+			if err := commitConfigSlice(s, slicer); err != nil {
+				return fmt.Errorf("slice %s: %w", s.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// commitConfigSlice commits a created slice to its branch of the repository
+// it was sliced from, as the commit command would.
+func commitConfigSlice(s config.Slice, slicer Slicer) error {
+	opts := gitrepo.CommitOptions{Branch: s.Branch, Message: defaultCommitMessage}
+	switch s.Provenance {
+	case "":
+		opts.Ignore = provenance.DefaultPath
+	case provenance.Off:
+	default:
+		opts.Ignore = path.Clean(s.Provenance)
+	}
+	commit, changed, err := slicer.Commit(s.SourcePath, s.OutputPath, opts)
+	if err != nil {
+		return fmt.Errorf("failed to commit slice: %w", err)
+	}
+	printCommit(s.OutputPath, s.Branch, commit, changed)
+	return nil
+}
+
+// runConfigSlice creates a single configured slice. Inline rules are written
+// to a temporary manifest first, because the slicer reads manifests by path.
+func runConfigSlice(cfg Config, fsys FileSystem, slicer Slicer, remapper Remapper) (sliceSummary, error) {
+	if cfg.Rules != "" {
+		f, err := os.CreateTemp("", "repo-slice-manifest-*")
+		if err != nil {
+			return sliceSummary{}, fmt.Errorf("failed to write inline rules: %w", err)
+		}
+		defer os.Remove(f.Name())
+		_, err = f.WriteString(cfg.Rules)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return sliceSummary{}, fmt.Errorf("failed to write inline rules: %w", err)
+		}
+		cfg.ManifestPath = f.Name()
+	}
+	return createSlice(cfg, fsys, slicer, remapper)
+}
+
+// parseRunArgs parses the arguments of the run command. Any positional
+// arguments are the names of the slices to create.
+func parseRunArgs(args []string) (runConfigOptions, error) {
+	var opts runConfigOptions
+	fs := flag.NewFlagSet("repo-slice run", flag.ContinueOnError)
+
+	fs.StringVar(&opts.ConfigPath, "config", config.DefaultPath, "Path to the configuration file")
+	fs.BoolVar(&opts.DryRun, "dry-run", false, "Print the files each slice selects without writing anything")
+
+	if err := fs.Parse(args); err != nil {
+		return runConfigOptions{}, err
+	}
+
+	opts.Names = fs.Args()
+	return opts, nil
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/AlienHeadwars/repo-slice/internal/gitrepo"
+	"github.com/AlienHeadwars/repo-slice/internal/provenance"
+)
+
+// writeConfig writes a configuration file to a temporary directory and returns
+// its path.
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), ".repo-slice.yaml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	return path
+}
+
+const testConfig = `
+slices:
+  - name: app
+    manifest: app.txt
+    output: out/app
+    branch: ai/app
+    max-files: 10
+    max-size: 1K
+  - name: docs
+    rules: "+ *.md\n- *\n"
+    output: out/docs
+`
+
+func TestRunConfigUnit(t *testing.T) {
+	var out bytes.Buffer
+	stdout = &out
+	t.Cleanup(func() { stdout = os.Stdout })
+
+	cfgPath := writeConfig(t, testConfig)
+	dir := filepath.Dir(cfgPath)
+
+	t.Run("Runs every slice", func(t *testing.T) {
+		out.Reset()
+		fsys, s := &mockFS{}, &mockSlicer{}
+		if err := run([]string{"run", "--config", cfgPath}, fsys, s, &mockRemapper{}); err != nil {
+			t.Fatalf("run() returned an unexpected error: %v", err)
+		}
+		if len(s.opts) != 2 {
+			t.Fatalf("Slice() called %d times, want 2", len(s.opts))
+		}
+		if s.opts[0].ManifestPath != filepath.Join(dir, "app.txt") || s.opts[0].Output != filepath.Join(dir, "out", "app") {
+			t.Errorf("first slice options = %+v", s.opts[0])
+		}
+		if s.opts[1].ManifestPath == "" {
+			t.Error("inline rules were not written to a manifest")
+		}
+		if fsys.limits.MaxFiles != 0 || fsys.limits.MaxSize != 0 {
+			t.Errorf("last slice limits = %+v, want none", fsys.limits)
+		}
+		// Only the app slice has a branch.
+		if want := []string{dir, filepath.Join(dir, "out", "app")}; !reflect.DeepEqual(s.committed, want) {
+			t.Errorf("Commit() called with %q, want %q", s.committed, want)
+		}
+		if want := (gitrepo.CommitOptions{Branch: "ai/app", Message: defaultCommitMessage, Ignore: provenance.DefaultPath}); s.commitOpts != want {
+			t.Errorf("Commit() options = %+v, want %+v", s.commitOpts, want)
+		}
+		if !strings.Contains(out.String(), "Committed "+filepath.Join(dir, "out", "app")+" to branch ai/app as 0123abcd\n") {
+			t.Errorf("unexpected output:\n%s", out.String())
+		}
+	})
+
+	t.Run("Runs named slices", func(t *testing.T) {
+		fsys, s := &mockFS{}, &mockSlicer{}
+		if err := run([]string{"run", "--config", cfgPath, "app"}, fsys, s, &mockRemapper{}); err != nil {
+			t.Fatalf("run() returned an unexpected error: %v", err)
+		}
+		if len(s.opts) != 1 {
+			t.Fatalf("Slice() called %d times, want 1", len(s.opts))
+		}
+		if fsys.limits.MaxFiles != 10 || fsys.limits.MaxSize != 1024 {
+			t.Errorf("limits = %+v, want 10 files and 1024 bytes", fsys.limits)
+		}
+	})
+
+	t.Run("Dry run does not slice", func(t *testing.T) {
+		out.Reset()
+		s := &mockSlicer{}
+		if err := run([]string{"run", "--config", cfgPath, "--dry-run"}, &mockFS{}, s, &mockRemapper{}); err != nil {
+			t.Fatalf("run() returned an unexpected error: %v", err)
+		}
+		if s.sliced || len(s.committed) != 0 {
+			t.Error("a dry run must not call Slice or Commit")
+		}
+		if !strings.Contains(out.String(), "Slice app:\n") || !strings.Contains(out.String(), "Slice docs:\n") {
+			t.Errorf("unexpected dry run output:\n%s", out.String())
+		}
+	})
+
+	errorCases := []struct {
+		name   string
+		args   []string
+		fs     *mockFS
+		slicer *mockSlicer
+	}{
+		{"Unknown flag", []string{"run", "--bad-flag"}, &mockFS{}, &mockSlicer{}},
+		{"Missing config", []string{"run", "--config", filepath.Join(dir, "missing.yaml")}, &mockFS{}, &mockSlicer{}},
+		{"Unknown slice", []string{"run", "--config", cfgPath, "nope"}, &mockFS{}, &mockSlicer{}},
+		{"Validation fails", []string{"run", "--config", cfgPath}, &mockFS{validateErr: errors.New("invalid")}, &mockSlicer{}},
+		{"Slice fails", []string{"run", "--config", cfgPath}, &mockFS{}, &mockSlicer{sliceErr: errors.New("failed")}},
+		{"Limits exceeded", []string{"run", "--config", cfgPath}, &mockFS{limitsErr: errors.New("too big")}, &mockSlicer{}},
+		{"Commit fails", []string{"run", "--config", cfgPath}, &mockFS{}, &mockSlicer{commitErr: errors.New("checked out")}},
+	}
+	for _, tc := range errorCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := run(tc.args, tc.fs, tc.slicer, &mockRemapper{}); err == nil {
+				t.Error("run() did not return an error")
+			}
+		})
+	}
+}
+
+// TestRunConfigIntegration creates two slices from one configuration file.
+func TestRunConfigIntegration(t *testing.T) {
+	var out bytes.Buffer
+	stdout = &out
+	t.Cleanup(func() { stdout = os.Stdout })
+
+	root := t.TempDir()
+	files := map[string]string{
+		"src/component.tsx": "export {}",
+		"README.md":         "# readme",
+		"app.txt":           "+ /src/***\n- *\n",
+		".repo-slice.yaml": `
+slices:
+  - name: app
+    manifest: app.txt
+    output: out/app
+    extension-map: tsx:ts
+  - name: docs
+    rules: |
+      + *.md
+      - *
+    output: out/docs
+    max-files: 1
+`,
+	}
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create directory for %s: %v", name, err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to create %s: %v", name, err)
+		}
+	}
+
+	args := []string{"run", "--config", filepath.Join(root, ".repo-slice.yaml")}
+	if err := run(args, &liveFS{}, &liveSlicer{}, &liveRemapper{}); err != nil {
+		t.Fatalf("run() failed on integration test: %v", err)
+	}
+
+	for _, name := range []string{"out/app/src/component.ts", "out/docs/README.md"} {
+		if _, err := os.Stat(filepath.Join(root, filepath.FromSlash(name))); err != nil {
+			t.Errorf("expected %s to exist: %v", name, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(root, "out", "docs", "src")); !os.IsNotExist(err) {
+		t.Error("the docs slice should not contain src")
+	}
+}
@@ -1,3 +1,8 @@
 module github.com/AlienHeadwars/repo-slice
 
-go 1.24.5
\ No newline at end of file
+go 1.24.5
+
+require (
+	github.com/BurntSushi/toml v1.6.0
+	gopkg.in/yaml.v3 v3.0.1
+)
@@ -0,0 +1,198 @@
+// file: internal/archive/archive.go
+
+// Package archive writes a slice as a single tar.gz or zip file. Archives are
+// reproducible: entries are written in lexical order with normalised
+// permissions, owners and timestamps, so the same tree always produces the same
+// bytes.
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/flate"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"time"
+)
+
+// Format identifies an archive format.
+type Format string
+
+const (
+	// FormatTarGz is a gzip-compressed tar file.
+	FormatTarGz Format = "tar.gz"
+	// FormatZip is a zip file.
+	FormatZip Format = "zip"
+)
+
+// Valid reports whether f names a supported archive format.
+func (f Format) Valid() bool {
+	return f == FormatTarGz || f == FormatZip
+}
+
+// ModTime is the modification time recorded for every entry. It is the
+// earliest time a zip file can represent.
+var ModTime = time.Date(1980, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// LinkReader is implemented by source file systems that can report the target
+// of a symbolic link. Links are stored as links only when the source is one.
+type LinkReader interface {
+	ReadLink(name string) (string, error)
+}
+
+// entry is a file, directory or symlink to be archived.
+type entry struct {
+	name   string // Slash-separated, with a trailing slash for directories.
+	mode   fs.FileMode
+	size   int64
+	target string // The destination of a symbolic link.
+}
+
+// Write archives every file, directory and symbolic link in src to w. Regular
+// files are stored with mode 0644, or 0755 if any execute bit is set, and
+// directories with mode 0755.
+func Write(w io.Writer, src fs.FS, format Format) error {
+	var write func(io.Writer, fs.FS, []entry) error
+	switch format {
+	case FormatTarGz:
+		write = writeTarGz
+	case FormatZip:
+		write = writeZip
+	default:
+		return fmt.Errorf("unknown archive format: %q", format)
+	}
+	entries, err := collect(src)
+	if err != nil {
+		return err
+	}
+	return write(w, src, entries)
+}
+
+// collect walks src in lexical order and returns the entries to archive.
+func collect(src fs.FS) ([]entry, error) {
+	var entries []entry
+	walkFn := func(path string, d fs.DirEntry, err error) error {
+		if err != nil || path == "." {
+			return err
+		}
+		switch t := d.Type(); {
+		case d.IsDir():
+			entries = append(entries, entry{name: path + "/", mode: fs.ModeDir | 0755})
+		case t&fs.ModeSymlink != 0:
+			lr, ok := src.(LinkReader)
+			if !ok {
+				return fmt.Errorf("failed to archive %s: source cannot read symbolic links", path)
+			}
+			target, err := lr.ReadLink(path)
+			if err != nil {
+				return fmt.Errorf("failed to archive %s: %w", path, err)
+			}
+			entries = append(entries, entry{name: path, mode: fs.ModeSymlink | 0777, target: target})
+		case t.IsRegular():
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			mode := fs.FileMode(0644)
+			if info.Mode()&0111 != 0 {
+				mode = 0755
+			}
+			entries = append(entries, entry{name: path, mode: mode, size: info.Size()})
+		}
+		return nil
+	}
+	if err := fs.WalkDir(src, ".", walkFn); err != nil {
+		return nil, fmt.Errorf("failed to walk slice: %w", err)
+	}
+	return entries, nil
+}
+
+func writeTarGz(w io.Writer, src fs.FS, entries []entry) error {
+	// The gzip header is left empty, so it records no name or time.
+	zw, err := gzip.NewWriterLevel(w, gzip.BestCompression)
+	if err != nil {
+		return err
+	}
+	tw := tar.NewWriter(zw)
+	for _, e := range entries {
+		hdr := &tar.Header{
+			Name:    e.name,
+			Mode:    int64(e.mode.Perm()),
+			ModTime: ModTime,
+		}
+		switch {
+		case e.mode.IsDir():
+			hdr.Typeflag = tar.TypeDir
+		case e.mode&fs.ModeSymlink != 0:
+			hdr.Typeflag = tar.TypeSymlink
+			hdr.Linkname = e.target
+		default:
+			hdr.Typeflag = tar.TypeReg
+			hdr.Size = e.size
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return fmt.Errorf("failed to archive %s: %w", e.name, err)
+		}
+		if hdr.Typeflag == tar.TypeReg {
+			if err := copyFile(tw, src, e); err != nil {
+				return err
+			}
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+func writeZip(w io.Writer, src fs.FS, entries []entry) error {
+	zw := zip.NewWriter(w)
+	zw.RegisterCompressor(zip.Deflate, func(out io.Writer) (io.WriteCloser, error) {
+		return flate.NewWriter(out, flate.BestCompression)
+	})
+	for _, e := range entries {
+		hdr := &zip.FileHeader{Name: e.name, Method: zip.Deflate, Modified: ModTime}
+		if e.mode.IsDir() {
+			hdr.Method = zip.Store
+		}
+		hdr.SetMode(e.mode)
+		fw, err := zw.CreateHeader(hdr)
+		if err != nil {
+			return fmt.Errorf("failed to archive %s: %w", e.name, err)
+		}
+		switch {
+		case e.mode.IsDir():
+		case e.mode&fs.ModeSymlink != 0:
+			// Zip stores a link's target as its content.
+			if _, err := io.WriteString(fw, e.target); err != nil {
+				return fmt.Errorf("failed to archive %s: %w", e.name, err)
+			}
+		default:
+			if err := copyFile(fw, src, e); err != nil {
+				return err
+			}
+		}
+	}
+	return zw.Close()
+}
+
+// copyFile writes the content of a regular file entry to w. A file that no
+// longer has the size it was listed with is an error, as a tar header has
+// already recorded that size.
+func copyFile(w io.Writer, src fs.FS, e entry) error {
+	f, err := src.Open(e.name)
+	if err != nil {
+		return fmt.Errorf("failed to archive %s: %w", e.name, err)
+	}
+	defer f.Close()
+	n, err := io.Copy(w, io.LimitReader(f, e.size))
+	if err == nil && n != e.size {
+		err = fmt.Errorf("file changed size while being archived")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to archive %s: %w", e.name, err)
+	}
+	return nil
+}
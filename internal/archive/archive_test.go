@@ -0,0 +1,146 @@
+// file: internal/archive/archive_test.go
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/fs"
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+// linkFS adds symbolic link support to a MapFS, whose symlink entries hold
+// their target as data.
+type linkFS struct{ fstest.MapFS }
+
+func (l linkFS) ReadLink(name string) (string, error) {
+	return string(l.MapFS[name].Data), nil
+}
+
+func testTree() linkFS {
+	return linkFS{fstest.MapFS{
+		"README.md":       {Data: []byte("readme"), Mode: 0600},
+		"bin/build.sh":    {Data: []byte("#!/bin/sh\n"), Mode: 0700},
+		"src/app/main.ts": {Data: []byte("export {}\n"), Mode: 0644},
+		"src/link.ts":     {Data: []byte("app/main.ts"), Mode: fs.ModeSymlink | 0777},
+		"empty":           {Mode: fs.ModeDir | 0700},
+	}}
+}
+
+// wantEntries lists the archived tree as "name mode content".
+var wantEntries = []string{
+	"README.md -rw-r--r-- readme",
+	"bin/ drwxr-xr-x ",
+	"bin/build.sh -rwxr-xr-x #!/bin/sh\n",
+	"empty/ drwxr-xr-x ",
+	"src/ drwxr-xr-x ",
+	"src/app/ drwxr-xr-x ",
+	"src/app/main.ts -rw-r--r-- export {}\n",
+	"src/link.ts Lrwxrwxrwx app/main.ts",
+}
+
+func TestWriteTarGz(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, testTree(), FormatTarGz); err != nil {
+		t.Fatalf("Write() returned an unexpected error: %v", err)
+	}
+
+	zr, err := gzip.NewReader(&buf)
+	if err != nil {
+		t.Fatalf("failed to read gzip stream: %v", err)
+	}
+	tr := tar.NewReader(zr)
+	var got []string
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("failed to read tar entry: %v", err)
+		}
+		if !hdr.ModTime.Equal(ModTime) || hdr.Uid != 0 || hdr.Gid != 0 || hdr.Uname != "" {
+			t.Errorf("%s has time %v and owner %d:%d %q, want normalised values", hdr.Name, hdr.ModTime, hdr.Uid, hdr.Gid, hdr.Uname)
+		}
+		data, _ := io.ReadAll(tr)
+		if hdr.Typeflag == tar.TypeSymlink {
+			data = []byte(hdr.Linkname)
+		}
+		got = append(got, hdr.Name+" "+hdr.FileInfo().Mode().String()+" "+string(data))
+	}
+	if !reflect.DeepEqual(got, wantEntries) {
+		t.Errorf("archive = %q, want %q", got, wantEntries)
+	}
+}
+
+func TestWriteZip(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, testTree(), FormatZip); err != nil {
+		t.Fatalf("Write() returned an unexpected error: %v", err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("failed to read zip file: %v", err)
+	}
+	var got []string
+	for _, f := range zr.File {
+		if !f.Modified.Equal(ModTime) {
+			t.Errorf("%s has time %v, want %v", f.Name, f.Modified, ModTime)
+		}
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("failed to open %s: %v", f.Name, err)
+		}
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		got = append(got, f.Name+" "+f.Mode().String()+" "+string(data))
+	}
+	if !reflect.DeepEqual(got, wantEntries) {
+		t.Errorf("archive = %q, want %q", got, wantEntries)
+	}
+}
+
+// TestWriteIsReproducible verifies that file times and permissions in the
+// source do not leak into the archive.
+func TestWriteIsReproducible(t *testing.T) {
+	for _, format := range []Format{FormatTarGz, FormatZip} {
+		t.Run(string(format), func(t *testing.T) {
+			changed := testTree()
+			changed.MapFS["README.md"] = &fstest.MapFile{Data: []byte("readme"), Mode: 0640, ModTime: ModTime.AddDate(40, 0, 0)}
+
+			var first, second bytes.Buffer
+			if err := Write(&first, testTree(), format); err != nil {
+				t.Fatalf("Write() returned an unexpected error: %v", err)
+			}
+			if err := Write(&second, changed, format); err != nil {
+				t.Fatalf("Write() returned an unexpected error: %v", err)
+			}
+			if !bytes.Equal(first.Bytes(), second.Bytes()) {
+				t.Error("Write() produced different archives for the same content")
+			}
+		})
+	}
+}
+
+func TestWriteErrors(t *testing.T) {
+	if err := Write(io.Discard, testTree(), "rar"); err == nil {
+		t.Error("Write() did not reject an unknown format")
+	}
+	// Embedding hides the ReadLink method, so the symbolic link cannot be read.
+	if err := Write(io.Discard, struct{ fs.FS }{testTree()}, FormatZip); err == nil {
+		t.Error("Write() did not report a symbolic link it cannot read")
+	}
+}
+
+func TestFormatValid(t *testing.T) {
+	for f, want := range map[Format]bool{FormatTarGz: true, FormatZip: true, "": false, "tar": false} {
+		if got := f.Valid(); got != want {
+			t.Errorf("Format(%q).Valid() = %v, want %v", f, got, want)
+		}
+	}
+}
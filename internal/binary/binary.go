@@ -0,0 +1,95 @@
+// file: internal/binary/binary.go
+
+// Package binary recognises binary files, such as images, fonts, compiled
+// artifacts and databases, which waste an assistant's context and can break
+// the tools that upload a slice.
+package binary
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"strings"
+	"unicode/utf8"
+)
+
+// Mode selects what a slice does with binary files.
+type Mode string
+
+const (
+	// ModeKeep copies binary files like any other file.
+	ModeKeep Mode = "keep"
+	// ModeSkip leaves binary files out of the slice.
+	ModeSkip Mode = "skip"
+	// ModeStub replaces each binary file with a short text file describing it.
+	ModeStub Mode = "stub"
+)
+
+// Valid reports whether m names a supported mode.
+func (m Mode) Valid() bool {
+	return m == ModeKeep || m == ModeSkip || m == ModeStub
+}
+
+// SniffLen is how much of the start of a file Detect needs to examine, which
+// is the same amount git inspects.
+const SniffLen = 8000
+
+// extensions maps the extensions of common binary formats to their type.
+var extensions = map[string]string{
+	".png": "PNG image", ".jpg": "JPEG image", ".jpeg": "JPEG image",
+	".gif": "GIF image", ".bmp": "BMP image", ".ico": "icon", ".webp": "WebP image",
+	".tif": "TIFF image", ".tiff": "TIFF image", ".psd": "Photoshop image",
+	".woff": "WOFF font", ".woff2": "WOFF2 font", ".ttf": "TrueType font",
+	".otf": "OpenType font", ".eot": "Embedded OpenType font",
+	".mp3": "MP3 audio", ".wav": "WAV audio", ".ogg": "Ogg media", ".flac": "FLAC audio",
+	".mp4": "MP4 video", ".mov": "QuickTime video", ".webm": "WebM video", ".avi": "AVI video",
+	".zip": "zip archive", ".gz": "gzip archive", ".tgz": "gzip archive",
+	".bz2": "bzip2 archive", ".xz": "xz archive", ".zst": "zstd archive",
+	".7z": "7-Zip archive", ".rar": "RAR archive", ".tar": "tar archive",
+	".jar": "Java archive", ".war": "Java archive", ".class": "Java class file",
+	".exe": "Windows executable", ".dll": "Windows library", ".so": "shared library",
+	".dylib": "shared library", ".a": "static library", ".o": "object file",
+	".obj": "object file", ".lib": "static library", ".wasm": "WebAssembly module",
+	".pyc": "Python bytecode", ".pdf": "PDF document",
+	".sqlite": "SQLite database", ".sqlite3": "SQLite database", ".db": "database",
+	".pdb": "debug symbols", ".bin": "binary data",
+}
+
+// Detect reports whether the file at the slash-separated name, whose content
+// starts with head, is binary, and if so returns its type. Files are binary if
+// their extension is a known binary format, or if the first SniffLen bytes
+// hold a NUL byte or are not valid UTF-8.
+func Detect(name string, head []byte) (string, bool) {
+	if kind, ok := extensions[strings.ToLower(path.Ext(name))]; ok {
+		return kind, true
+	}
+	if len(head) > SniffLen {
+		head = head[:SniffLen]
+	}
+	if bytes.IndexByte(head, 0) >= 0 {
+		return "binary data", true
+	}
+	// A character split by the end of the sniffed bytes is not an error.
+	for i := 1; i < utf8.UTFMax && i <= len(head); i++ {
+		if utf8.RuneStart(head[len(head)-i]) {
+			if !utf8.FullRune(head[len(head)-i:]) {
+				head = head[:len(head)-i]
+			}
+			break
+		}
+	}
+	if !utf8.Valid(head) {
+		return "binary data", true
+	}
+	return "", false
+}
+
+// StubSuffix is appended to the name of a binary file to name its stub, so
+// the stub is never mistaken for the original.
+const StubSuffix = ".txt"
+
+// Stub returns the content of the text file that stands in for the binary
+// file at the slash-separated name.
+func Stub(name, kind string, size int64) []byte {
+	return fmt.Appendf(nil, "Binary file omitted from the slice.\nName: %s\nType: %s\nSize: %d bytes\n", name, kind, size)
+}
@@ -0,0 +1,43 @@
+package binary
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestDetect(t *testing.T) {
+	testCases := []struct {
+		name     string
+		file     string
+		head     []byte
+		wantKind string
+		wantOK   bool
+	}{
+		{"Text", "main.go", []byte("package main\n"), "", false},
+		{"UTF-8 text", "README.md", []byte("naïve café ☕\n"), "", false},
+		{"Empty", "empty.txt", nil, "", false},
+		{"Known extension", "assets/logo.PNG", []byte("anything"), "PNG image", true},
+		{"Database", "data/app.sqlite", nil, "SQLite database", true},
+		{"NUL byte", "blob", []byte("abc\x00def"), "binary data", true},
+		{"Invalid UTF-8", "latin1.txt", []byte("caf\xe9 au lait"), "binary data", true},
+		{"Character split by the sniff limit", "long.txt", append(bytes.Repeat([]byte("a"), SniffLen-1), "☕"...), "", false},
+		{"NUL beyond the sniff limit", "long.txt", append(bytes.Repeat([]byte("a"), SniffLen), 0), "", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			kind, ok := Detect(tc.file, tc.head)
+			if kind != tc.wantKind || ok != tc.wantOK {
+				t.Errorf("Detect(%q) = %q, %v, want %q, %v", tc.file, kind, ok, tc.wantKind, tc.wantOK)
+			}
+		})
+	}
+}
+
+func TestStub(t *testing.T) {
+	got := string(Stub("assets/logo.png", "PNG image", 20480))
+	want := "Binary file omitted from the slice.\nName: assets/logo.png\nType: PNG image\nSize: 20480 bytes\n"
+	if got != want {
+		t.Errorf("Stub() = %q, want %q", got, want)
+	}
+}
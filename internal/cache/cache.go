@@ -0,0 +1,164 @@
+// file: internal/cache/cache.go
+
+// Package cache remembers the files of a slice, so that slicing into the same
+// output again only copies the files that changed since.
+//
+// A file is reused when its size and modification time in the source are
+// unchanged or, failing that, its content hash is, and its copy in the output
+// is exactly as the previous slice left it. Every setting that shapes the
+// content of a slice is folded into a key, and a cache with a different key
+// reuses nothing.
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Version is the version of the cache file's format. A cache written in
+// another format is ignored.
+const Version = 1
+
+// File records a file of a slice.
+type File struct {
+	Size    int64     `json:"size"` // The size of the file in the source.
+	ModTime time.Time `json:"mtime"`
+	SHA256  string    `json:"sha256"` // Hex-encoded hash of its content in the source.
+	// Output is the slash-separated path of its copy in the slice, after
+	// remapping, and OutputSize and OutputModTime describe that copy.
+	Output        string    `json:"output"`
+	OutputSize    int64     `json:"outputSize"`
+	OutputModTime time.Time `json:"outputMtime"`
+}
+
+// Cache holds the files of a previous slice, by their slash-separated path in
+// the source.
+type Cache struct {
+	Version int             `json:"version"`
+	Key     string          `json:"key"`
+	Files   map[string]File `json:"files"`
+	stale   bool            // Written with another key, so nothing is reused.
+}
+
+// New returns an empty cache for slices made with the settings in key.
+func New(key string) *Cache {
+	return &Cache{Version: Version, Key: key, Files: make(map[string]File)}
+}
+
+// Key returns a key identifying the settings in parts.
+func Key(parts ...string) string {
+	h := sha256.New()
+	for _, p := range parts {
+		io.WriteString(h, p)
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Load reads the cache file at name, for slices made with the settings in
+// key. A missing file, or one in another format, gives an empty cache. A
+// cache with another key still lists the files it holds, so that they can be
+// removed, but reuses none of them.
+func Load(name, key string) (*Cache, error) {
+	data, err := os.ReadFile(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return New(key), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cache: %w", err)
+	}
+	var c Cache
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("failed to parse cache %s: %w", name, err)
+	}
+	if c.Version != Version {
+		return New(key), nil
+	}
+	if c.Files == nil {
+		c.Files = make(map[string]File)
+	}
+	c.stale = c.Key != key
+	return &c, nil
+}
+
+// Lookup returns the record of the file at the slash-separated source path
+// name, if it can be reused.
+func (c *Cache) Lookup(name string) (File, bool) {
+	if c.stale {
+		return File{}, false
+	}
+	f, ok := c.Files[name]
+	return f, ok
+}
+
+// Unchanged reports whether the copy of f in the slice at dir is still as the
+// previous slice left it.
+func (c *Cache) Unchanged(dir string, f File) bool {
+	info, err := os.Lstat(filepath.Join(dir, filepath.FromSlash(f.Output)))
+	return err == nil && info.Mode().IsRegular() && info.Size() == f.OutputSize && info.ModTime().Equal(f.OutputModTime)
+}
+
+// Build returns a cache of the files of the slice at dir, made with the
+// settings in key, recording the copy of each file as it now is. Files whose
+// copy is missing, because it was removed from the slice, are left out.
+func Build(dir, key string, files map[string]File) (*Cache, error) {
+	c := New(key)
+	for name, f := range files {
+		info, err := os.Lstat(filepath.Join(dir, filepath.FromSlash(f.Output)))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		f.OutputSize, f.OutputModTime = info.Size(), info.ModTime()
+		c.Files[name] = f
+	}
+	return c, nil
+}
+
+// Save writes the cache to the file at name, creating any directories it
+// needs. The file is replaced in one step, so an interrupted run leaves the
+// previous cache intact.
+func (c *Cache) Save(name string) error {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return fmt.Errorf("failed to write cache: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(name), filepath.Base(name)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write cache: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write cache: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write cache: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), name); err != nil {
+		return fmt.Errorf("failed to write cache: %w", err)
+	}
+	return nil
+}
+
+// Hash returns the hex-encoded SHA-256 hash of the content read from r.
+func Hash(r io.Reader) (string, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
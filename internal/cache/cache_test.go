@@ -0,0 +1,120 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestKey(t *testing.T) {
+	if Key("a", "bc") == Key("ab", "c") {
+		t.Error("Key() gives the same key for different settings")
+	}
+	if Key("a", "b") != Key("a", "b") {
+		t.Error("Key() gives different keys for the same settings")
+	}
+}
+
+func TestBuildSaveAndLoad(t *testing.T) {
+	dir := t.TempDir()
+	then := time.Unix(1700000000, 0).UTC()
+	if err := os.MkdirAll(filepath.Join(dir, "src"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "src", "app.ts"), []byte("app"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(filepath.Join(dir, "src", "app.ts"), then, then); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]File{
+		"src/app.tsx": {Size: 3, ModTime: then, SHA256: "abc", Output: "src/app.ts"},
+		"secret.env":  {Size: 9, ModTime: then, SHA256: "def", Output: "secret.env"}, // Removed from the slice.
+	}
+
+	c, err := Build(dir, "key", files)
+	if err != nil {
+		t.Fatalf("Build() unexpected error: %v", err)
+	}
+	if got := c.Files["src/app.tsx"]; len(c.Files) != 1 || got.OutputSize != 3 || !got.OutputModTime.Equal(then) {
+		t.Errorf("Build() files = %+v, want only src/app.tsx with its copy of 3 bytes at %v", c.Files, then)
+	}
+	if !c.Unchanged(dir, c.Files["src/app.tsx"]) {
+		t.Error("Unchanged() = false for an untouched copy")
+	}
+
+	name := filepath.Join(t.TempDir(), "caches", "slice.json")
+	if err := c.Save(name); err != nil {
+		t.Fatalf("Save() unexpected error: %v", err)
+	}
+	loaded, err := Load(name, "key")
+	if err != nil {
+		t.Fatalf("Load() unexpected error: %v", err)
+	}
+	if f, ok := loaded.Lookup("src/app.tsx"); !ok || !f.OutputModTime.Equal(then) {
+		t.Errorf("Lookup() = %+v, %v, want the saved record", f, ok)
+	}
+
+	// Settings have changed: nothing is reused, but the copies are still known.
+	other, err := Load(name, "other key")
+	if err != nil {
+		t.Fatalf("Load() unexpected error: %v", err)
+	}
+	if _, ok := other.Lookup("src/app.tsx"); ok {
+		t.Error("Lookup() found a file in a cache with another key")
+	}
+	if len(other.Files) != 1 {
+		t.Errorf("cache with another key lists %d files, want 1", len(other.Files))
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "src", "app.ts"), []byte("edited"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if c.Unchanged(dir, c.Files["src/app.tsx"]) {
+		t.Error("Unchanged() = true for an edited copy")
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, data string) string {
+		t.Helper()
+		file := filepath.Join(dir, name)
+		if err := os.WriteFile(file, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return file
+	}
+
+	testCases := []struct {
+		name      string
+		file      string
+		wantFiles int
+		wantErr   string
+	}{
+		{"Missing file", filepath.Join(dir, "missing.json"), 0, ""},
+		{"Other format", write("v2.json", `{"version":2,"key":"key","files":{"a":{}}}`), 0, ""},
+		{"Current format", write("v1.json", `{"version":1,"key":"key","files":{"a":{}}}`), 1, ""},
+		{"Corrupt file", write("bad.json", `{"version":`), 0, "failed to parse cache"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := Load(tc.file, "key")
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("Load() error = %v, want it to contain %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() unexpected error: %v", err)
+			}
+			if len(c.Files) != tc.wantFiles {
+				t.Errorf("Load() gives %d files, want %d", len(c.Files), tc.wantFiles)
+			}
+		})
+	}
+}
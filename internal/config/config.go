@@ -0,0 +1,202 @@
+// file: internal/config/config.go
+
+// Package config reads repo-slice configuration files, which describe a list of
+// named slices so that every context branch of a repository can be produced in
+// a single invocation.
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/AlienHeadwars/repo-slice/internal/tokens/builtin"
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultPath is the configuration file used when none is named.
+const DefaultPath = ".repo-slice.yaml"
+
+// Slice describes a single slice. It holds the same settings as the
+// command-line flags, so a command-line invocation is simply a slice without
+// a name.
+type Slice struct {
+	Name           string `yaml:"name" toml:"name"`
+	ManifestPath   string `yaml:"manifest" toml:"manifest"`
+	Rules          string `yaml:"rules" toml:"rules"` // An inline manifest, used instead of ManifestPath.
+	ManifestFormat string `yaml:"manifest-format" toml:"manifest-format"`
+	SourcePath     string `yaml:"source" toml:"source"`
+	OutputPath     string `yaml:"output" toml:"output"`
+	OutputFormat   string `yaml:"output-format" toml:"output-format"` // Write an archive instead of a directory.
+	Mirror         bool   `yaml:"mirror" toml:"mirror"`               // Delete whatever else the output directory holds.
+	Cache          string `yaml:"cache" toml:"cache"`                 // Slice incrementally, with a cache in this file.
+	ExtensionMap   string `yaml:"extension-map" toml:"extension-map"`
+	Engine         string `yaml:"engine" toml:"engine"`
+	Gitignore      bool   `yaml:"gitignore" toml:"gitignore"`       // Apply the source repository's ignore files first.
+	TrackedOnly    bool   `yaml:"tracked-only" toml:"tracked-only"` // Only consider files tracked by git.
+	Ref            string `yaml:"ref" toml:"ref"`                   // Slice this commit instead of the working tree.
+	Binary         string `yaml:"binary" toml:"binary"`             // "keep", "skip" or "stub".
+	Branch         string `yaml:"branch" toml:"branch"`             // The branch the slice is committed to.
+	MaxFiles       int    `yaml:"max-files" toml:"max-files"`
+	MaxSize        string `yaml:"max-size" toml:"max-size"`
+	Secrets        string `yaml:"secrets" toml:"secrets"` // "off", "fail", "redact" or "exclude".
+	MaxFileSize    string `yaml:"max-file-size" toml:"max-file-size"`
+	MaxFileLines   int    `yaml:"max-file-lines" toml:"max-file-lines"`
+	Oversized      string `yaml:"oversized" toml:"oversized"` // "skip" or "truncate".
+	MaxTokens      int    `yaml:"max-tokens" toml:"max-tokens"`
+	TokenVocab     string `yaml:"token-vocab" toml:"token-vocab"` // A BPE vocabulary used to count tokens, or "builtin".
+	OnOverflow     string `yaml:"on-overflow" toml:"on-overflow"` // "fail" or "drop".
+	Priority       string `yaml:"priority" toml:"priority"`       // The priority list used to drop files.
+	Provenance     string `yaml:"provenance" toml:"provenance"`   // Where the slice records its origin, or "off".
+}
+
+// File is the top-level structure of a configuration file.
+type File struct {
+	Slices []Slice `yaml:"slices" toml:"slices"`
+}
+
+// FileSystem defines the file access needed to read configuration files,
+// allowing a mock implementation in unit tests.
+type FileSystem interface {
+	ReadFile(name string) ([]byte, error)
+}
+
+// LiveFS is a concrete implementation of the FileSystem interface that uses
+// the standard library's os package.
+type LiveFS struct{}
+
+// ReadFile reads the named file using the os package.
+func (LiveFS) ReadFile(name string) ([]byte, error) {
+	return os.ReadFile(name)
+}
+
+var validName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// Load reads the configuration file at path. Files ending in ".toml" are read
+// as TOML and everything else as YAML. Unknown keys are rejected so that typos
+// do not silently change a slice. Relative manifest, source and output paths
+// are resolved against the directory containing the file, and the source
+// defaults to that directory.
+func Load(path string, fsys FileSystem) (*File, error) {
+	data, err := fsys.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	var f File
+	if strings.EqualFold(filepath.Ext(path), ".toml") {
+		md, err := toml.Decode(string(data), &f)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse config %s: %w", path, err)
+		}
+		if undecoded := md.Undecoded(); len(undecoded) > 0 {
+			return nil, fmt.Errorf("failed to parse config %s: unknown key %q", path, undecoded[0].String())
+		}
+	} else {
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(&f); err != nil {
+			return nil, fmt.Errorf("failed to parse config %s: %w", path, err)
+		}
+	}
+
+	if err := f.validate(); err != nil {
+		return nil, fmt.Errorf("invalid config %s: %w", path, err)
+	}
+	f.resolve(filepath.Dir(path))
+	return &f, nil
+}
+
+// validate checks the settings that can be verified without touching the
+// source tree; the rest is checked when each slice runs.
+func (f *File) validate() error {
+	if len(f.Slices) == 0 {
+		return fmt.Errorf("no slices defined")
+	}
+	seen := make(map[string]bool)
+	for i, s := range f.Slices {
+		if !validName.MatchString(s.Name) {
+			return fmt.Errorf("slice %d: name %q must start with a letter or digit and contain only letters, digits, '.', '_' and '-'", i+1, s.Name)
+		}
+		if seen[s.Name] {
+			return fmt.Errorf("slice %q is defined more than once", s.Name)
+		}
+		seen[s.Name] = true
+
+		if (s.ManifestPath == "") == (s.Rules == "") {
+			return fmt.Errorf("slice %q: exactly one of 'manifest' or 'rules' must be set", s.Name)
+		}
+		if s.OutputPath == "" {
+			return fmt.Errorf("slice %q: 'output' is required", s.Name)
+		}
+		if s.Branch != "" && s.OutputFormat != "" {
+			return fmt.Errorf("slice %q: 'branch' needs an output directory and cannot be used with 'output-format'", s.Name)
+		}
+		if s.MaxFiles < 0 {
+			return fmt.Errorf("slice %q: 'max-files' must not be negative", s.Name)
+		}
+		if s.MaxTokens < 0 {
+			return fmt.Errorf("slice %q: 'max-tokens' must not be negative", s.Name)
+		}
+	}
+	return nil
+}
+
+func (f *File) resolve(dir string) {
+	for i := range f.Slices {
+		s := &f.Slices[i]
+		if s.SourcePath == "" {
+			s.SourcePath = "."
+		}
+		s.SourcePath = resolvePath(dir, s.SourcePath)
+		if s.OutputPath != "-" { // Standard output.
+			s.OutputPath = resolvePath(dir, s.OutputPath)
+		}
+		if s.ManifestPath != "" {
+			s.ManifestPath = resolvePath(dir, s.ManifestPath)
+		}
+		if s.TokenVocab != "" && s.TokenVocab != builtin.Name {
+			s.TokenVocab = resolvePath(dir, s.TokenVocab)
+		}
+		if s.Priority != "" {
+			s.Priority = resolvePath(dir, s.Priority)
+		}
+		if s.Cache != "" {
+			s.Cache = resolvePath(dir, s.Cache)
+		}
+	}
+}
+
+func resolvePath(dir, p string) string {
+	if filepath.IsAbs(p) {
+		return p
+	}
+	return filepath.Join(dir, p)
+}
+
+// Select returns the named slices in the order given, or every slice in file
+// order when no names are given.
+func (f *File) Select(names []string) ([]Slice, error) {
+	if len(names) == 0 {
+		return f.Slices, nil
+	}
+	var slices []Slice
+	for _, name := range names {
+		found := false
+		for _, s := range f.Slices {
+			if s.Name == name {
+				slices = append(slices, s)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("no slice named %q", name)
+		}
+	}
+	return slices, nil
+}
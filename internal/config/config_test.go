@@ -0,0 +1,195 @@
+package config
+
+import (
+	"io/fs"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// mockFS is a map-backed implementation of the FileSystem interface.
+type mockFS map[string]string
+
+func (m mockFS) ReadFile(name string) ([]byte, error) {
+	data, ok := m[name]
+	if !ok {
+		return nil, fs.ErrNotExist
+	}
+	return []byte(data), nil
+}
+
+const yamlConfig = `
+slices:
+  - name: frontend
+    manifest: manifests/frontend.txt
+    output: build/frontend
+    extension-map: tsx:ts,mdx:md
+    branch: ai/frontend
+    gitignore: true
+    binary: stub
+    max-files: 500
+    max-size: 10M
+    secrets: redact
+    max-file-size: 1M
+    max-file-lines: 2000
+    oversized: truncate
+    max-tokens: 200000
+    token-vocab: vocab/cl100k.tiktoken
+    on-overflow: drop
+    priority: priority.txt
+  - name: docs
+    source: docs
+    rules: |
+      # format: gitignore
+      *.png
+    output: /tmp/docs
+    token-vocab: builtin
+`
+
+const tomlConfig = `
+[[slices]]
+name = "frontend"
+manifest = "manifests/frontend.txt"
+output = "build/frontend"
+extension-map = "tsx:ts,mdx:md"
+branch = "ai/frontend"
+gitignore = true
+binary = "stub"
+max-files = 500
+max-size = "10M"
+secrets = "redact"
+max-file-size = "1M"
+max-file-lines = 2000
+oversized = "truncate"
+max-tokens = 200000
+token-vocab = "vocab/cl100k.tiktoken"
+on-overflow = "drop"
+priority = "priority.txt"
+
+[[slices]]
+name = "docs"
+source = "docs"
+rules = """
+# format: gitignore
+*.png
+"""
+output = "/tmp/docs"
+token-vocab = "builtin"
+`
+
+func TestLoad(t *testing.T) {
+	root := filepath.FromSlash("/repo")
+	fsys := mockFS{
+		filepath.Join(root, ".repo-slice.yaml"): yamlConfig,
+		filepath.Join(root, "slices.toml"):      tomlConfig,
+	}
+	want := []Slice{
+		{
+			Name:         "frontend",
+			ManifestPath: filepath.Join(root, "manifests", "frontend.txt"),
+			SourcePath:   root,
+			OutputPath:   filepath.Join(root, "build", "frontend"),
+			ExtensionMap: "tsx:ts,mdx:md",
+			Branch:       "ai/frontend",
+			Gitignore:    true,
+			Binary:       "stub",
+			MaxFiles:     500,
+			MaxSize:      "10M",
+			Secrets:      "redact",
+			MaxFileSize:  "1M",
+			MaxFileLines: 2000,
+			Oversized:    "truncate",
+			MaxTokens:    200000,
+			TokenVocab:   filepath.Join(root, "vocab", "cl100k.tiktoken"),
+			OnOverflow:   "drop",
+			Priority:     filepath.Join(root, "priority.txt"),
+		},
+		{
+			Name:       "docs",
+			Rules:      "# format: gitignore\n*.png\n",
+			SourcePath: filepath.Join(root, "docs"),
+			OutputPath: "/tmp/docs",
+			TokenVocab: "builtin", // Not a path.
+		},
+	}
+
+	for _, name := range []string{".repo-slice.yaml", "slices.toml"} {
+		t.Run(name, func(t *testing.T) {
+			f, err := Load(filepath.Join(root, name), fsys)
+			if err != nil {
+				t.Fatalf("Load() returned an unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(f.Slices, want) {
+				t.Errorf("Load() = %+v, want %+v", f.Slices, want)
+			}
+		})
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	testCases := []struct {
+		name    string
+		file    string
+		content string
+		wantErr string
+	}{
+		{"Missing file", "missing.yaml", "", "failed to read config"},
+		{"Invalid YAML", "c.yaml", "slices: [", "failed to parse config"},
+		{"Unknown YAML key", "c.yaml", "slices:\n  - name: a\n    rule: x\n", "field rule not found"},
+		{"Unknown TOML key", "c.toml", "[[slices]]\nname = \"a\"\nrule = \"x\"\n", "unknown key"},
+		{"No slices", "c.yaml", "slices: []\n", "no slices defined"},
+		{"Invalid name", "c.yaml", "slices:\n  - name: my slice\n    rules: x\n    output: o\n", "name \"my slice\""},
+		{"Duplicate name", "c.yaml", "slices:\n  - {name: a, rules: x, output: o}\n  - {name: a, rules: y, output: p}\n", "defined more than once"},
+		{"Manifest and rules", "c.yaml", "slices:\n  - {name: a, manifest: m, rules: x, output: o}\n", "exactly one of"},
+		{"Neither manifest nor rules", "c.yaml", "slices:\n  - {name: a, output: o}\n", "exactly one of"},
+		{"Missing output", "c.yaml", "slices:\n  - {name: a, rules: x}\n", "'output' is required"},
+		{"Branch with output format", "c.yaml", "slices:\n  - {name: a, rules: x, output: o.zip, output-format: zip, branch: ai}\n", "'branch' needs an output directory"},
+		{"Negative file limit", "c.yaml", "slices:\n  - {name: a, rules: x, output: o, max-files: -1}\n", "must not be negative"},
+		{"Negative token limit", "c.yaml", "slices:\n  - {name: a, rules: x, output: o, max-tokens: -1}\n", "'max-tokens' must not be negative"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fsys := mockFS{}
+			if tc.content != "" {
+				fsys[tc.file] = tc.content
+			}
+			_, err := Load(tc.file, fsys)
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("Load() error = %v, want it to contain %q", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestSelect(t *testing.T) {
+	f := &File{Slices: []Slice{{Name: "a"}, {Name: "b"}, {Name: "c"}}}
+
+	testCases := []struct {
+		name    string
+		names   []string
+		want    []string
+		wantErr bool
+	}{
+		{"All slices", nil, []string{"a", "b", "c"}, false},
+		{"Named slices in the order given", []string{"c", "a"}, []string{"c", "a"}, false},
+		{"Unknown slice", []string{"a", "z"}, nil, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			slices, err := f.Select(tc.names)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("Select() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			var got []string
+			for _, s := range slices {
+				got = append(got, s.Name)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("Select() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+// file: internal/deps/deps.go
+
+// Package deps resolves the manifest rules that name a computed set of files,
+// such as "gopkg:./cmd/app" or "js:./src/main.tsx", by following imports
+// through the source tree. Hand-written manifests go stale as code starts
+// importing new packages; a set follows the imports instead, so a slice keeps
+// everything it needs.
+package deps
+
+import (
+	"fmt"
+	"io/fs"
+
+	"github.com/AlienHeadwars/repo-slice/internal/filter"
+)
+
+// Resolve computes the files of every set named by the rules from the source
+// tree in src.
+func Resolve(rules filter.Rules, src fs.FS) error {
+	for i := range rules {
+		r := &rules[i]
+		if r.Set == nil {
+			continue
+		}
+		var files []string
+		var err error
+		switch r.Set.Kind {
+		case "gopkg":
+			opts := GoOptions{Tests: r.Set.Has("tests"), External: r.Set.Has("external")}
+			files, err = GoClosure(src, r.Set.Root, opts)
+		case "js":
+			files, err = JSClosure(src, r.Set.Root)
+		default:
+			err = fmt.Errorf("unknown set kind %q", r.Set.Kind)
+		}
+		if err != nil {
+			return fmt.Errorf("%s:%d: %w", r.File, r.Line, err)
+		}
+		r.Set.Resolve(files)
+	}
+	return nil
+}
@@ -0,0 +1,349 @@
+// file: internal/deps/golang.go
+package deps
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// GoOptions selects what a Go package closure includes beyond the non-test
+// files of module-local packages.
+type GoOptions struct {
+	// Tests adds each package's test files and testdata directory, and
+	// follows the imports of the tests.
+	Tests bool
+	// External follows imports of other modules into the tree: vendored
+	// packages and modules replaced by a local directory.
+	External bool
+}
+
+// otherSources are the non-Go files the go command builds into a package.
+var otherSources = map[string]bool{
+	".s": true, ".S": true, ".sx": true, ".c": true, ".h": true, ".cc": true,
+	".cpp": true, ".cxx": true, ".hh": true, ".hpp": true, ".hxx": true,
+	".m": true, ".syso": true,
+}
+
+// GoClosure returns the slash-separated paths of the files of the Go package
+// at root, a directory relative to the root of src such as "./cmd/app", and
+// of every package it transitively imports from the tree. A root ending in
+// "/..." names every package beneath that directory. The go.mod and go.sum
+// files of each module involved are included, so the slice still builds.
+func GoClosure(src fs.FS, root string, opts GoOptions) ([]string, error) {
+	g := &goGraph{
+		src:      src,
+		opts:     opts,
+		modules:  make(map[string]*goModule),
+		files:    make(map[string]bool),
+		packages: make(map[string]bool),
+	}
+	dir, all := strings.CutSuffix(root, "/...")
+	if root == "..." {
+		dir, all = ".", true
+	}
+	dir = path.Clean(strings.TrimPrefix(dir, "./"))
+	if path.IsAbs(dir) || dir == ".." || strings.HasPrefix(dir, "../") {
+		return nil, fmt.Errorf("Go package %s is outside the source", root)
+	}
+
+	roots := []string{dir}
+	if all {
+		var err error
+		if roots, err = g.packagesBeneath(dir); err != nil {
+			return nil, err
+		}
+	}
+	for _, r := range roots {
+		found, err := g.visit(r)
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			return nil, fmt.Errorf("no Go files in %s", r)
+		}
+	}
+
+	files := make([]string, 0, len(g.files))
+	for f := range g.files {
+		files = append(files, f)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// goModule is a module whose source is in the tree.
+type goModule struct {
+	path    string            // The module path.
+	dir     string            // Its directory in the tree.
+	replace map[string]string // Module paths replaced by directories in the tree.
+}
+
+// owns reports whether the import path belongs to the module, and if so
+// returns the package's directory.
+func (m *goModule) owns(importPath string) (string, bool) {
+	if importPath == m.path {
+		return m.dir, true
+	}
+	if rest, ok := strings.CutPrefix(importPath, m.path+"/"); ok {
+		return path.Join(m.dir, rest), true
+	}
+	return "", false
+}
+
+// goGraph walks the import graph of the packages in a tree.
+type goGraph struct {
+	src      fs.FS
+	opts     GoOptions
+	modules  map[string]*goModule // By directory; nil for a directory outside any module.
+	files    map[string]bool
+	packages map[string]bool // Directories already visited.
+}
+
+// packagesBeneath returns every directory at or beneath dir that holds Go
+// files, skipping the directories the go command ignores and nested modules.
+func (g *goGraph) packagesBeneath(dir string) ([]string, error) {
+	var dirs []string
+	walkFn := func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			if strings.HasSuffix(p, ".go") && (len(dirs) == 0 || dirs[len(dirs)-1] != path.Dir(p)) {
+				dirs = append(dirs, path.Dir(p))
+			}
+			return nil
+		}
+		name := d.Name()
+		if p != dir && (name == "vendor" || name == "testdata" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")) {
+			return fs.SkipDir
+		}
+		if p != dir {
+			if _, err := fs.Stat(g.src, path.Join(p, "go.mod")); err == nil {
+				return fs.SkipDir
+			}
+		}
+		return nil
+	}
+	if err := fs.WalkDir(g.src, dir, walkFn); err != nil {
+		return nil, fmt.Errorf("failed to find Go packages in %s: %w", dir, err)
+	}
+	return dirs, nil
+}
+
+// visit adds the package in dir, and then the packages it imports. It reports
+// whether dir holds a Go package.
+func (g *goGraph) visit(dir string) (bool, error) {
+	if g.packages[dir] {
+		return true, nil
+	}
+	entries, err := fs.ReadDir(g.src, dir)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	var goFiles, others []string
+	for _, e := range entries {
+		name := e.Name()
+		if !e.Type().IsRegular() || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") {
+			continue
+		}
+		switch {
+		case strings.HasSuffix(name, "_test.go"):
+			if g.opts.Tests {
+				goFiles = append(goFiles, path.Join(dir, name))
+			}
+		case strings.HasSuffix(name, ".go"):
+			goFiles = append(goFiles, path.Join(dir, name))
+		case otherSources[path.Ext(name)]:
+			others = append(others, path.Join(dir, name))
+		}
+	}
+	if len(goFiles) == 0 {
+		return false, nil
+	}
+	g.packages[dir] = true
+	for _, f := range others {
+		g.files[f] = true
+	}
+
+	if g.opts.Tests {
+		if err := g.addTree(path.Join(dir, "testdata")); err != nil {
+			return false, err
+		}
+	}
+	mod, err := g.module(dir)
+	if err != nil {
+		return false, err
+	}
+	for _, file := range goFiles {
+		g.files[file] = true
+		imports, err := g.imports(file)
+		if err != nil {
+			return false, err
+		}
+		for _, imp := range imports {
+			if err := g.follow(imp, mod); err != nil {
+				return false, err
+			}
+		}
+	}
+	return true, nil
+}
+
+// imports returns the import paths of a Go file.
+func (g *goGraph) imports(file string) ([]string, error) {
+	data, err := fs.ReadFile(g.src, file)
+	if err != nil {
+		return nil, err
+	}
+	f, err := parser.ParseFile(token.NewFileSet(), file, data, parser.ImportsOnly)
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, spec := range f.Imports {
+		p, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid import %s", file, spec.Path.Value)
+		}
+		paths = append(paths, p)
+	}
+	return paths, nil
+}
+
+// follow visits the package an import refers to, if its source is in the tree
+// and the options allow it. Standard library and unresolved imports are
+// skipped.
+func (g *goGraph) follow(importPath string, mod *goModule) error {
+	if mod == nil {
+		return nil
+	}
+	if dir, ok := mod.owns(importPath); ok {
+		_, err := g.visit(dir)
+		return err
+	}
+	if !g.opts.External {
+		return nil
+	}
+	// The longest replaced module path that owns the import wins.
+	best := ""
+	for modPath := range mod.replace {
+		if (importPath == modPath || strings.HasPrefix(importPath, modPath+"/")) && len(modPath) > len(best) {
+			best = modPath
+		}
+	}
+	if best != "" {
+		dir := path.Join(mod.replace[best], strings.TrimPrefix(importPath, best))
+		_, err := g.visit(dir)
+		return err
+	}
+	found, err := g.visit(path.Join(mod.dir, "vendor", importPath))
+	if found {
+		g.addFile(path.Join(mod.dir, "vendor", "modules.txt"))
+	}
+	return err
+}
+
+// module returns the module containing dir, or nil if there is none, and
+// adds its go.mod and go.sum files to the closure.
+func (g *goGraph) module(dir string) (*goModule, error) {
+	if m, ok := g.modules[dir]; ok {
+		return m, nil
+	}
+	var m *goModule
+	data, err := fs.ReadFile(g.src, path.Join(dir, "go.mod"))
+	switch {
+	case err == nil:
+		m = parseGoMod(data, dir)
+		g.addFile(path.Join(dir, "go.mod"))
+		g.addFile(path.Join(dir, "go.sum"))
+	case !errors.Is(err, fs.ErrNotExist):
+		return nil, err
+	case dir != ".":
+		if m, err = g.module(path.Dir(dir)); err != nil {
+			return nil, err
+		}
+	}
+	g.modules[dir] = m
+	return m, nil
+}
+
+// addFile adds the named file to the closure if it exists.
+func (g *goGraph) addFile(name string) {
+	if _, err := fs.Stat(g.src, name); err == nil {
+		g.files[name] = true
+	}
+}
+
+// addTree adds every file beneath dir, if it exists, to the closure.
+func (g *goGraph) addTree(dir string) error {
+	err := fs.WalkDir(g.src, dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			g.files[p] = true
+		}
+		return nil
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// parseGoMod reads the module path and the local replacements from the go.mod
+// file of the module in dir. Replacements outside the tree are ignored.
+func parseGoMod(data []byte, dir string) *goModule {
+	m := &goModule{dir: dir, replace: make(map[string]string)}
+	inReplace := false
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "//")
+		fields := strings.Fields(line)
+		switch {
+		case len(fields) == 0:
+			continue
+		case inReplace && fields[0] == ")":
+			inReplace = false
+			continue
+		case fields[0] == "module" && len(fields) > 1:
+			m.path = strings.Trim(fields[1], `"`)
+			continue
+		case fields[0] == "replace" && len(fields) > 1 && fields[1] == "(":
+			inReplace = true
+			continue
+		case fields[0] == "replace":
+			fields = fields[1:]
+		case !inReplace:
+			continue
+		}
+		from, to, ok := strings.Cut(strings.Join(fields, " "), "=>")
+		from, to = strings.TrimSpace(from), strings.TrimSpace(to)
+		if !ok || from == "" || to == "" {
+			continue
+		}
+		target := strings.Fields(to)[0]
+		if !strings.HasPrefix(target, "./") && !strings.HasPrefix(target, "../") {
+			continue // A module version, not a directory.
+		}
+		local := path.Join(dir, target)
+		if local == ".." || strings.HasPrefix(local, "../") {
+			continue
+		}
+		m.replace[strings.Trim(strings.Fields(from)[0], `"`)] = local
+	}
+	return m
+}
@@ -0,0 +1,141 @@
+package deps
+
+import (
+	"reflect"
+	"testing"
+	"testing/fstest"
+
+	"github.com/AlienHeadwars/repo-slice/internal/filter"
+)
+
+func goFile(src string) *fstest.MapFile {
+	return &fstest.MapFile{Data: []byte(src)}
+}
+
+// goTree is a module with a command, the packages it imports, an unrelated
+// package, a vendored dependency and a locally replaced module.
+var goTree = fstest.MapFS{
+	"go.mod":  goFile("module example.com/app\n\ngo 1.22\n\nrequire (\n\tgithub.com/ext/lib v1.0.0\n\texample.com/shared v0.0.0\n)\n\nreplace example.com/shared => ./shared // local copy\n"),
+	"go.sum":  goFile("github.com/ext/lib v1.0.0 h1:x\n"),
+	"main.go": goFile("package main\n\nfunc main() {}\n"),
+	"cmd/app/main.go": goFile(`package main
+
+import (
+	"fmt"
+
+	"example.com/app/internal/a"
+	"example.com/shared/util"
+	"github.com/ext/lib"
+)
+
+func main() { fmt.Println(a.A, lib.L, util.U) }
+`),
+	"cmd/app/main_test.go":           goFile("package main\n\nimport _ \"example.com/app/internal/testutil\"\n"),
+	"cmd/app/README.md":              goFile("app"),
+	"cmd/other/main.go":              goFile("package main\n\nimport _ \"example.com/app/internal/unused\"\n"),
+	"internal/a/a.go":                goFile("package a\n\nimport \"example.com/app/internal/b\"\n\nvar A = b.B\n"),
+	"internal/a/a_amd64.s":           goFile("TEXT ·f(SB),0,$0\n"),
+	"internal/a/testdata/input.txt":  goFile("input"),
+	"internal/a/_scratch.go":         goFile("package a\n\nimport \"example.com/app/internal/unused\"\n"),
+	"internal/b/b.go":                goFile("package b\n\nconst B = 1\n"),
+	"internal/testutil/testutil.go":  goFile("package testutil\n"),
+	"internal/unused/unused.go":      goFile("package unused\n"),
+	"shared/go.mod":                  goFile("module example.com/shared\n"),
+	"shared/util/util.go":            goFile("package util\n\nimport \"example.com/shared/base\"\n\nconst U = base.X\n"),
+	"shared/base/base.go":            goFile("package base\n\nconst X = 1\n"),
+	"vendor/modules.txt":             goFile("# github.com/ext/lib v1.0.0\n"),
+	"vendor/github.com/ext/lib/l.go": goFile("package lib\n\nconst L = 1\n"),
+}
+
+func TestGoClosure(t *testing.T) {
+	testCases := []struct {
+		name string
+		root string
+		opts GoOptions
+		want []string
+	}{
+		{
+			name: "Module-local imports only",
+			root: "./cmd/app",
+			want: []string{"cmd/app/main.go", "go.mod", "go.sum", "internal/a/a.go", "internal/a/a_amd64.s", "internal/b/b.go"},
+		},
+		{
+			name: "Tests and their imports",
+			root: "cmd/app",
+			opts: GoOptions{Tests: true},
+			want: []string{
+				"cmd/app/main.go", "cmd/app/main_test.go", "go.mod", "go.sum", "internal/a/a.go", "internal/a/a_amd64.s",
+				"internal/a/testdata/input.txt", "internal/b/b.go", "internal/testutil/testutil.go",
+			},
+		},
+		{
+			name: "External modules in the tree",
+			root: "./cmd/app",
+			opts: GoOptions{External: true},
+			want: []string{
+				"cmd/app/main.go", "go.mod", "go.sum", "internal/a/a.go", "internal/a/a_amd64.s", "internal/b/b.go",
+				"shared/base/base.go", "shared/go.mod", "shared/util/util.go",
+				"vendor/github.com/ext/lib/l.go", "vendor/modules.txt",
+			},
+		},
+		{
+			name: "Every package beneath a directory",
+			root: "./cmd/...",
+			want: []string{"cmd/app/main.go", "cmd/other/main.go", "go.mod", "go.sum", "internal/a/a.go", "internal/a/a_amd64.s", "internal/b/b.go", "internal/unused/unused.go"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := GoClosure(goTree, tc.root, tc.opts)
+			if err != nil {
+				t.Fatalf("GoClosure() returned an unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("GoClosure() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestGoClosureErrors(t *testing.T) {
+	broken := fstest.MapFS{
+		"go.mod":      goFile("module example.com/app\n"),
+		"bad/bad.go":  goFile("package bad\n\nimport (\n"),
+		"docs/doc.md": goFile("docs"),
+	}
+	for _, root := range []string{"./docs", "./missing", "../elsewhere", "./bad"} {
+		t.Run(root, func(t *testing.T) {
+			if _, err := GoClosure(broken, root, GoOptions{}); err == nil {
+				t.Errorf("GoClosure(%q) did not return an error", root)
+			}
+		})
+	}
+}
+
+func TestResolve(t *testing.T) {
+	rules := filter.Rules{filter.NewRule(filter.Include, "gopkg,tests:./cmd/app"), filter.NewRule(filter.Exclude, "*")}
+	set, err := filter.ParseSet(rules[0].Pattern)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rules[0].Set = set
+	if err := Resolve(rules, goTree); err != nil {
+		t.Fatalf("Resolve() returned an unexpected error: %v", err)
+	}
+	for path, want := range map[string]bool{
+		"cmd/app/main_test.go":          true,
+		"internal/testutil/testutil.go": true,
+		"internal/unused/unused.go":     false,
+		"cmd/app/README.md":             false,
+	} {
+		if got := rules.Included(path, false); got != want {
+			t.Errorf("Included(%q) = %v, want %v", path, got, want)
+		}
+	}
+
+	rules[0].Set.Root = "./nowhere"
+	if err := Resolve(rules, goTree); err == nil {
+		t.Error("Resolve() did not fail for a missing package")
+	}
+}
@@ -0,0 +1,330 @@
+// file: internal/deps/js.go
+package deps
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// scriptExts are the extensions of the files whose imports are followed, in
+// the order an extensionless specifier tries them.
+var scriptExts = []string{".ts", ".tsx", ".d.ts", ".js", ".jsx", ".mts", ".cts", ".mjs", ".cjs"}
+
+// resolveExts are the extensions an extensionless specifier may omit.
+var resolveExts = append(scriptExts, ".json")
+
+// jsOutputExts maps the extension of a compiled file, which TypeScript lets
+// an import name, to the extensions of the sources it is compiled from.
+var jsOutputExts = map[string][]string{
+	".js":  {".ts", ".tsx"},
+	".jsx": {".tsx"},
+	".mjs": {".mts"},
+	".cjs": {".cts"},
+}
+
+// jsImport matches the module specifiers of static imports and re-exports,
+// dynamic imports and calls to require.
+var jsImport = regexp.MustCompile(`(?:\b(?:import|export)\s+(?:type\s+)?(?:[\w*${}\s,]+?\s+from\s+)?|\b(?:import|require)\s*\(\s*)["']([^"'\n]+)["']`)
+
+// JSClosure returns the slash-separated paths of the JavaScript or TypeScript
+// file at entry, relative to the root of src, and of every file it
+// transitively imports from the tree. Relative specifiers are resolved as
+// bundlers do, trying the script extensions and index files, and aliases are
+// resolved with the paths of the nearest tsconfig.json or jsconfig.json,
+// which is included too. Packages, such as those in node_modules, are not
+// followed.
+func JSClosure(src fs.FS, entry string) ([]string, error) {
+	g := &jsGraph{src: src, files: make(map[string]bool), configs: make(map[string]*tsconfig)}
+	name := path.Clean(strings.TrimPrefix(entry, "./"))
+	if path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+		return nil, fmt.Errorf("entry %s is outside the source", entry)
+	}
+	file, ok := g.resolve(name)
+	if !ok {
+		return nil, fmt.Errorf("entry %s not found", entry)
+	}
+	if err := g.visit(file); err != nil {
+		return nil, err
+	}
+
+	files := make([]string, 0, len(g.files))
+	for f := range g.files {
+		files = append(files, f)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// jsGraph walks the import graph of the scripts in a tree.
+type jsGraph struct {
+	src     fs.FS
+	files   map[string]bool
+	configs map[string]*tsconfig // By directory; nil where no config applies.
+}
+
+// visit adds the file, and if it is a script, the files it imports.
+func (g *jsGraph) visit(file string) error {
+	if g.files[file] {
+		return nil
+	}
+	g.files[file] = true
+	if !isScript(file) {
+		return nil
+	}
+	data, err := fs.ReadFile(g.src, file)
+	if err != nil {
+		return err
+	}
+	cfg, err := g.config(path.Dir(file))
+	if err != nil {
+		return err
+	}
+	if cfg != nil {
+		g.files[cfg.file] = true
+	}
+	for _, m := range jsImport.FindAllSubmatch(stripComments(data), -1) {
+		target, ok := g.resolveSpecifier(string(m[1]), path.Dir(file), cfg)
+		if !ok {
+			continue
+		}
+		if err := g.visit(target); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolveSpecifier returns the file a specifier in a file in dir refers to,
+// if it is in the tree.
+func (g *jsGraph) resolveSpecifier(spec, dir string, cfg *tsconfig) (string, bool) {
+	spec, _, _ = strings.Cut(spec, "?")
+	if spec == "." || spec == ".." || strings.HasPrefix(spec, "./") || strings.HasPrefix(spec, "../") {
+		return g.resolve(path.Join(dir, spec))
+	}
+	if cfg == nil || strings.HasPrefix(spec, "/") {
+		return "", false
+	}
+	for _, candidate := range cfg.candidates(spec) {
+		if file, ok := g.resolve(candidate); ok {
+			return file, true
+		}
+	}
+	return "", false
+}
+
+// resolve returns the file that the slash-separated name refers to: the file
+// itself, the file with an omitted extension, the source of a compiled file,
+// or the index file of a directory.
+func (g *jsGraph) resolve(name string) (string, bool) {
+	if name == ".." || strings.HasPrefix(name, "../") {
+		return "", false
+	}
+	if g.isFile(name) {
+		return name, true
+	}
+	for _, ext := range resolveExts {
+		if g.isFile(name + ext) {
+			return name + ext, true
+		}
+	}
+	ext := path.Ext(name)
+	for _, srcExt := range jsOutputExts[ext] {
+		if source := strings.TrimSuffix(name, ext) + srcExt; g.isFile(source) {
+			return source, true
+		}
+	}
+	for _, ext := range resolveExts {
+		if index := path.Join(name, "index"+ext); g.isFile(index) {
+			return index, true
+		}
+	}
+	return "", false
+}
+
+func (g *jsGraph) isFile(name string) bool {
+	info, err := fs.Stat(g.src, name)
+	return err == nil && info.Mode().IsRegular()
+}
+
+func isScript(name string) bool {
+	for _, ext := range scriptExts {
+		if strings.HasSuffix(name, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// tsconfig holds the module resolution settings of a tsconfig.json or
+// jsconfig.json file.
+type tsconfig struct {
+	file    string              // The config file, relative to the source root.
+	baseURL string              // Directory bare specifiers are resolved against, or "".
+	pathDir string              // Directory the targets of paths are relative to.
+	paths   map[string][]string // Aliases and their target patterns.
+}
+
+// candidates returns the names a bare specifier may refer to, in order of
+// preference: an exact alias, the alias with the longest matching prefix, and
+// then the specifier relative to baseUrl.
+func (c *tsconfig) candidates(spec string) []string {
+	var names []string
+	if targets, ok := c.paths[spec]; ok {
+		for _, t := range targets {
+			names = append(names, path.Join(c.pathDir, t))
+		}
+	}
+	best, bestPrefix := "", -1
+	for alias := range c.paths {
+		prefix, suffix, ok := strings.Cut(alias, "*")
+		if !ok || len(prefix) <= bestPrefix || !strings.HasPrefix(spec, prefix) || !strings.HasSuffix(spec[len(prefix):], suffix) {
+			continue
+		}
+		best, bestPrefix = alias, len(prefix)
+	}
+	if best != "" {
+		prefix, suffix, _ := strings.Cut(best, "*")
+		star := strings.TrimSuffix(spec[len(prefix):], suffix)
+		for _, t := range c.paths[best] {
+			names = append(names, path.Join(c.pathDir, strings.Replace(t, "*", star, 1)))
+		}
+	}
+	if c.baseURL != "" {
+		names = append(names, path.Join(c.baseURL, spec))
+	}
+	return names
+}
+
+// config returns the configuration nearest to dir, or nil if there is none.
+func (g *jsGraph) config(dir string) (*tsconfig, error) {
+	if c, ok := g.configs[dir]; ok {
+		return c, nil
+	}
+	var c *tsconfig
+	for _, name := range []string{"tsconfig.json", "jsconfig.json"} {
+		file := path.Join(dir, name)
+		if !g.isFile(file) {
+			continue
+		}
+		var err error
+		if c, err = g.loadConfig(file, nil); err != nil {
+			return nil, err
+		}
+		break
+	}
+	if c == nil && dir != "." {
+		var err error
+		if c, err = g.config(path.Dir(dir)); err != nil {
+			return nil, err
+		}
+	}
+	g.configs[dir] = c
+	return c, nil
+}
+
+// loadConfig reads a configuration file, after the configuration it extends,
+// if that is a file in the tree. seen guards against cycles.
+func (g *jsGraph) loadConfig(file string, seen map[string]bool) (*tsconfig, error) {
+	if seen[file] {
+		return nil, fmt.Errorf("%s extends itself", file)
+	}
+	data, err := fs.ReadFile(g.src, file)
+	if err != nil {
+		return nil, err
+	}
+	var raw struct {
+		Extends         json.RawMessage `json:"extends"`
+		CompilerOptions struct {
+			BaseURL *string             `json:"baseUrl"`
+			Paths   map[string][]string `json:"paths"`
+		} `json:"compilerOptions"`
+	}
+	if err := json.Unmarshal(stripTrailingCommas(stripComments(data)), &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", file, err)
+	}
+
+	dir := path.Dir(file)
+	c := &tsconfig{file: file, pathDir: dir}
+	var extends string
+	if len(raw.Extends) > 0 && json.Unmarshal(raw.Extends, &extends) == nil && strings.HasPrefix(extends, ".") {
+		base := path.Join(dir, extends)
+		if !strings.HasSuffix(base, ".json") && !g.isFile(base) {
+			base += ".json"
+		}
+		if g.isFile(base) {
+			if seen == nil {
+				seen = make(map[string]bool)
+			}
+			seen[file] = true
+			parent, err := g.loadConfig(base, seen)
+			if err != nil {
+				return nil, err
+			}
+			g.files[base] = true
+			c.baseURL, c.pathDir, c.paths = parent.baseURL, parent.pathDir, parent.paths
+		}
+	}
+	if raw.CompilerOptions.BaseURL != nil {
+		c.baseURL = path.Join(dir, *raw.CompilerOptions.BaseURL)
+		c.pathDir = c.baseURL
+	}
+	if raw.CompilerOptions.Paths != nil {
+		c.paths = raw.CompilerOptions.Paths
+		if raw.CompilerOptions.BaseURL == nil && c.baseURL == "" {
+			c.pathDir = dir
+		}
+	}
+	return c, nil
+}
+
+// stripComments blanks out the line and block comments of JavaScript or
+// JSONC source, leaving strings intact, so that commented-out imports are
+// not followed. Newlines are kept.
+func stripComments(data []byte) []byte {
+	out := make([]byte, len(data))
+	copy(out, data)
+	var quote byte
+	for i := 0; i < len(out); i++ {
+		c := out[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote || (c == '\n' && quote != '`') {
+				quote = 0
+			}
+		case c == '"' || c == '\'' || c == '`':
+			quote = c
+		case c == '/' && i+1 < len(out) && out[i+1] == '/':
+			for ; i < len(out) && out[i] != '\n'; i++ {
+				out[i] = ' '
+			}
+		case c == '/' && i+1 < len(out) && out[i+1] == '*':
+			end := len(out)
+			if j := strings.Index(string(out[i+2:]), "*/"); j >= 0 {
+				end = i + 2 + j + 2
+			}
+			for ; i < end; i++ {
+				if out[i] != '\n' {
+					out[i] = ' '
+				}
+			}
+			i--
+		}
+	}
+	return out
+}
+
+// trailingComma matches a comma before a closing bracket, which JSONC allows.
+var trailingComma = regexp.MustCompile(`,(\s*[}\]])`)
+
+// stripTrailingCommas removes the trailing commas of JSONC so that it parses
+// as JSON.
+func stripTrailingCommas(data []byte) []byte {
+	return trailingComma.ReplaceAll(data, []byte("$1"))
+}
@@ -0,0 +1,105 @@
+package deps
+
+import (
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+// jsTree is a frontend with two pages sharing components, path aliases
+// inherited from a base config, and packages that are not followed.
+var jsTree = fstest.MapFS{
+	"package.json": goFile(`{"name": "web"}`),
+	"tsconfig.base.json": goFile(`{
+		// Shared settings.
+		"compilerOptions": {
+			"baseUrl": "src",
+			"paths": {
+				"@components/*": ["components/*"],
+				"@config": ["config/index.ts"],
+			},
+		},
+	}`),
+	"tsconfig.json": goFile(`{"extends": "./tsconfig.base.json", "include": ["src/**/*"]}`),
+	"src/pages/Home.tsx": goFile(`import React from "react";
+import { Button } from "@components/Button";
+import type { Theme } from '../theme';
+import config from "@config";
+import "./Home.css";
+// import { Legacy } from "./Legacy";
+/* import Old from "./Old"; */
+const Chart = React.lazy(() => import("../charts"));
+export * from "./shared.js";
+`),
+	"src/pages/Home.css":          goFile(".home {}"),
+	"src/pages/Legacy.tsx":        goFile("export const Legacy = 1;\n"),
+	"src/pages/Old.tsx":           goFile("export default 1;\n"),
+	"src/pages/shared.ts":         goFile("export const s = 1;\n"),
+	"src/pages/About.tsx":         goFile(`import { Button } from "@components/Button";` + "\n"),
+	"src/theme.ts":                goFile("export type Theme = {};\n"),
+	"src/charts/index.jsx":        goFile("const util = require('./util');\nmodule.exports = util;\n"),
+	"src/charts/util.js":          goFile("module.exports = {};\n"),
+	"src/components/Button.tsx":   goFile(`export { Icon } from "./Icon";` + "\n"),
+	"src/components/Icon.tsx":     goFile("export const Icon = () => null;\n"),
+	"src/config/index.ts":         goFile(`import { env } from "utils/env";` + "\nexport default env;\n"),
+	"src/utils/env.ts":            goFile("export const env = {};\n"),
+	"node_modules/react/index.js": goFile("module.exports = {};\n"),
+}
+
+func TestJSClosure(t *testing.T) {
+	testCases := []struct {
+		name  string
+		entry string
+		want  []string
+	}{
+		{
+			name:  "Relative imports, aliases and baseUrl",
+			entry: "./src/pages/Home.tsx",
+			want: []string{
+				"src/charts/index.jsx", "src/charts/util.js", "src/components/Button.tsx", "src/components/Icon.tsx",
+				"src/config/index.ts", "src/pages/Home.css", "src/pages/Home.tsx", "src/pages/shared.ts",
+				"src/theme.ts", "src/utils/env.ts", "tsconfig.base.json", "tsconfig.json",
+			},
+		},
+		{
+			name:  "Entry without an extension",
+			entry: "src/pages/About",
+			want:  []string{"src/components/Button.tsx", "src/components/Icon.tsx", "src/pages/About.tsx", "tsconfig.base.json", "tsconfig.json"},
+		},
+		{
+			name:  "Directory entry resolves its index",
+			entry: "./src/charts",
+			want:  []string{"src/charts/index.jsx", "src/charts/util.js", "tsconfig.base.json", "tsconfig.json"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := JSClosure(jsTree, tc.entry)
+			if err != nil {
+				t.Fatalf("JSClosure() returned an unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("JSClosure() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestJSClosureErrors(t *testing.T) {
+	broken := fstest.MapFS{
+		"a.ts":              goFile(`import "./b";`),
+		"bad/app.ts":        goFile("export {};\n"),
+		"bad/tsconfig.json": goFile("{ nope"),
+	}
+	for _, entry := range []string{"./missing.ts", "../outside.ts", "bad/app.ts"} {
+		t.Run(entry, func(t *testing.T) {
+			if _, err := JSClosure(broken, entry); err == nil {
+				t.Errorf("JSClosure(%q) did not return an error", entry)
+			}
+		})
+	}
+	if got, err := JSClosure(broken, "a.ts"); err != nil || !reflect.DeepEqual(got, []string{"a.ts"}) {
+		t.Errorf("JSClosure() = %v, %v, want unresolved imports skipped", got, err)
+	}
+}
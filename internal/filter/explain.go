@@ -0,0 +1,46 @@
+// file: internal/filter/explain.go
+package filter
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Decision records why a path is included in or excluded from a slice.
+type Decision struct {
+	Path     string
+	IsDir    bool
+	Included bool
+	Rule     *Rule  // The rule that decided the outcome, or nil if none matched.
+	Parent   string // An excluded ancestor directory that stopped traversal, if any.
+	Own      *Rule  // With Parent set, the rule that would otherwise have applied to Path.
+}
+
+// Location returns the manifest position of the rule as "file:line", or just
+// the file for built-in rules that have no line.
+func (r *Rule) Location() string {
+	if r.Line == 0 {
+		return r.File
+	}
+	return fmt.Sprintf("%s:%d", r.File, r.Line)
+}
+
+// Explain evaluates the path the way a slice traversal would and reports the
+// rule responsible for the outcome. Ancestor directories are checked first,
+// because once a directory is excluded its contents are never examined; this
+// is how a missing "+ **/" rule silently drops nested files.
+func (rs Rules) Explain(name string, isDir bool) Decision {
+	d := Decision{Path: name, IsDir: isDir}
+	parts := strings.Split(name, "/")
+	for i := 1; i < len(parts); i++ {
+		parent := strings.Join(parts[:i], "/")
+		if rule := rs.Match(parent, true); rule != nil && rule.Action == Exclude {
+			d.Parent, d.Rule = parent, rule
+			d.Own = rs.Match(name, isDir)
+			return d
+		}
+	}
+	d.Rule = rs.Match(name, isDir)
+	d.Included = d.Rule == nil || d.Rule.Action == Include
+	return d
+}
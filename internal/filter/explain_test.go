@@ -0,0 +1,60 @@
+package filter
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestExplain(t *testing.T) {
+	rules := Rules{
+		NewRule(Include, "*.go"),
+		NewRule(Exclude, "/docs/trace.log"),
+		NewRule(Exclude, "*"),
+	}
+	for i := range rules {
+		rules[i].File, rules[i].Line = "manifest.txt", i+1
+	}
+
+	testCases := []struct {
+		name       string
+		path       string
+		isDir      bool
+		wantIncl   bool
+		wantLine   int
+		wantParent string
+		wantOwn    int
+	}{
+		{"Included by a rule", "main.go", false, true, 1, "", 0},
+		{"Excluded by a rule", "README.md", false, false, 3, "", 0},
+		{"Blocked by an excluded parent", "src/app/app.go", false, false, 3, "src", 1},
+		{"Excluded directory", "docs", true, false, 3, "", 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			d := rules.Explain(tc.path, tc.isDir)
+			if d.Included != tc.wantIncl {
+				t.Errorf("Included = %v, want %v", d.Included, tc.wantIncl)
+			}
+			if d.Rule == nil || d.Rule.Line != tc.wantLine {
+				t.Errorf("Rule = %+v, want line %d", d.Rule, tc.wantLine)
+			}
+			if d.Parent != tc.wantParent {
+				t.Errorf("Parent = %q, want %q", d.Parent, tc.wantParent)
+			}
+			if tc.wantOwn != 0 && (d.Own == nil || d.Own.Line != tc.wantOwn) {
+				t.Errorf("Own = %+v, want line %d", d.Own, tc.wantOwn)
+			}
+			if d.Rule.Location() != fmt.Sprintf("manifest.txt:%d", tc.wantLine) {
+				t.Errorf("Location() = %q", d.Rule.Location())
+			}
+		})
+	}
+
+	t.Run("No rule matched", func(t *testing.T) {
+		d := Rules{}.Explain("main.go", false)
+		if !d.Included || d.Rule != nil {
+			t.Errorf("Explain() = %+v, want included with no rule", d)
+		}
+	})
+}
@@ -0,0 +1,428 @@
+// file: internal/filter/filter.go
+
+// Package filter parses manifests written in rsync's filter-rule syntax and
+// decides which paths they select. It allows the slicer to evaluate a manifest
+// in-process instead of depending on an installed rsync binary.
+package filter
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Action is the effect a rule has on the paths it matches.
+type Action int
+
+const (
+	// Include keeps a matching path in the slice.
+	Include Action = iota
+	// Exclude removes a matching path, and for directories everything inside.
+	Exclude
+)
+
+// String returns the rsync short form of the action.
+func (a Action) String() string {
+	if a == Exclude {
+		return "-"
+	}
+	return "+"
+}
+
+// Rule is a single include or exclude rule together with the manifest location
+// it was read from, so that decisions can be traced back to a line.
+type Rule struct {
+	Action  Action
+	Pattern string // The pattern as written, including any anchoring slash.
+	Negate  bool   // Set by the "!" modifier; the rule matches paths the pattern does not.
+	File    string // The manifest file that declared the rule.
+	Line    int    // The 1-based line number within File.
+	Text    string // The raw manifest line.
+	Set     *Set   // For a pattern naming a computed set of files, the set.
+	// Transform names the transform applied to the content of the files the
+	// rule includes, such as TransformGoAPI, or is "" to copy them as they are.
+	// It is not part of Pattern.
+	Transform string
+
+	match      string // Pattern stripped of its anchoring and trailing slash.
+	anchored   bool
+	dirOnly    bool
+	wild       bool
+	wild2      bool
+	wild2First bool
+	wild3      bool
+	slashes    int
+	git        bool // Matched with gitignore rather than rsync semantics.
+}
+
+// Rules is an ordered rule list evaluated with rsync's first-match-wins logic.
+type Rules []Rule
+
+// FileSystem defines the file access needed to read manifests, allowing a mock
+// implementation in unit tests.
+type FileSystem interface {
+	ReadFile(name string) ([]byte, error)
+}
+
+// LiveFS is a concrete implementation of the FileSystem interface that uses
+// the standard library's os package.
+type LiveFS struct{}
+
+// ReadFile reads the named file using the os package.
+func (LiveFS) ReadFile(name string) ([]byte, error) {
+	return os.ReadFile(name)
+}
+
+// ruleKind identifies the rule types understood by the parser.
+type ruleKind int
+
+const (
+	kindInclude ruleKind = iota
+	kindExclude
+	kindMerge
+	kindDirMerge
+	kindClear
+	kindReceiver
+)
+
+// longNames maps rsync's long rule names to their kinds. Hide and show only
+// apply to the sending side, which is the only side a local slice has, so they
+// behave like exclude and include.
+var longNames = []struct {
+	name string
+	kind ruleKind
+}{
+	{"include", kindInclude},
+	{"exclude", kindExclude},
+	{"merge", kindMerge},
+	{"dir-merge", kindDirMerge},
+	{"clear", kindClear},
+	{"hide", kindExclude},
+	{"show", kindInclude},
+	{"protect", kindReceiver},
+	{"risk", kindReceiver},
+}
+
+var shortNames = map[byte]ruleKind{
+	'+': kindInclude,
+	'-': kindExclude,
+	'.': kindMerge,
+	':': kindDirMerge,
+	'!': kindClear,
+	'H': kindExclude,
+	'S': kindInclude,
+	'P': kindReceiver,
+	'R': kindReceiver,
+}
+
+// knownModifiers lists every modifier rsync accepts after a rule name.
+const knownModifiers = "!/CsrpxenwN+-"
+
+// maxMergeDepth bounds nested merge files so that a cycle is reported rather
+// than exhausting the stack.
+const maxMergeDepth = 32
+
+// ErrUnsupported marks problems caused by valid rsync syntax that the native
+// engine does not implement, as opposed to lines rsync itself would reject.
+var ErrUnsupported = errors.New("unsupported")
+
+// Problem is a manifest line that could not be turned into a rule.
+type Problem struct {
+	File string
+	Line int
+	Text string
+	Err  error
+}
+
+// Error formats the problem with its manifest location.
+func (p Problem) Error() string {
+	return fmt.Sprintf("%s:%d: %v", p.File, p.Line, p.Err)
+}
+
+// Load reads the manifest at path and returns its rules in evaluation order.
+// The format selects the manifest dialect; when it is empty the dialect is
+// taken from the manifest's header line. For rsync manifests, merge rules
+// ("." and "merge") are expanded in place and relative merge paths are
+// resolved against baseDir, the directory rsync would run from. The first
+// invalid or unsupported line is returned as an error.
+func Load(path, baseDir string, format Format, fsys FileSystem) (Rules, error) {
+	rules, problems, err := LoadAll(path, baseDir, format, fsys)
+	if err != nil {
+		return nil, err
+	}
+	if len(problems) > 0 {
+		return nil, problems[0]
+	}
+	return rules, nil
+}
+
+// LoadAll behaves like Load but skips lines it cannot use and reports them as
+// problems, so that a whole manifest can be diagnosed in one pass. The error
+// is reserved for manifests that cannot be read at all.
+func LoadAll(path, baseDir string, format Format, fsys FileSystem) (Rules, []Problem, error) {
+	data, err := fsys.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+	if format, err = resolveFormat(format, data); err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if format == FormatGitignore {
+		return parseGitignore(data, path), nil, nil
+	}
+
+	l := loader{baseDir: baseDir, fsys: fsys}
+	if err := l.parse(path, data, 0); err != nil {
+		return nil, nil, err
+	}
+	return l.rules, l.problems, nil
+}
+
+// loader accumulates rules and problems while expanding merge files.
+type loader struct {
+	baseDir  string
+	fsys     FileSystem
+	rules    Rules
+	problems []Problem
+}
+
+func (l *loader) load(file string, depth int) error {
+	if depth > maxMergeDepth {
+		return fmt.Errorf("%s: merge files nested more than %d levels deep", file, maxMergeDepth)
+	}
+	data, err := l.fsys.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("failed to read manifest: %w", err)
+	}
+	return l.parse(file, data, depth)
+}
+
+func (l *loader) parse(file string, data []byte, depth int) error {
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		lineNo := i + 1
+		problem := func(err error) {
+			l.problems = append(l.problems, Problem{File: file, Line: lineNo, Text: line, Err: err})
+		}
+		kind, mods, arg, ok, err := parseLine(line)
+		if err != nil {
+			problem(err)
+			continue
+		}
+		if !ok {
+			continue
+		}
+
+		switch kind {
+		case kindClear:
+			l.rules = l.rules[:0]
+		case kindReceiver:
+			// Protect and risk rules only govern deletions on the receiver.
+		case kindDirMerge:
+			problem(fmt.Errorf("%w per-directory merge rule", ErrUnsupported))
+		case kindMerge:
+			if mods != "" {
+				problem(fmt.Errorf("%w modifier %q on merge rule", ErrUnsupported, mods))
+				continue
+			}
+			mergePath := arg
+			if !filepath.IsAbs(mergePath) {
+				mergePath = filepath.Join(l.baseDir, mergePath)
+			}
+			if err := l.load(mergePath, depth+1); err != nil {
+				return err
+			}
+		default:
+			if strings.Contains(mods, "r") {
+				// Receiver-only rules do not influence what is transferred.
+				continue
+			}
+			if unsupported := strings.Trim(mods, "!sp"); unsupported != "" {
+				problem(fmt.Errorf("%w modifier %q", ErrUnsupported, unsupported))
+				continue
+			}
+			action := Include
+			if kind == kindExclude {
+				action = Exclude
+			}
+			transform, pattern, err := parseTransform(action, arg)
+			if err != nil {
+				problem(err)
+				continue
+			}
+			rule := NewRule(action, pattern)
+			set, err := ParseSet(pattern)
+			if err != nil {
+				problem(err)
+				continue
+			}
+			rule.Set, rule.Transform = set, transform
+			rule.Negate = strings.Contains(mods, "!")
+			rule.File, rule.Line, rule.Text = file, lineNo, line
+			l.rules = append(l.rules, rule)
+		}
+	}
+	return nil
+}
+
+// parseLine splits a manifest line into its rule kind, modifiers and argument.
+// It returns ok=false for blank lines and comments.
+func parseLine(line string) (kind ruleKind, mods, arg string, ok bool, err error) {
+	s := strings.TrimLeft(line, " \t")
+	if s == "" || s[0] == '#' || s[0] == ';' {
+		return 0, "", "", false, nil
+	}
+
+	rest, found := "", false
+	for _, ln := range longNames {
+		if r, cut := strings.CutPrefix(s, ln.name); cut && (r == "" || strings.ContainsRune(", _", rune(r[0]))) {
+			kind, rest, found = ln.kind, strings.TrimPrefix(r, ","), true
+			break
+		}
+	}
+	if !found {
+		k, known := shortNames[s[0]]
+		if !known {
+			return 0, "", "", false, fmt.Errorf("unknown filter rule: %q", line)
+		}
+		kind, rest = k, strings.TrimPrefix(s[1:], ",")
+	}
+
+	end := strings.IndexAny(rest, " _")
+	if end < 0 {
+		end = len(rest)
+	}
+	mods = strings.ReplaceAll(rest[:end], ",", "")
+	if bad := strings.Trim(mods, knownModifiers); bad != "" {
+		return 0, "", "", false, fmt.Errorf("invalid modifier %q in rule %q", bad, line)
+	}
+	if end < len(rest) {
+		arg = rest[end+1:]
+	}
+
+	if kind == kindClear {
+		return kind, mods, "", true, nil
+	}
+	if arg == "" {
+		return 0, "", "", false, fmt.Errorf("rule %q is missing a pattern", line)
+	}
+	return kind, mods, arg, true, nil
+}
+
+// NewRule compiles a pattern into a rule with the given action. The pattern
+// follows rsync's conventions: a leading "/" anchors it to the root of the
+// slice and a trailing "/" restricts it to directories.
+func NewRule(action Action, pattern string) Rule {
+	r := Rule{Action: action, Pattern: pattern, Text: action.String() + " " + pattern}
+
+	p := pattern
+	if len(p) > 1 && strings.HasSuffix(p, "/") {
+		p = strings.TrimSuffix(p, "/")
+		r.dirOnly = true
+	}
+	r.slashes = strings.Count(p, "/")
+	r.wild = strings.ContainsAny(p, "*?[")
+	r.wild2 = strings.Contains(p, "**")
+	r.wild2First = strings.HasPrefix(p, "**")
+	r.wild3 = len(p) > 4 && strings.HasSuffix(p, "/***")
+	if strings.HasPrefix(p, "/") {
+		r.anchored = true
+		p = p[1:]
+	}
+	r.match = p
+	return r
+}
+
+// Matches reports whether the rule applies to the slash-separated path, which
+// is relative to the root of the source tree.
+func (r *Rule) Matches(name string, isDir bool) bool {
+	if r.Set != nil {
+		return r.Set.matches(name, isDir, r.Action) != r.Negate
+	}
+	if r.dirOnly && !isDir {
+		return r.Negate
+	}
+	matched := r.matchName(name)
+	if !matched && r.wild3 && isDir {
+		// "dir/***" matches the directory itself as well as its contents.
+		matched = r.matchPattern(strings.TrimSuffix(r.match, "/***"), name)
+	}
+	return matched != r.Negate
+}
+
+func (r *Rule) matchName(name string) bool {
+	return r.matchPattern(r.match, name)
+}
+
+// matchPattern mirrors rsync's rule_matches(): patterns without a slash or
+// "**" only see the final path component, unanchored patterns may match at a
+// component boundary, and a leading "**" may also match zero directories.
+func (r *Rule) matchPattern(p, name string) bool {
+	if r.git {
+		return r.matchGit(p, name)
+	}
+	if r.slashes == 0 && !r.wild2 {
+		name = path.Base(name)
+	} else if r.wild2First {
+		name = "/" + name
+	}
+
+	if !r.wild {
+		if r.anchored {
+			return name == p
+		}
+		return name == p || strings.HasSuffix(name, "/"+p)
+	}
+
+	switch {
+	case !r.anchored && r.slashes > 0 && !r.wild2:
+		// Match against the last slashes+1 path components.
+		start := len(name)
+		for n := 0; n <= r.slashes && start > 0; n++ {
+			start = strings.LastIndexByte(name[:start], '/')
+			if start < 0 {
+				start = 0
+				break
+			}
+		}
+		if start > 0 {
+			start++
+		}
+		return wildmatch(p, name[start:])
+	case !r.anchored && !r.wild2First && r.wild2:
+		// Try the pattern after every directory separator.
+		for text := name; ; {
+			if wildmatch(p, text) {
+				return true
+			}
+			i := strings.IndexByte(text, '/')
+			if i < 0 {
+				return false
+			}
+			text = text[i+1:]
+		}
+	default:
+		return wildmatch(p, name)
+	}
+}
+
+// Match returns the first rule that applies to the path, or nil if none do.
+func (rs Rules) Match(name string, isDir bool) *Rule {
+	for i := range rs {
+		if rs[i].Matches(name, isDir) {
+			return &rs[i]
+		}
+	}
+	return nil
+}
+
+// Included reports whether the path survives the rule list on its own. Paths
+// that no rule matches are included, as they are with rsync. Callers walking a
+// tree must still skip the contents of excluded directories.
+func (rs Rules) Included(name string, isDir bool) bool {
+	rule := rs.Match(name, isDir)
+	return rule == nil || rule.Action == Include
+}
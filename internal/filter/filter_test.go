@@ -0,0 +1,195 @@
+package filter
+
+import (
+	"errors"
+	"io/fs"
+	"strings"
+	"testing"
+)
+
+// mockFS is a map-backed implementation of the FileSystem interface.
+type mockFS map[string]string
+
+func (m mockFS) ReadFile(name string) ([]byte, error) {
+	data, ok := m[name]
+	if !ok {
+		return nil, fs.ErrNotExist
+	}
+	return []byte(data), nil
+}
+
+func TestRuleMatches(t *testing.T) {
+	testCases := []struct {
+		name    string
+		pattern string
+		path    string
+		isDir   bool
+		want    bool
+	}{
+		{"Unanchored name matches at any depth", "main.go", "cmd/main.go", false, true},
+		{"Unanchored name needs a whole component", "main.go", "cmd/xmain.go", false, false},
+		{"Anchored name matches at root", "/main.go", "main.go", false, true},
+		{"Anchored name ignores nested paths", "/main.go", "cmd/main.go", false, false},
+		{"Unanchored path matches trailing components", "app/app.go", "src/app/app.go", false, true},
+		{"Unanchored wildcard path matches trailing components", "app/*.go", "src/app/app.go", false, true},
+		{"Unanchored wildcard path needs enough components", "src/app/*.go", "app/app.go", false, false},
+		{"Directory rule skips files", "docs/", "docs", false, false},
+		{"Directory rule matches directories", "docs/", "docs", true, true},
+		{"Star does not cross directories", "/src/*", "src/app/app.go", false, false},
+		{"Double star crosses directories", "/src/**", "src/app/app.go", false, true},
+		{"Double star does not match its own directory", "/src/**", "src", true, false},
+		{"Leading double star matches at root", "**/*.md", "README.md", false, true},
+		{"Leading double star matches nested", "**/*.md", "docs/guide.md", false, true},
+		{"Double star directory rule", "**/", "src/app", true, true},
+		{"Infix double star tries every boundary", "app/**.go", "src/app/app.go", false, true},
+		{"Triple star matches the directory", "/src/***", "src", true, true},
+		{"Triple star matches contents", "/src/***", "src/app/app.go", false, true},
+		{"Character class", "*.[ch]", "lib/x.h", false, true},
+		{"Question mark", "?.txt", "a.txt", false, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rule := NewRule(Include, tc.pattern)
+			if got := rule.Matches(tc.path, tc.isDir); got != tc.want {
+				t.Errorf("%q.Matches(%q, %v) = %v, want %v", tc.pattern, tc.path, tc.isDir, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRuleNegate(t *testing.T) {
+	rule := NewRule(Exclude, "*/")
+	rule.Negate = true
+	if !rule.Matches("main.go", false) {
+		t.Error("negated directory rule should match files")
+	}
+	if rule.Matches("src", true) {
+		t.Error("negated directory rule should not match directories")
+	}
+}
+
+func TestLoad(t *testing.T) {
+	fsys := mockFS{
+		"/m/manifest.txt": "# comment\n; also a comment\n\n+ /main.go\r\n. base.txt\n- *\n",
+		"/src/base.txt":   "include /common.txt\nP protected\n-r receiver-only\n",
+		"/m/clear.txt":    "+ a\n!\n- b\n",
+		"/m/loop.txt":     ". /m/loop.txt\n",
+		"/m/bad.txt":      "* nope\n",
+		"/m/badmod.txt":   "-C foo\n",
+		"/m/dirmerge.txt": ": .rules\n",
+		"/m/empty.txt":    "+\n",
+		"/m/missing.txt":  ". nowhere.txt\n",
+	}
+
+	t.Run("expands merges in place", func(t *testing.T) {
+		rules, err := Load("/m/manifest.txt", "/src", "", fsys)
+		if err != nil {
+			t.Fatalf("Load() returned an unexpected error: %v", err)
+		}
+		var got []string
+		for _, r := range rules {
+			got = append(got, r.Action.String()+" "+r.Pattern)
+		}
+		want := "+ /main.go,+ /common.txt,- *"
+		if strings.Join(got, ",") != want {
+			t.Errorf("Load() = %v, want %v", got, want)
+		}
+		if rules[0].Line != 4 || rules[0].File != "/m/manifest.txt" || rules[0].Text != "+ /main.go" {
+			t.Errorf("rule location = %s:%d %q", rules[0].File, rules[0].Line, rules[0].Text)
+		}
+		if rules[1].File != "/src/base.txt" || rules[1].Line != 1 {
+			t.Errorf("merged rule location = %s:%d", rules[1].File, rules[1].Line)
+		}
+	})
+
+	t.Run("clear rule resets the list", func(t *testing.T) {
+		rules, err := Load("/m/clear.txt", "/src", "", fsys)
+		if err != nil {
+			t.Fatalf("Load() returned an unexpected error: %v", err)
+		}
+		if len(rules) != 1 || rules[0].Pattern != "b" {
+			t.Errorf("Load() = %+v, want only the rule after the clear", rules)
+		}
+	})
+
+	errorCases := []string{
+		"/m/loop.txt",
+		"/m/bad.txt",
+		"/m/badmod.txt",
+		"/m/dirmerge.txt",
+		"/m/empty.txt",
+		"/m/missing.txt",
+		"/m/does-not-exist.txt",
+	}
+	for _, name := range errorCases {
+		t.Run("fails for "+name, func(t *testing.T) {
+			if _, err := Load(name, "/src", "", fsys); err == nil {
+				t.Errorf("Load(%q) did not return an error", name)
+			}
+		})
+	}
+}
+
+func TestLoadAll(t *testing.T) {
+	fsys := mockFS{"/m/manifest.txt": "+ a\n-C foo\n* nope\n: .rules\n- b\n"}
+
+	rules, problems, err := LoadAll("/m/manifest.txt", "/src", "", fsys)
+	if err != nil {
+		t.Fatalf("LoadAll() returned an unexpected error: %v", err)
+	}
+	if len(rules) != 2 {
+		t.Errorf("LoadAll() returned %d rules, want 2", len(rules))
+	}
+	if len(problems) != 3 {
+		t.Fatalf("LoadAll() returned %d problems, want 3", len(problems))
+	}
+	wantUnsupported := []bool{true, false, true}
+	for i, p := range problems {
+		if p.Line != i+2 {
+			t.Errorf("problem %d on line %d, want %d", i, p.Line, i+2)
+		}
+		if errors.Is(p.Err, ErrUnsupported) != wantUnsupported[i] {
+			t.Errorf("problem %d (%v) unsupported = %v, want %v", i, p, !wantUnsupported[i], wantUnsupported[i])
+		}
+	}
+	if problems[0].Error() != `/m/manifest.txt:2: unsupported modifier "C"` {
+		t.Errorf("Problem.Error() = %q", problems[0].Error())
+	}
+
+	if _, _, err := LoadAll("/m/missing.txt", "/src", "", fsys); err == nil {
+		t.Error("LoadAll() did not return an error for a missing manifest")
+	}
+}
+
+func TestRulesFirstMatchWins(t *testing.T) {
+	rules := Rules{
+		NewRule(Exclude, "/src/app/app_test.go"),
+		NewRule(Include, "/src/**"),
+		NewRule(Include, "**/"),
+		NewRule(Exclude, "*"),
+	}
+
+	testCases := []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{"src/app/app_test.go", false, false},
+		{"src/app/app.go", false, true},
+		{"docs", true, true},
+		{"README.md", false, false},
+	}
+	for _, tc := range testCases {
+		if got := rules.Included(tc.path, tc.isDir); got != tc.want {
+			t.Errorf("Included(%q) = %v, want %v", tc.path, got, tc.want)
+		}
+	}
+
+	if (Rules{}).Match("anything", false) != nil {
+		t.Error("an empty rule list should not match")
+	}
+	if !(Rules{}).Included("anything", false) {
+		t.Error("paths matched by no rule should be included")
+	}
+}
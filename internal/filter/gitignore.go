@@ -0,0 +1,151 @@
+// file: internal/filter/gitignore.go
+package filter
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Format names a manifest dialect.
+type Format string
+
+const (
+	// FormatRsync is rsync's filter-rule syntax, an allow-list evaluated with
+	// first-match-wins logic.
+	FormatRsync Format = "rsync"
+	// FormatGitignore is .gitignore syntax: every path is included unless it
+	// is ignored, the last matching pattern wins, and "!" re-includes a path.
+	FormatGitignore Format = "gitignore"
+)
+
+// Valid reports whether the format is a recognised dialect. The empty format
+// is valid and means the dialect is read from the manifest header.
+func (f Format) Valid() bool {
+	return f == "" || f == FormatRsync || f == FormatGitignore
+}
+
+// formatHeader matches the optional first manifest line that declares its
+// dialect, for example "# format: gitignore". It is a comment in both
+// dialects, so it never changes the rules themselves.
+var formatHeader = regexp.MustCompile(`^#\s*format:\s*(\S+)\s*$`)
+
+// DetectFormat returns the dialect of the manifest at path. An explicit format
+// takes precedence over the manifest header, and manifests without a header
+// use rsync syntax.
+func DetectFormat(path string, format Format, fsys FileSystem) (Format, error) {
+	data, err := fsys.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read manifest: %w", err)
+	}
+	format, err = resolveFormat(format, data)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", path, err)
+	}
+	return format, nil
+}
+
+func resolveFormat(format Format, data []byte) (Format, error) {
+	if !format.Valid() {
+		return "", fmt.Errorf("unknown manifest format %q", format)
+	}
+	if format != "" {
+		return format, nil
+	}
+	first, _, _ := strings.Cut(string(data), "\n")
+	m := formatHeader.FindStringSubmatch(strings.TrimSuffix(first, "\r"))
+	if m == nil {
+		return FormatRsync, nil
+	}
+	format = Format(strings.ToLower(m[1]))
+	if format == "" || !format.Valid() {
+		return "", fmt.Errorf("unknown manifest format %q in header", m[1])
+	}
+	return format, nil
+}
+
+// parseGitignore converts gitignore patterns into rules. Gitignore is
+// evaluated last-match-wins, so the rules are returned in reverse order to give
+// the same result under first-match-wins evaluation.
+func parseGitignore(data []byte, file string) Rules {
+	var rules Rules
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		p := trimUnescapedSpace(line)
+		if p == "" || p[0] == '#' {
+			continue
+		}
+		action := Exclude
+		if p[0] == '!' {
+			action, p = Include, p[1:]
+		} else if strings.HasPrefix(p, `\#`) || strings.HasPrefix(p, `\!`) {
+			p = p[1:]
+		}
+		if p == "" || p == "/" {
+			continue
+		}
+		rule := newGitRule(action, p)
+		rule.File, rule.Line, rule.Text = file, i+1, line
+		rules = append(rules, rule)
+	}
+
+	for i, j := 0, len(rules)-1; i < j; i, j = i+1, j-1 {
+		rules[i], rules[j] = rules[j], rules[i]
+	}
+	return rules
+}
+
+// trimUnescapedSpace removes trailing spaces unless they are escaped with a
+// backslash, as git does.
+func trimUnescapedSpace(s string) string {
+	for strings.HasSuffix(s, " ") && !strings.HasSuffix(s, `\ `) {
+		s = s[:len(s)-1]
+	}
+	return s
+}
+
+// newGitRule compiles a gitignore pattern. A pattern containing a slash other
+// than a trailing one is relative to the root; otherwise it matches a name at
+// any depth.
+func newGitRule(action Action, pattern string) Rule {
+	r := Rule{Action: action, Pattern: pattern, Text: pattern, git: true}
+	p := pattern
+	if strings.HasSuffix(p, "/") {
+		p = strings.TrimSuffix(p, "/")
+		r.dirOnly = true
+	}
+	if strings.Contains(p, "/") {
+		r.anchored = true
+		p = strings.TrimPrefix(p, "/")
+	}
+	r.match = p
+	return r
+}
+
+// matchGit applies gitignore matching to a path.
+func (r *Rule) matchGit(p, name string) bool {
+	if !r.anchored {
+		return wildmatch(p, path.Base(name))
+	}
+	return gitWildmatch(p, name)
+}
+
+// gitWildmatch extends wildmatch with git's rule that a "**/" at the start of a
+// pattern or after a slash also matches zero directories, so "a/**/b" matches
+// "a/b" and "**/foo" matches "foo".
+func gitWildmatch(p, text string) bool {
+	if wildmatch(p, text) {
+		return true
+	}
+	for i := 0; ; i++ {
+		j := strings.Index(p[i:], "**/")
+		if j < 0 {
+			return false
+		}
+		i += j
+		if (i == 0 || p[i-1] == '/') && gitWildmatch(p[:i]+p[i+3:], text) {
+			return true
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package filter
+
+import (
+	"testing"
+)
+
+func TestGitignoreMatches(t *testing.T) {
+	testCases := []struct {
+		name    string
+		pattern string
+		path    string
+		isDir   bool
+		want    bool
+	}{
+		{"Name matches at any depth", "*.log", "docs/trace.log", false, true},
+		{"Name matches directories", "build", "src/build", true, true},
+		{"Directory pattern skips files", "build/", "build", false, false},
+		{"Directory pattern matches nested directories", "build/", "src/build", true, true},
+		{"Leading slash anchors to the root", "/main.go", "cmd/main.go", false, false},
+		{"Middle slash anchors to the root", "docs/*.md", "src/docs/guide.md", false, false},
+		{"Anchored pattern matches from the root", "docs/*.md", "docs/guide.md", false, true},
+		{"Star does not cross directories", "docs/*", "docs/api/index.md", false, false},
+		{"Leading double star matches at root", "**/node_modules", "node_modules", true, true},
+		{"Leading double star matches nested", "**/node_modules", "web/app/node_modules", true, true},
+		{"Infix double star matches zero directories", "a/**/b", "a/b", false, true},
+		{"Infix double star matches several directories", "a/**/b", "a/x/y/b", false, true},
+		{"Trailing double star matches contents", "vendor/**", "vendor/lib/x.go", false, true},
+		{"Trailing double star does not match the directory", "vendor/**", "vendor", true, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rule := newGitRule(Exclude, tc.pattern)
+			if got := rule.Matches(tc.path, tc.isDir); got != tc.want {
+				t.Errorf("%q.Matches(%q, %v) = %v, want %v", tc.pattern, tc.path, tc.isDir, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestLoadGitignore(t *testing.T) {
+	manifest := "# build output\n*.log\n!keep.log\n\ntmp/   \ntrailing\\ \n\\#notes\n"
+	fsys := mockFS{
+		"/m/ignore.txt": manifest,
+		"/m/header.txt": "# format: gitignore\n" + manifest,
+		"/m/bad.txt":    "# format: ant\n*.log\n",
+	}
+
+	rules, problems, err := LoadAll("/m/ignore.txt", "/src", FormatGitignore, fsys)
+	if err != nil || len(problems) > 0 {
+		t.Fatalf("LoadAll() = %v, %v", problems, err)
+	}
+	if len(rules) != 5 || rules[0].Pattern != "#notes" || rules[4].Line != 2 {
+		t.Fatalf("LoadAll() = %+v, want five rules in reverse order", rules)
+	}
+
+	testCases := []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{"main.go", false, true},
+		{"docs/trace.log", false, false},
+		{"docs/keep.log", false, true},
+		{"tmp", true, false},
+		{"tmp", false, true},
+		{"trailing ", false, false},
+		{"#notes", false, false},
+	}
+	for _, tc := range testCases {
+		if got := rules.Included(tc.path, tc.isDir); got != tc.want {
+			t.Errorf("Included(%q, %v) = %v, want %v", tc.path, tc.isDir, got, tc.want)
+		}
+	}
+
+	rules, err = Load("/m/header.txt", "/src", "", fsys)
+	if err != nil {
+		t.Fatalf("Load() returned an unexpected error: %v", err)
+	}
+	if len(rules) != 5 || rules.Included("docs/trace.log", false) {
+		t.Errorf("Load() did not honour the format header: %+v", rules)
+	}
+
+	if _, err := Load("/m/bad.txt", "/src", "", fsys); err == nil {
+		t.Error("Load() did not return an error for an unknown format header")
+	}
+	if _, err := Load("/m/ignore.txt", "/src", "ant", fsys); err == nil {
+		t.Error("Load() did not return an error for an unknown format")
+	}
+}
+
+func TestDetectFormat(t *testing.T) {
+	fsys := mockFS{
+		"/plain.txt":  "+ *.go\n",
+		"/header.txt": "#format: GitIgnore\r\n*.log\n",
+	}
+
+	testCases := []struct {
+		path   string
+		format Format
+		want   Format
+	}{
+		{"/plain.txt", "", FormatRsync},
+		{"/header.txt", "", FormatGitignore},
+		{"/header.txt", FormatRsync, FormatRsync},
+	}
+	for _, tc := range testCases {
+		got, err := DetectFormat(tc.path, tc.format, fsys)
+		if err != nil || got != tc.want {
+			t.Errorf("DetectFormat(%q, %q) = %q, %v, want %q", tc.path, tc.format, got, err, tc.want)
+		}
+	}
+	if _, err := DetectFormat("/missing.txt", "", fsys); err == nil {
+		t.Error("DetectFormat() did not return an error for a missing manifest")
+	}
+}
@@ -0,0 +1,92 @@
+// file: internal/filter/ignore.go
+package filter
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// gitDirRule reports the repository's own .git directory, which git never
+// treats as part of the working tree.
+var gitDirRule = func() *Rule {
+	r := newGitRule(Exclude, ".git")
+	r.File, r.Text = "git", ".git"
+	return &r
+}()
+
+// GitIgnore applies a repository's ignore files the way git does. A .gitignore
+// file applies to its own directory and everything beneath it, deeper files
+// take precedence over shallower ones, and .git/info/exclude has the lowest
+// precedence of all. Ignore files are read lazily and cached.
+type GitIgnore struct {
+	repo   fs.FS
+	prefix string
+	files  map[string]Rules
+}
+
+// NewGitIgnore returns a GitIgnore for the repository whose working tree is the
+// root of repo. Paths passed to Excluded are relative to prefix, the slash-
+// separated location of the slice source within the working tree.
+func NewGitIgnore(repo fs.FS, prefix string) *GitIgnore {
+	return &GitIgnore{repo: repo, prefix: path.Clean(prefix), files: make(map[string]Rules)}
+}
+
+// Excluded returns the rule that makes git ignore the path, or nil if git
+// would not ignore it.
+func (g *GitIgnore) Excluded(name string, isDir bool) (*Rule, error) {
+	if path.Base(name) == ".git" {
+		return gitDirRule, nil
+	}
+
+	full := path.Join(g.prefix, name)
+	for dir := path.Dir(full); ; dir = path.Dir(dir) {
+		rules, err := g.load(path.Join(dir, ".gitignore"))
+		if err != nil {
+			return nil, err
+		}
+		rel := full
+		if dir != "." {
+			rel = strings.TrimPrefix(full, dir+"/")
+		}
+		if r := rules.Match(rel, isDir); r != nil {
+			return excludedBy(r), nil
+		}
+		if dir == "." {
+			break
+		}
+	}
+
+	if info, err := fs.Stat(g.repo, ".git"); err != nil || !info.IsDir() {
+		return nil, nil // Worktrees and submodules keep their excludes elsewhere.
+	}
+	rules, err := g.load(".git/info/exclude")
+	if err != nil {
+		return nil, err
+	}
+	return excludedBy(rules.Match(full, isDir)), nil
+}
+
+func excludedBy(r *Rule) *Rule {
+	if r == nil || r.Action != Exclude {
+		return nil
+	}
+	return r
+}
+
+// load returns the rules of the ignore file at name, or none if it does not
+// exist.
+func (g *GitIgnore) load(name string) (Rules, error) {
+	if rules, ok := g.files[name]; ok {
+		return rules, nil
+	}
+	data, err := fs.ReadFile(g.repo, name)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	rules := parseGitignore(data, name)
+	g.files[name] = rules
+	return rules, nil
+}
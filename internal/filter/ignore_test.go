@@ -0,0 +1,88 @@
+package filter
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestGitIgnoreExcluded(t *testing.T) {
+	repo := fstest.MapFS{
+		".gitignore":         {Data: []byte("*.log\nbin/\n/.env\n!keep.log\n")},
+		"web/.gitignore":     {Data: []byte("node_modules/\n!debug.log\n/dist\n")},
+		"web/app/.gitignore": {Data: []byte("debug.log\n")},
+		".git/info/exclude":  {Data: []byte("scratch.txt\n")},
+	}
+
+	testCases := []struct {
+		name     string
+		path     string
+		isDir    bool
+		wantRule string // The location of the deciding rule, or "" if not ignored.
+	}{
+		{"Root pattern applies at any depth", "docs/trace.log", false, ".gitignore:1"},
+		{"Negation re-includes", "keep.log", false, ""},
+		{"Directory pattern", "cmd/bin", true, ".gitignore:2"},
+		{"Directory pattern skips files", "cmd/bin", false, ""},
+		{"Anchored pattern", ".env", false, ".gitignore:3"},
+		{"Anchored pattern ignores nested paths", "web/.env", false, ""},
+		{"Nested file applies below its directory", "web/node_modules", true, "web/.gitignore:1"},
+		{"Nested file does not apply elsewhere", "node_modules", true, ""},
+		{"Deeper negation wins", "web/debug.log", false, ""},
+		{"Deepest file wins", "web/app/debug.log", false, "web/app/.gitignore:1"},
+		{"Nested anchored pattern is relative to its directory", "web/dist", true, "web/.gitignore:3"},
+		{"Info exclude applies", "scratch.txt", false, ".git/info/exclude:1"},
+		{"Git directory is always ignored", ".git", true, "git"},
+		{"Nested git directory is always ignored", "vendor/lib/.git", true, "git"},
+		{"Unmatched path is kept", "main.go", false, ""},
+	}
+
+	g := NewGitIgnore(repo, ".")
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rule, err := g.Excluded(tc.path, tc.isDir)
+			if err != nil {
+				t.Fatalf("Excluded() returned an unexpected error: %v", err)
+			}
+			got := ""
+			if rule != nil {
+				got = rule.Location()
+			}
+			if got != tc.wantRule {
+				t.Errorf("Excluded(%q, %v) = %q, want %q", tc.path, tc.isDir, got, tc.wantRule)
+			}
+		})
+	}
+}
+
+func TestGitIgnoreWithPrefix(t *testing.T) {
+	repo := fstest.MapFS{
+		".gitignore":     {Data: []byte("/web/dist\n*.log\n")},
+		"web/.gitignore": {Data: []byte("/tmp\n")},
+	}
+	g := NewGitIgnore(repo, "web")
+
+	for path, want := range map[string]bool{"dist": true, "tmp": true, "a/trace.log": true, "src": false} {
+		rule, err := g.Excluded(path, true)
+		if err != nil {
+			t.Fatalf("Excluded() returned an unexpected error: %v", err)
+		}
+		if (rule != nil) != want {
+			t.Errorf("Excluded(%q) = %v, want ignored %v", path, rule, want)
+		}
+	}
+}
+
+func TestGitIgnoreWithoutGitDirectory(t *testing.T) {
+	repo := fstest.MapFS{
+		".gitignore": {Data: []byte("*.tmp\n")},
+		".git":       {Data: []byte("gitdir: ../.git/worktrees/x\n")},
+	}
+	g := NewGitIgnore(repo, ".")
+	rule, err := g.Excluded("a.tmp", false)
+	if err != nil || rule == nil {
+		t.Errorf("Excluded() = %v, %v, want the .gitignore rule", rule, err)
+	}
+	if rule, err := g.Excluded("main.go", false); err != nil || rule != nil {
+		t.Errorf("Excluded() = %v, %v, want nil", rule, err)
+	}
+}
@@ -0,0 +1,100 @@
+// file: internal/filter/set.go
+package filter
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+)
+
+// Set is a rule pattern that names a computed set of files instead of
+// matching names, such as "gopkg:./cmd/app" for a Go package and everything
+// it imports. A set is written as its kind, any options separated by commas,
+// a colon and its root: "gopkg,tests:./cmd/app". Sets are resolved against the
+// source tree before the rules are evaluated; until then they match nothing.
+type Set struct {
+	Kind    string
+	Options []string
+	Root    string
+
+	files    map[string]bool
+	dirs     map[string]bool // Every directory containing a file of the set.
+	resolved bool
+}
+
+// setOptions lists the known kinds of set and the options each accepts.
+var setOptions = map[string][]string{
+	"gopkg": {"tests", "external"},
+	"js":    nil,
+}
+
+// ParseSet parses a set pattern. It returns nil, and no error, if the pattern
+// does not name a set.
+func ParseSet(pattern string) (*Set, error) {
+	head, root, ok := strings.Cut(pattern, ":")
+	if !ok {
+		return nil, nil
+	}
+	fields := strings.Split(head, ",")
+	known, ok := setOptions[fields[0]]
+	if !ok {
+		return nil, nil
+	}
+	s := &Set{Kind: fields[0], Root: root}
+	for _, opt := range fields[1:] {
+		if len(known) == 0 {
+			return nil, fmt.Errorf("%s sets take no options", s.Kind)
+		}
+		if !slices.Contains(known, opt) {
+			return nil, fmt.Errorf("unknown %s option %q; expected one of %s", s.Kind, opt, strings.Join(known, ", "))
+		}
+		s.Options = append(s.Options, opt)
+	}
+	if root == "" {
+		return nil, fmt.Errorf("%s set is missing its root", s.Kind)
+	}
+	return s, nil
+}
+
+// Has reports whether the set was given the named option.
+func (s *Set) Has(option string) bool {
+	return slices.Contains(s.Options, option)
+}
+
+// Resolved reports whether Resolve has been called.
+func (s *Set) Resolved() bool { return s.resolved }
+
+// Resolve records the slash-separated paths of the files in the set.
+func (s *Set) Resolve(files []string) {
+	s.files, s.dirs = make(map[string]bool), make(map[string]bool)
+	for _, f := range files {
+		s.files[f] = true
+		for dir := path.Dir(f); dir != "."; dir = path.Dir(dir) {
+			s.dirs[dir] = true
+		}
+	}
+	s.resolved = true
+}
+
+// matches reports whether the path is in the set. An include rule also
+// matches the directories leading to its files, so that they are traversed;
+// an exclude rule matches only the files, so that it never removes a
+// directory holding other files.
+func (s *Set) matches(name string, isDir bool, action Action) bool {
+	if isDir {
+		return action == Include && s.dirs[name]
+	}
+	return s.files[name]
+}
+
+// Sets returns the sets named by the rules, in order.
+func (rs Rules) Sets() []*Set {
+	var sets []*Set
+	for i := range rs {
+		if rs[i].Set != nil {
+			sets = append(sets, rs[i].Set)
+		}
+	}
+	return sets
+}
@@ -0,0 +1,73 @@
+package filter
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseSet(t *testing.T) {
+	testCases := []struct {
+		pattern string
+		want    *Set
+		wantErr bool
+	}{
+		{"main.go", nil, false},
+		{"docs:intro.md", nil, false},
+		{"gopkg:./cmd/app", &Set{Kind: "gopkg", Root: "./cmd/app"}, false},
+		{"gopkg,tests,external:./cmd/app", &Set{Kind: "gopkg", Options: []string{"tests", "external"}, Root: "./cmd/app"}, false},
+		{"js:./src/main.tsx", &Set{Kind: "js", Root: "./src/main.tsx"}, false},
+		{"js,tests:./src/main.tsx", nil, true},
+		{"gopkg,vendor:./cmd/app", nil, true},
+		{"gopkg:", nil, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.pattern, func(t *testing.T) {
+			got, err := ParseSet(tc.pattern)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("ParseSet(%q) error = %v, wantErr %v", tc.pattern, err, tc.wantErr)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("ParseSet(%q) = %+v, want %+v", tc.pattern, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSetMatches(t *testing.T) {
+	rules, err := Load("/m/manifest.txt", "/src", "", mockFS{
+		"/m/manifest.txt": "- gopkg:./cmd/tool\n+ gopkg:./cmd/app\n- *\n",
+	})
+	if err != nil {
+		t.Fatalf("Load() returned an unexpected error: %v", err)
+	}
+	if got := len(rules.Sets()); got != 2 {
+		t.Fatalf("Sets() returned %d sets, want 2", got)
+	}
+	if rules.Included("cmd/app/main.go", false) {
+		t.Error("an unresolved set should match nothing")
+	}
+
+	rules.Sets()[0].Resolve([]string{"cmd/tool/main.go"})
+	rules.Sets()[1].Resolve([]string{"cmd/app/main.go", "internal/a/a.go", "go.mod"})
+	for _, tc := range []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{"cmd", true, true},
+		{"cmd/app", true, true},
+		{"cmd/app/main.go", false, true},
+		{"internal/a", true, true},
+		{"internal/a/a.go", false, true},
+		{"go.mod", false, true},
+		{"cmd/tool", true, false},
+		{"cmd/tool/main.go", false, false},
+		{"internal/b", true, false},
+		{"internal/a/a_test.go", false, false},
+	} {
+		if got := rules.Included(tc.path, tc.isDir); got != tc.want {
+			t.Errorf("Included(%q, %v) = %v, want %v", tc.path, tc.isDir, got, tc.want)
+		}
+	}
+}
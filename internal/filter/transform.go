@@ -0,0 +1,51 @@
+// file: internal/filter/transform.go
+package filter
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Transforms lists the transforms an include rule can apply to the content of
+// the files it selects. A transform is written before the pattern, followed by
+// a colon: "goapi:/internal/**/*.go" or "goapi:gopkg:./cmd/app".
+var Transforms = []string{TransformGoAPI}
+
+// TransformGoAPI reduces Go files to their declarations, without bodies.
+const TransformGoAPI = "goapi"
+
+// parseTransform splits the transform a pattern names, if any, from the rest
+// of the pattern.
+func parseTransform(action Action, pattern string) (transform, rest string, err error) {
+	name, rest, ok := strings.Cut(pattern, ":")
+	if !ok || !slices.Contains(Transforms, name) {
+		return "", pattern, nil
+	}
+	if action != Include {
+		return "", "", fmt.Errorf("%s transform on an exclude rule; transforms apply to included files", name)
+	}
+	if rest == "" {
+		return "", "", fmt.Errorf("%s transform is missing a pattern", name)
+	}
+	return name, rest, nil
+}
+
+// Transform returns the transform of the rule that includes the file at the
+// slash-separated name, or "" if the file is copied as it is.
+func (rs Rules) Transform(name string) string {
+	if r := rs.Match(name, false); r != nil && r.Action == Include {
+		return r.Transform
+	}
+	return ""
+}
+
+// Transformed reports whether any rule applies a transform.
+func (rs Rules) Transformed() bool {
+	for i := range rs {
+		if rs[i].Transform != "" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,30 @@
+package filter
+
+import "testing"
+
+func TestTransform(t *testing.T) {
+	fsys := mockFS{
+		"/m/manifest.txt": "+ goapi:/internal/**\n+ goapi:gopkg:./cmd/app\n+ /internal/**\n- *\n",
+		"/m/exclude.txt":  "- goapi:*.go\n",
+		"/m/empty.txt":    "+ goapi:\n",
+	}
+	rules, err := Load("/m/manifest.txt", "/src", "", fsys)
+	if err != nil {
+		t.Fatalf("Load() returned an unexpected error: %v", err)
+	}
+	if rules[0].Transform != TransformGoAPI || rules[0].Pattern != "/internal/**" {
+		t.Errorf("rule = %q transform %q, want /internal/** with goapi", rules[0].Pattern, rules[0].Transform)
+	}
+	if rules[1].Set == nil || rules[1].Transform != TransformGoAPI {
+		t.Errorf("set rule = %+v, want a gopkg set with goapi", rules[1])
+	}
+	if !rules.Transformed() || rules.Transform("internal/a/a.go") != TransformGoAPI || rules.Transform("main.go") != "" {
+		t.Error("Transform() did not follow the deciding rule")
+	}
+
+	for _, name := range []string{"/m/exclude.txt", "/m/empty.txt"} {
+		if _, err := Load(name, "/src", "", fsys); err == nil {
+			t.Errorf("Load(%q) did not return an error", name)
+		}
+	}
+}
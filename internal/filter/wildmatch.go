@@ -0,0 +1,205 @@
+// file: internal/filter/wildmatch.go
+package filter
+
+import "strings"
+
+// Results of a single wildcard comparison. The two abort values let a caller
+// stop backtracking early once it is clear that no later starting position can
+// succeed, which keeps pathological patterns such as "*/*/*/*" from going
+// exponential.
+const (
+	wildNoMatch = iota
+	wildMatch
+	wildAbortAll
+	wildAbortToStarStar
+)
+
+// wildmatch reports whether text matches the rsync wildcard pattern p. A single
+// "*" and "?" never match a "/", while "**" matches across directory
+// separators.
+func wildmatch(p, text string) bool {
+	return doWild(p, text) == wildMatch
+}
+
+// doWild is a port of rsync's dowild() routine. It is kept deliberately close
+// to the original so that the two implementations can be compared line by line
+// when a behavioural difference is reported.
+func doWild(p, text string) int {
+	for ; len(p) > 0; p, text = p[1:], text[1:] {
+		if len(text) == 0 && p[0] != '*' {
+			return wildAbortAll
+		}
+		switch p[0] {
+		case '\\':
+			// A backslash matches the following pattern character literally.
+			p = p[1:]
+			if len(p) == 0 || text[0] != p[0] {
+				return wildNoMatch
+			}
+		case '?':
+			if text[0] == '/' {
+				return wildNoMatch
+			}
+		case '*':
+			special := len(p) > 1 && p[1] == '*'
+			for len(p) > 0 && p[0] == '*' {
+				p = p[1:]
+			}
+			if len(p) == 0 {
+				// A trailing "**" matches everything, while a trailing "*"
+				// only matches if no directory separators remain.
+				if !special && strings.Contains(text, "/") {
+					return wildNoMatch
+				}
+				return wildMatch
+			}
+			for ; len(text) > 0; text = text[1:] {
+				matched := doWild(p, text)
+				if matched != wildNoMatch {
+					if !special || matched != wildAbortToStarStar {
+						return matched
+					}
+				} else if !special && text[0] == '/' {
+					return wildAbortToStarStar
+				}
+			}
+			return wildAbortAll
+		case '[':
+			var ok bool
+			p, ok = matchClass(p, text[0])
+			if !ok {
+				return wildNoMatch
+			}
+		default:
+			if text[0] != p[0] {
+				return wildNoMatch
+			}
+		}
+	}
+	if len(text) == 0 {
+		return wildMatch
+	}
+	return wildNoMatch
+}
+
+// matchClass evaluates the bracket expression at the start of p against c. It
+// returns the pattern positioned on the closing "]" and whether c is a member
+// of the class. An unterminated class yields an empty pattern, which the caller
+// treats as a failed match.
+func matchClass(p string, c byte) (string, bool) {
+	i := 1
+	if i >= len(p) {
+		return "", false
+	}
+	negated := false
+	if p[i] == '!' || p[i] == '^' {
+		negated = true
+		i++
+	}
+	matched := false
+	var prev byte
+	for first := true; first || (i < len(p) && p[i] != ']'); first = false {
+		if i >= len(p) {
+			return "", false
+		}
+		ch := p[i]
+		switch {
+		case ch == '\\':
+			i++
+			if i >= len(p) {
+				return "", false
+			}
+			ch = p[i]
+			if c == ch {
+				matched = true
+			}
+		case ch == '-' && prev != 0 && i+1 < len(p) && p[i+1] != ']':
+			i++
+			hi := p[i]
+			if hi == '\\' {
+				i++
+				if i >= len(p) {
+					return "", false
+				}
+				hi = p[i]
+			}
+			if c >= prev && c <= hi {
+				matched = true
+			}
+			// Clearing ch prevents a range end from starting another range.
+			ch = 0
+		case ch == '[' && i+1 < len(p) && p[i+1] == ':':
+			end := strings.IndexByte(p[i+2:], ']')
+			if end < 0 {
+				return "", false
+			}
+			end += i + 2
+			if p[end-1] != ':' || end-1 < i+2 {
+				// Without a closing ":]" the bracket is an ordinary member.
+				if c == '[' {
+					matched = true
+				}
+				break
+			}
+			member, known := classMember(p[i+2:end-1], c)
+			if !known {
+				return "", false
+			}
+			if member {
+				matched = true
+			}
+			i = end
+			ch = 0
+		default:
+			if c == ch {
+				matched = true
+			}
+		}
+		prev = ch
+		i++
+	}
+	if i >= len(p) {
+		return "", false
+	}
+	if matched == negated || c == '/' {
+		return p[i:], false
+	}
+	return p[i:], true
+}
+
+// classMember reports whether c belongs to the named POSIX character class and
+// whether the class name is recognised at all.
+func classMember(name string, c byte) (member, known bool) {
+	isUpper := c >= 'A' && c <= 'Z'
+	isLower := c >= 'a' && c <= 'z'
+	isDigit := c >= '0' && c <= '9'
+	isAlpha := isUpper || isLower
+	isPrint := c >= 0x20 && c < 0x7f
+	switch name {
+	case "alnum":
+		return isAlpha || isDigit, true
+	case "alpha":
+		return isAlpha, true
+	case "blank":
+		return c == ' ' || c == '\t', true
+	case "cntrl":
+		return c < 0x20 || c == 0x7f, true
+	case "digit":
+		return isDigit, true
+	case "graph":
+		return isPrint && c != ' ', true
+	case "lower":
+		return isLower, true
+	case "print":
+		return isPrint, true
+	case "punct":
+		return isPrint && c != ' ' && !isAlpha && !isDigit, true
+	case "space":
+		return c == ' ' || (c >= '\t' && c <= '\r'), true
+	case "upper":
+		return isUpper, true
+	case "xdigit":
+		return isDigit || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F'), true
+	}
+	return false, false
+}
@@ -0,0 +1,48 @@
+package filter
+
+import "testing"
+
+func TestWildmatch(t *testing.T) {
+	testCases := []struct {
+		pattern string
+		text    string
+		want    bool
+	}{
+		{"foo", "foo", true},
+		{"foo", "bar", false},
+		{"*.go", "main.go", true},
+		{"*.go", "src/main.go", false},
+		{"**.go", "src/main.go", true},
+		{"src/*", "src/app", true},
+		{"src/*", "src/app/app.go", false},
+		{"src/**", "src/app/app.go", true},
+		{"**/*.md", "/README.md", true},
+		{"**/*.md", "docs/guide.md", true},
+		{"?ain.go", "main.go", true},
+		{"a?b", "a/b", false},
+		{"[cm]*", "main.go", true},
+		{"[!cm]*", "main.go", false},
+		{"[^cm]*", "readme", true},
+		{"[a-c]x", "bx", true},
+		{"[a-c]x", "dx", false},
+		{"[]]", "]", true},
+		{"[[:digit:]]*", "1.txt", true},
+		{"[[:upper:]]*", "README.md", true},
+		{"[[:upper:]]*", "main.go", false},
+		{"[[:bogus:]]", "a", false},
+		{"a[/]b", "a/b", false},
+		{"[abc", "a", false},
+		{`\*`, "*", true},
+		{`\*`, "a", false},
+		{"*/*/*", "a/b/c", true},
+		{"*/*/*", "a/b", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.pattern+"~"+tc.text, func(t *testing.T) {
+			if got := wildmatch(tc.pattern, tc.text); got != tc.want {
+				t.Errorf("wildmatch(%q, %q) = %v, want %v", tc.pattern, tc.text, got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,317 @@
+// file: internal/gitrepo/commit.go
+package gitrepo
+
+import (
+	"bytes"
+	"compress/zlib"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// CommitOptions describes a commit of a directory to a branch.
+type CommitOptions struct {
+	Branch  string // The branch to point at the commit, without refs/heads/.
+	Message string
+	// Orphan makes a commit without a parent, even if the branch exists,
+	// instead of adding to its history.
+	Orphan bool
+	// Ignore is a slash-separated path in the slice, such as its provenance
+	// record, whose changes alone do not make a new commit.
+	Ignore string
+}
+
+// Commit writes the files beneath dir into the object database of the
+// repository containing repoDir, as blobs and trees, commits the tree and
+// points the branch at the commit, which it returns. Neither the working tree
+// nor the index of the repository is touched, so the branch must not be
+// checked out in any of its worktrees; in a bare repository, the branch HEAD
+// names is not checked out and can be committed to. Empty directories and
+// anything named .git are left out, as git itself would leave them out.
+//
+// If the branch already holds the same files, apart from opts.Ignore, nothing
+// is committed: Commit returns the branch's tip and reports that it did not
+// change.
+func Commit(repoDir, dir string, opts CommitOptions, run Runner) (string, bool, error) {
+	ref := "refs/heads/" + opts.Branch
+	if opts.Branch == "" || strings.HasPrefix(opts.Branch, "-") {
+		return "", false, fmt.Errorf("invalid branch name %q", opts.Branch)
+	}
+	if _, err := run.Output(repoDir, "git", "check-ref-format", ref); err != nil {
+		return "", false, fmt.Errorf("invalid branch name %q", opts.Branch)
+	}
+	worktree, err := checkedOut(repoDir, ref, run)
+	if err != nil {
+		return "", false, err
+	}
+	if worktree != "" {
+		return "", false, fmt.Errorf("branch %s is checked out in %s; committing to it would leave the working tree out of date", opts.Branch, worktree)
+	}
+
+	store, err := openObjects(repoDir, run)
+	if err != nil {
+		return "", false, err
+	}
+	tree, err := store.writeTree(dir)
+	if err != nil {
+		return "", false, err
+	}
+	parent, err := branchTip(repoDir, ref, run)
+	if err != nil {
+		return "", false, err
+	}
+	if parent != "" && sameTree(repoDir, parent, tree, opts.Ignore, run) {
+		return parent, false, nil
+	}
+
+	args := []string{"commit-tree", tree, "-m", opts.Message}
+	if parent != "" && !opts.Orphan {
+		args = append(args, "-p", parent)
+	}
+	out, err := run.Output(repoDir, "git", args...)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to create commit: %w", err)
+	}
+	commit := strings.TrimSpace(string(out))
+
+	// The update only succeeds if the branch is still where it was found, so
+	// a concurrent update is never overwritten.
+	old := parent
+	if old == "" {
+		old = strings.Repeat("0", len(commit))
+	}
+	if _, err := run.Output(repoDir, "git", "update-ref", "-m", "repo-slice: commit", ref, commit, old); err != nil {
+		return "", false, fmt.Errorf("failed to update branch %s: %w", opts.Branch, err)
+	}
+	return commit, true, nil
+}
+
+// checkedOut returns the worktree of the repository containing repoDir that
+// has ref checked out, or "" if none does. A bare repository has no worktree
+// of its own, so its HEAD is not reported.
+func checkedOut(repoDir, ref string, run Runner) (string, error) {
+	out, err := run.Output(repoDir, "git", "worktree", "list", "--porcelain")
+	if err != nil {
+		return "", fmt.Errorf("failed to list worktrees: %w", err)
+	}
+	var worktree string
+	for _, line := range strings.Split(string(out), "\n") {
+		switch {
+		case strings.HasPrefix(line, "worktree "):
+			worktree = strings.TrimPrefix(line, "worktree ")
+		case line == "branch "+ref:
+			return worktree, nil
+		}
+	}
+	return "", nil
+}
+
+// branchTip returns the commit that ref points at, or "" if it does not exist.
+func branchTip(repoDir, ref string, run Runner) (string, error) {
+	if _, err := run.Output(repoDir, "git", "show-ref", "--verify", "--quiet", ref); err != nil {
+		return "", nil
+	}
+	return ResolveCommit(repoDir, ref, run)
+}
+
+// sameTree reports whether the tree of commit holds the same files as tree,
+// apart from the slash-separated path ignore, if it is not empty.
+func sameTree(repoDir, commit, tree, ignore string, run Runner) bool {
+	out, err := run.Output(repoDir, "git", "rev-parse", commit+"^{tree}")
+	if err != nil {
+		return false
+	}
+	if strings.TrimSpace(string(out)) == tree {
+		return true
+	}
+	if ignore == "" {
+		return false
+	}
+	// diff-tree fails both when the trees differ and when it cannot compare
+	// them, and either way the slice is committed.
+	_, err = run.Output(repoDir, "git", "diff-tree", "--quiet", "-r", commit, tree, "--", ":(exclude,top,literal)"+ignore)
+	return err == nil
+}
+
+// objectStore writes loose objects into a repository's object database.
+type objectStore struct {
+	dir     string // The objects directory.
+	newHash func() hash.Hash
+}
+
+// openObjects locates the object database of the repository containing
+// repoDir, and the hash function that names its objects.
+func openObjects(repoDir string, run Runner) (*objectStore, error) {
+	out, err := run.Output(repoDir, "git", "rev-parse", "--git-path", "objects")
+	if err != nil {
+		return nil, fmt.Errorf("%s is not in a git repository: %w", repoDir, err)
+	}
+	dir := strings.TrimSpace(string(out))
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(repoDir, dir)
+	}
+	store := &objectStore{dir: dir, newHash: sha1.New}
+	// Older versions of git only support SHA-1, and cannot report it.
+	if out, err := run.Output(repoDir, "git", "rev-parse", "--show-object-format"); err == nil {
+		switch format := strings.TrimSpace(string(out)); format {
+		case "sha1":
+		case "sha256":
+			store.newHash = sha256.New
+		default:
+			return nil, fmt.Errorf("unsupported object format %q", format)
+		}
+	}
+	return store, nil
+}
+
+// write stores an object of the given kind, unless the database already holds
+// it, and returns its hash.
+func (s *objectStore) write(kind string, data []byte) (string, error) {
+	h := s.newHash()
+	fmt.Fprintf(h, "%s %d\x00", kind, len(data))
+	h.Write(data)
+	id := hex.EncodeToString(h.Sum(nil))
+
+	name := filepath.Join(s.dir, id[:2], id[2:])
+	if _, err := os.Stat(name); err == nil {
+		return id, nil
+	}
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	fmt.Fprintf(zw, "%s %d\x00", kind, len(data))
+	zw.Write(data)
+	if err := zw.Close(); err != nil {
+		return "", err
+	}
+
+	// Objects are written to a temporary file and renamed into place, so a
+	// reader never sees a partial object.
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return "", fmt.Errorf("failed to write object: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(name), "tmp_obj_")
+	if err != nil {
+		return "", fmt.Errorf("failed to write object: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(buf.Bytes()); err != nil {
+		tmp.Close()
+		return "", fmt.Errorf("failed to write object: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return "", fmt.Errorf("failed to write object: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0444); err != nil {
+		return "", fmt.Errorf("failed to write object: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), name); err != nil && !errors.Is(err, fs.ErrExist) {
+		return "", fmt.Errorf("failed to write object: %w", err)
+	}
+	return id, nil
+}
+
+// gitEntry is an entry of a tree object.
+type gitEntry struct {
+	mode string
+	name string
+	id   string
+}
+
+// writeTree stores the files beneath dir and the trees holding them, and
+// returns the hash of the top tree, which is the empty tree if there are no
+// files.
+func (s *objectStore) writeTree(dir string) (string, error) {
+	id, empty, err := s.writeDir(dir)
+	if err == nil && empty {
+		return s.write("tree", nil)
+	}
+	return id, err
+}
+
+// writeDir stores the tree of dir, reporting whether it is empty.
+func (s *objectStore) writeDir(dir string) (string, bool, error) {
+	items, err := os.ReadDir(dir)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to read slice: %w", err)
+	}
+	var entries []gitEntry
+	for _, item := range items {
+		if item.Name() == ".git" {
+			continue
+		}
+		name := filepath.Join(dir, item.Name())
+		var e gitEntry
+		switch mode := item.Type(); {
+		case mode.IsDir():
+			id, empty, err := s.writeDir(name)
+			if err != nil {
+				return "", false, err
+			}
+			if empty {
+				continue
+			}
+			e = gitEntry{"40000", item.Name(), id}
+		case mode&fs.ModeSymlink != 0:
+			target, err := os.Readlink(name)
+			if err != nil {
+				return "", false, fmt.Errorf("failed to read slice: %w", err)
+			}
+			id, err := s.write("blob", []byte(filepath.ToSlash(target)))
+			if err != nil {
+				return "", false, err
+			}
+			e = gitEntry{"120000", item.Name(), id}
+		case mode.IsRegular():
+			info, err := item.Info()
+			if err != nil {
+				return "", false, fmt.Errorf("failed to read slice: %w", err)
+			}
+			data, err := os.ReadFile(name)
+			if err != nil {
+				return "", false, fmt.Errorf("failed to read slice: %w", err)
+			}
+			id, err := s.write("blob", data)
+			if err != nil {
+				return "", false, err
+			}
+			e = gitEntry{"100644", item.Name(), id}
+			if info.Mode()&0111 != 0 {
+				e.mode = "100755"
+			}
+		default:
+			continue // Devices, sockets and pipes cannot be committed.
+		}
+		entries = append(entries, e)
+	}
+	if len(entries) == 0 {
+		return "", true, nil
+	}
+
+	// Git sorts a tree's entries as if each directory's name ended in a slash.
+	sortKey := func(e gitEntry) string {
+		if e.mode == "40000" {
+			return e.name + "/"
+		}
+		return e.name
+	}
+	sort.Slice(entries, func(i, j int) bool { return sortKey(entries[i]) < sortKey(entries[j]) })
+	var buf bytes.Buffer
+	for _, e := range entries {
+		raw, err := hex.DecodeString(e.id)
+		if err != nil {
+			return "", false, err
+		}
+		fmt.Fprintf(&buf, "%s %s\x00", e.mode, e.name)
+		buf.Write(raw)
+	}
+	id, err := s.write("tree", buf.Bytes())
+	return id, false, err
+}
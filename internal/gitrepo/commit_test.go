@@ -0,0 +1,204 @@
+package gitrepo
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// gitRunner runs git for real, with a fixed identity and no user
+// configuration.
+type gitRunner struct{}
+
+func (gitRunner) Output(workDir, command string, args ...string) ([]byte, error) {
+	cmd := exec.Command(command, args...)
+	cmd.Dir = workDir
+	cmd.Env = append(os.Environ(),
+		"GIT_CONFIG_GLOBAL=/dev/null", "GIT_CONFIG_NOSYSTEM=1",
+		"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+		"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com",
+	)
+	return cmd.Output()
+}
+
+// git runs a git command in dir and returns its trimmed output.
+func git(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	out, err := gitRunner{}.Output(dir, "git", args...)
+	if err != nil {
+		t.Fatalf("git %s: %v", strings.Join(args, " "), err)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// writeSlice creates a slice with a file, an executable, a symlink, an empty
+// directory and a .git directory.
+func writeSlice(t *testing.T, readme string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, mode := range map[string]os.FileMode{"README.md": 0644, "bin/run.sh": 0755, ".git/HEAD": 0644} {
+		file := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(readme), mode); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink("../README.md", filepath.Join(dir, "bin", "README.md")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(dir, "empty"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestCommit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	repo := filepath.Join(t.TempDir(), "repo.git")
+	git(t, filepath.Dir(repo), "init", "--quiet", "--bare", repo)
+	opts := CommitOptions{Branch: "context/dev", Message: "Update slice"}
+
+	first, changed, err := Commit(repo, writeSlice(t, "one"), opts, gitRunner{})
+	if err != nil || !changed {
+		t.Fatalf("Commit() = %v, %v; want a new commit", changed, err)
+	}
+	if got := git(t, repo, "rev-parse", "refs/heads/context/dev"); got != first {
+		t.Errorf("branch points at %s, want %s", got, first)
+	}
+	wantTree := strings.Join([]string{
+		"100644 blob 43dd47ea691c90a5fa7827892c70241913351963\tREADME.md",
+		"120000 blob 32d46ee883b58d6a383eed06eb98f33aa6530ded\tbin/README.md",
+		"100755 blob 43dd47ea691c90a5fa7827892c70241913351963\tbin/run.sh",
+	}, "\n")
+	if got := git(t, repo, "ls-tree", "-r", first); got != wantTree {
+		t.Errorf("commit holds:\n%s\nwant:\n%s", got, wantTree)
+	}
+	if got := git(t, repo, "log", "--format=%s/%P", first); got != "Update slice/" {
+		t.Errorf("commit log = %q, want a single commit without a parent", got)
+	}
+	git(t, repo, "fsck", "--strict", "--no-dangling")
+
+	second, changed, err := Commit(repo, writeSlice(t, "two"), opts, gitRunner{})
+	if err != nil || !changed {
+		t.Fatalf("Commit() = %v, %v; want a new commit", changed, err)
+	}
+	if got := git(t, repo, "rev-parse", second+"^"); got != first {
+		t.Errorf("second commit has parent %s, want %s", got, first)
+	}
+
+	opts.Orphan = true
+	orphan, changed, err := Commit(repo, writeSlice(t, "three"), opts, gitRunner{})
+	if err != nil || !changed {
+		t.Fatalf("Commit() = %v, %v; want a new commit", changed, err)
+	}
+	if got := git(t, repo, "log", "--format=%P", orphan); got != "" {
+		t.Errorf("orphan commit has parents %q", got)
+	}
+	git(t, repo, "fsck", "--strict")
+
+	if _, _, err := Commit(repo, writeSlice(t, "one"), CommitOptions{Branch: "bad..name"}, gitRunner{}); err == nil {
+		t.Error("Commit() accepted an invalid branch name")
+	}
+}
+
+func TestCommitCheckedOutBranch(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	repo := filepath.Join(t.TempDir(), "repo")
+	git(t, filepath.Dir(repo), "init", "--quiet", "--initial-branch=main", repo)
+	git(t, repo, "commit", "--quiet", "--allow-empty", "-m", "Initial commit")
+	linked := filepath.Join(filepath.Dir(repo), "linked")
+	git(t, repo, "worktree", "add", "--quiet", "-b", "feature", linked)
+
+	for _, branch := range []string{"main", "feature"} {
+		if _, _, err := Commit(repo, writeSlice(t, "one"), CommitOptions{Branch: branch}, gitRunner{}); err == nil || !strings.Contains(err.Error(), "is checked out") {
+			t.Errorf("Commit() to %s error = %v, want the checked out branch to be refused", branch, err)
+		}
+	}
+	if _, _, err := Commit(repo, writeSlice(t, "one"), CommitOptions{Branch: "context"}, gitRunner{}); err != nil {
+		t.Errorf("Commit() returned an unexpected error: %v", err)
+	}
+	for _, dir := range []string{repo, linked} {
+		if status := git(t, dir, "status", "--porcelain"); status != "" {
+			t.Errorf("Commit() changed the working tree of %s:\n%s", dir, status)
+		}
+	}
+}
+
+func TestCommitBareHead(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	repo := filepath.Join(t.TempDir(), "repo.git")
+	git(t, filepath.Dir(repo), "init", "--quiet", "--bare", "--initial-branch=main", repo)
+
+	commit, _, err := Commit(repo, writeSlice(t, "one"), CommitOptions{Branch: "main"}, gitRunner{})
+	if err != nil {
+		t.Fatalf("Commit() to the HEAD of a bare repository returned an error: %v", err)
+	}
+	if got := git(t, repo, "rev-parse", "HEAD"); got != commit {
+		t.Errorf("HEAD = %s, want %s", got, commit)
+	}
+}
+
+func TestCommitUnchanged(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	repo := filepath.Join(t.TempDir(), "repo.git")
+	git(t, filepath.Dir(repo), "init", "--quiet", "--bare", repo)
+	// withRecord adds a record to a slice that differs on every call.
+	records := 0
+	withRecord := func(dir string) string {
+		records++
+		file := filepath.Join(dir, ".repo-slice", "provenance.json")
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(strconv.Itoa(records)), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return dir
+	}
+
+	testCases := []struct {
+		name        string
+		dir         string
+		opts        CommitOptions
+		wantChanged bool
+	}{
+		{"New branch", writeSlice(t, "one"), CommitOptions{Branch: "ai", Orphan: true}, true},
+		{"Same files", writeSlice(t, "one"), CommitOptions{Branch: "ai", Orphan: true}, false},
+		{"Changed file", writeSlice(t, "two"), CommitOptions{Branch: "ai", Orphan: true}, true},
+		{"Added record", withRecord(writeSlice(t, "two")), CommitOptions{Branch: "ai"}, true},
+		{"Changed record", withRecord(writeSlice(t, "two")), CommitOptions{Branch: "ai"}, true},
+		{"Ignored record", withRecord(writeSlice(t, "two")), CommitOptions{Branch: "ai", Ignore: ".repo-slice/provenance.json"}, false},
+		{"Ignored record and changed file", withRecord(writeSlice(t, "three")), CommitOptions{Branch: "ai", Ignore: ".repo-slice/provenance.json"}, true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tip, _ := branchTip(repo, "refs/heads/ai", gitRunner{})
+			commit, changed, err := Commit(repo, tc.dir, tc.opts, gitRunner{})
+			if err != nil {
+				t.Fatalf("Commit() returned an unexpected error: %v", err)
+			}
+			if changed != tc.wantChanged {
+				t.Errorf("Commit() changed = %v, want %v", changed, tc.wantChanged)
+			}
+			if !changed && commit != tip {
+				t.Errorf("Commit() = %s, want the unchanged tip %s", commit, tip)
+			}
+			if got := git(t, repo, "rev-parse", "refs/heads/ai"); got != commit {
+				t.Errorf("branch points at %s, want %s", got, commit)
+			}
+		})
+	}
+}
@@ -0,0 +1,289 @@
+// file: internal/gitrepo/tree.go
+
+// Package gitrepo reads from and writes to a git repository's object database
+// by running git commands, so that slices can be taken from, and published to,
+// commits that are not checked out.
+package gitrepo
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Runner runs a command from a working directory and returns its standard
+// output. The slicer's executor satisfies it.
+type Runner interface {
+	Output(workDir, command string, args ...string) ([]byte, error)
+}
+
+// ResolveCommit returns the full hash of the commit that ref names.
+func ResolveCommit(dir, ref string, run Runner) (string, error) {
+	if ref == "" || strings.HasPrefix(ref, "-") {
+		return "", fmt.Errorf("invalid git ref %q", ref)
+	}
+	out, err := run.Output(dir, "git", "rev-parse", "--verify", "--quiet", ref+"^{commit}")
+	if err != nil {
+		return "", fmt.Errorf("git ref %q does not name a commit: %w", ref, err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// CommitTime returns the committer time of commit, which, unlike the time a
+// slice is made, is the same every time the commit is sliced.
+func CommitTime(dir, commit string, run Runner) (time.Time, error) {
+	out, err := run.Output(dir, "git", "show", "-s", "--format=%ct", commit)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to read commit %s: %w", commit, err)
+	}
+	seconds, err := strconv.ParseInt(strings.TrimSpace(string(out)), 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to read commit time of %s: %w", commit, err)
+	}
+	return time.Unix(seconds, 0).UTC(), nil
+}
+
+// Prefix returns the slash-separated location of dir within its repository's
+// working tree, or "." at the top level.
+func Prefix(dir string, run Runner) (string, error) {
+	out, err := run.Output(dir, "git", "rev-parse", "--show-prefix")
+	if err != nil {
+		return "", fmt.Errorf("failed to locate %s in its repository: %w", dir, err)
+	}
+	prefix := strings.TrimSuffix(strings.TrimSpace(string(out)), "/")
+	if prefix == "" {
+		return ".", nil
+	}
+	return prefix, nil
+}
+
+// Dirty reports whether the working tree at or beneath dir differs from the
+// commit checked out, counting staged, unstaged and untracked changes but not
+// ignored files.
+func Dirty(dir string, run Runner) (bool, error) {
+	out, err := run.Output(dir, "git", "status", "--porcelain", "--untracked-files=normal", "--", ".")
+	if err != nil {
+		return false, fmt.Errorf("failed to read the status of %s: %w", dir, err)
+	}
+	return len(bytes.TrimSpace(out)) > 0, nil
+}
+
+// treeEntry is a file, symlink or directory in a commit's tree.
+type treeEntry struct {
+	name   string // Base name.
+	mode   fs.FileMode
+	object string
+	size   int64
+}
+
+// TreeFS is a read-only fs.FS over the tree of a commit. Listings come from a
+// single "git ls-tree" call; file contents are read only when a file is
+// opened. Every entry has the commit's timestamp, so slices of the same commit
+// are identical. Submodules are omitted, as they have no content in the tree.
+type TreeFS struct {
+	dir      string
+	run      Runner
+	root     string // The directory of the tree this view is rooted at.
+	entries  map[string]*treeEntry
+	children map[string][]string
+	modTime  time.Time
+}
+
+// OpenTree lists the whole tree of the commit named by ref in the repository
+// containing dir.
+func OpenTree(dir, ref string, run Runner) (*TreeFS, error) {
+	commit, err := ResolveCommit(dir, ref, run)
+	if err != nil {
+		return nil, err
+	}
+	modTime, err := CommitTime(dir, commit, run)
+	if err != nil {
+		return nil, err
+	}
+	listing, err := run.Output(dir, "git", "ls-tree", "-r", "-t", "-z", "--long", "--full-tree", commit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tree of %s: %w", commit, err)
+	}
+
+	t := &TreeFS{
+		dir:      dir,
+		run:      run,
+		root:     ".",
+		entries:  map[string]*treeEntry{".": {name: ".", mode: fs.ModeDir | 0755}},
+		children: make(map[string][]string),
+		modTime:  modTime,
+	}
+	for _, record := range bytes.Split(listing, []byte{0}) {
+		if len(record) == 0 {
+			continue
+		}
+		if err := t.add(string(record)); err != nil {
+			return nil, err
+		}
+	}
+	for _, names := range t.children {
+		sort.Strings(names)
+	}
+	return t, nil
+}
+
+// add records one line of "git ls-tree --long" output:
+// "<mode> <type> <object> <size>\t<path>".
+func (t *TreeFS) add(record string) error {
+	meta, name, ok := strings.Cut(record, "\t")
+	fields := strings.Fields(meta)
+	if !ok || len(fields) != 4 {
+		return fmt.Errorf("unexpected git ls-tree output: %q", record)
+	}
+	e := &treeEntry{name: path.Base(name), object: fields[2]}
+	switch fields[0] {
+	case "040000":
+		e.mode = fs.ModeDir | 0755
+	case "100644":
+		e.mode = 0644
+	case "100755":
+		e.mode = 0755
+	case "120000":
+		e.mode = fs.ModeSymlink | 0777
+	default:
+		return nil // Submodules.
+	}
+	if !e.mode.IsDir() {
+		size, err := strconv.ParseInt(fields[3], 10, 64)
+		if err != nil {
+			return fmt.Errorf("unexpected git ls-tree output: %q", record)
+		}
+		e.size = size
+	}
+	t.entries[name] = e
+	parent := path.Dir(name)
+	t.children[parent] = append(t.children[parent], name)
+	return nil
+}
+
+// Sub returns a view of the tree rooted at dir.
+func (t *TreeFS) Sub(dir string) (fs.FS, error) {
+	if !fs.ValidPath(dir) {
+		return nil, &fs.PathError{Op: "sub", Path: dir, Err: fs.ErrInvalid}
+	}
+	full := path.Join(t.root, dir)
+	if e, ok := t.entries[full]; !ok || !e.mode.IsDir() {
+		return nil, &fs.PathError{Op: "sub", Path: dir, Err: fs.ErrNotExist}
+	}
+	sub := *t
+	sub.root = full
+	return &sub, nil
+}
+
+func (t *TreeFS) lookup(op, name string) (string, *treeEntry, error) {
+	if !fs.ValidPath(name) {
+		return "", nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	full := path.Join(t.root, name)
+	e, ok := t.entries[full]
+	if !ok {
+		return "", nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+	return full, e, nil
+}
+
+// Open opens the named file or directory. Symbolic links are not followed.
+func (t *TreeFS) Open(name string) (fs.File, error) {
+	full, e, err := t.lookup("open", name)
+	if err != nil {
+		return nil, err
+	}
+	info := t.info(e)
+	if e.mode.IsDir() {
+		return &treeDir{fsys: t, dir: full, info: info}, nil
+	}
+	data, err := t.read(e)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	return &treeFile{Reader: bytes.NewReader(data), info: info}, nil
+}
+
+// ReadLink returns the target of the named symbolic link.
+func (t *TreeFS) ReadLink(name string) (string, error) {
+	_, e, err := t.lookup("readlink", name)
+	if err != nil {
+		return "", err
+	}
+	if e.mode&fs.ModeSymlink == 0 {
+		return "", &fs.PathError{Op: "readlink", Path: name, Err: fs.ErrInvalid}
+	}
+	data, err := t.read(e)
+	if err != nil {
+		return "", &fs.PathError{Op: "readlink", Path: name, Err: err}
+	}
+	return string(data), nil
+}
+
+func (t *TreeFS) read(e *treeEntry) ([]byte, error) {
+	return t.run.Output(t.dir, "git", "cat-file", "blob", e.object)
+}
+
+func (t *TreeFS) info(e *treeEntry) fs.FileInfo {
+	return treeInfo{entry: e, modTime: t.modTime}
+}
+
+// treeInfo implements fs.FileInfo for a tree entry.
+type treeInfo struct {
+	entry   *treeEntry
+	modTime time.Time
+}
+
+func (i treeInfo) Name() string       { return i.entry.name }
+func (i treeInfo) Size() int64        { return i.entry.size }
+func (i treeInfo) Mode() fs.FileMode  { return i.entry.mode }
+func (i treeInfo) ModTime() time.Time { return i.modTime }
+func (i treeInfo) IsDir() bool        { return i.entry.mode.IsDir() }
+func (i treeInfo) Sys() any           { return nil }
+
+// treeFile is an open blob.
+type treeFile struct {
+	*bytes.Reader
+	info fs.FileInfo
+}
+
+func (f *treeFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+func (f *treeFile) Close() error               { return nil }
+
+// treeDir is an open directory.
+type treeDir struct {
+	fsys   *TreeFS
+	dir    string
+	info   fs.FileInfo
+	offset int
+}
+
+func (d *treeDir) Stat() (fs.FileInfo, error) { return d.info, nil }
+func (d *treeDir) Close() error               { return nil }
+
+func (d *treeDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.dir, Err: fs.ErrInvalid}
+}
+
+// ReadDir returns the directory's entries in lexical order.
+func (d *treeDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	names := d.fsys.children[d.dir][d.offset:]
+	if n > 0 && len(names) > n {
+		names = names[:n]
+	}
+	if n > 0 && len(names) == 0 {
+		return nil, io.EOF
+	}
+	entries := make([]fs.DirEntry, len(names))
+	for i, name := range names {
+		entries[i] = fs.FileInfoToDirEntry(d.fsys.info(d.fsys.entries[name]))
+	}
+	d.offset += len(names)
+	return entries, nil
+}
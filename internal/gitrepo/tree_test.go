@@ -0,0 +1,175 @@
+// file: internal/gitrepo/tree_test.go
+package gitrepo
+
+import (
+	"errors"
+	"io/fs"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeRunner returns canned output for each command line.
+type fakeRunner map[string]string
+
+func (f fakeRunner) Output(workDir, command string, args ...string) ([]byte, error) {
+	line := strings.Join(append([]string{command}, args...), " ")
+	out, ok := f[line]
+	if !ok {
+		return nil, errors.New("unexpected command: " + line)
+	}
+	return []byte(out), nil
+}
+
+const testCommit = "0123456789abcdef0123456789abcdef01234567"
+
+// testRepo is a repository whose commit holds a README, an executable script,
+// a symlink, a nested file and a submodule.
+var testRepo = fakeRunner{
+	"git rev-parse --verify --quiet v1^{commit}": testCommit + "\n",
+	"git show -s --format=%ct " + testCommit:     "1700000000\n",
+	"git ls-tree -r -t -z --long --full-tree " + testCommit: strings.Join([]string{
+		"100644 blob aaaa      6\tREADME.md",
+		"040000 tree bbbb      -\tsrc",
+		"100755 blob cccc     10\tsrc/build.sh",
+		"120000 blob dddd      9\tsrc/link",
+		"040000 tree eeee      -\tsrc/pkg",
+		"100644 blob ffff      4\tsrc/pkg/a.go",
+		"160000 commit 1111    -\tvendor/lib",
+	}, "\x00") + "\x00",
+	"git cat-file blob aaaa": "readme",
+	"git cat-file blob dddd": "build.sh",
+	"git cat-file blob ffff": "pkg\n",
+}
+
+func TestOpenTree(t *testing.T) {
+	tree, err := OpenTree("/repo", "v1", testRepo)
+	if err != nil {
+		t.Fatalf("OpenTree() returned an unexpected error: %v", err)
+	}
+
+	var got []string
+	err = fs.WalkDir(tree, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if !info.ModTime().Equal(time.Unix(1700000000, 0)) {
+			t.Errorf("%s has time %v, want the commit time", path, info.ModTime())
+		}
+		got = append(got, path+" "+info.Mode().String())
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WalkDir() returned an unexpected error: %v", err)
+	}
+	want := []string{
+		". drwxr-xr-x",
+		"README.md -rw-r--r--",
+		"src drwxr-xr-x",
+		"src/build.sh -rwxr-xr-x",
+		"src/link Lrwxrwxrwx",
+		"src/pkg drwxr-xr-x",
+		"src/pkg/a.go -rw-r--r--",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("WalkDir() visited %v, want %v", got, want)
+	}
+
+	if data, err := fs.ReadFile(tree, "README.md"); err != nil || string(data) != "readme" {
+		t.Errorf("ReadFile(README.md) = %q, %v; want %q", data, err, "readme")
+	}
+	if target, err := tree.ReadLink("src/link"); err != nil || target != "build.sh" {
+		t.Errorf("ReadLink(src/link) = %q, %v; want %q", target, err, "build.sh")
+	}
+	if _, err := tree.ReadLink("README.md"); err == nil {
+		t.Error("ReadLink() did not reject a regular file")
+	}
+	if _, err := tree.Open("missing"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Open(missing) error = %v, want fs.ErrNotExist", err)
+	}
+}
+
+func TestTreeFSSub(t *testing.T) {
+	tree, err := OpenTree("/repo", "v1", testRepo)
+	if err != nil {
+		t.Fatalf("OpenTree() returned an unexpected error: %v", err)
+	}
+
+	sub, err := tree.Sub("src")
+	if err != nil {
+		t.Fatalf("Sub(src) returned an unexpected error: %v", err)
+	}
+	if data, err := fs.ReadFile(sub, "pkg/a.go"); err != nil || string(data) != "pkg\n" {
+		t.Errorf("ReadFile(pkg/a.go) = %q, %v; want %q", data, err, "pkg\n")
+	}
+	entries, err := fs.ReadDir(sub, ".")
+	if err != nil {
+		t.Fatalf("ReadDir() returned an unexpected error: %v", err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	if want := []string{"build.sh", "link", "pkg"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("ReadDir() = %v, want %v", names, want)
+	}
+	if _, ok := sub.(interface{ ReadLink(string) (string, error) }); !ok {
+		t.Error("Sub() returned a view that cannot read symbolic links")
+	}
+
+	for _, dir := range []string{"README.md", "missing", "../src"} {
+		if _, err := tree.Sub(dir); err == nil {
+			t.Errorf("Sub(%q) did not return an error", dir)
+		}
+	}
+}
+
+func TestResolveCommit(t *testing.T) {
+	if got, err := ResolveCommit("/repo", "v1", testRepo); err != nil || got != testCommit {
+		t.Errorf("ResolveCommit(v1) = %q, %v; want %q", got, err, testCommit)
+	}
+	for _, ref := range []string{"", "--all", "missing"} {
+		if _, err := ResolveCommit("/repo", ref, testRepo); err == nil {
+			t.Errorf("ResolveCommit(%q) did not return an error", ref)
+		}
+	}
+}
+
+func TestCommitTime(t *testing.T) {
+	if got, err := CommitTime("/repo", testCommit, testRepo); err != nil || !got.Equal(time.Unix(1700000000, 0)) {
+		t.Errorf("CommitTime() = %v, %v; want the committer time", got, err)
+	}
+	if _, err := CommitTime("/repo", "missing", testRepo); err == nil {
+		t.Error("CommitTime() did not report an unknown commit")
+	}
+}
+
+func TestPrefix(t *testing.T) {
+	testCases := []struct{ out, want string }{
+		{"\n", "."},
+		{"cmd/repo-slice/\n", "cmd/repo-slice"},
+	}
+	for _, tc := range testCases {
+		run := fakeRunner{"git rev-parse --show-prefix": tc.out}
+		if got, err := Prefix("/repo", run); err != nil || got != tc.want {
+			t.Errorf("Prefix() with output %q = %q, %v; want %q", tc.out, got, err, tc.want)
+		}
+	}
+}
+
+func TestDirty(t *testing.T) {
+	const status = "git status --porcelain --untracked-files=normal -- ."
+	for out, want := range map[string]bool{"": false, "\n": false, " M main.go\n?? new.txt\n": true} {
+		if got, err := Dirty("/repo", fakeRunner{status: out}); err != nil || got != want {
+			t.Errorf("Dirty() with status %q = %v, %v; want %v", out, got, err, want)
+		}
+	}
+	if _, err := Dirty("/repo", fakeRunner{}); err == nil {
+		t.Error("Dirty() did not return an error when git failed")
+	}
+}
@@ -0,0 +1,248 @@
+// file: internal/lint/lint.go
+
+// Package lint inspects manifests for the rsync filter-rule pitfalls that make
+// slices silently differ from what their authors intended.
+package lint
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+
+	"github.com/AlienHeadwars/repo-slice/internal/deps"
+	"github.com/AlienHeadwars/repo-slice/internal/filter"
+)
+
+// Names of the checks, reported with each diagnostic so that output can be
+// filtered or suppressed by tooling.
+const (
+	CheckSyntax           = "syntax"
+	CheckUnsupported      = "unsupported-modifier"
+	CheckTrailingSpace    = "trailing-whitespace"
+	CheckUnreachable      = "unreachable-rule"
+	CheckShadowed         = "shadowed-rule"
+	CheckNoMatch          = "no-match"
+	CheckMissingTraversal = "missing-parent-traversal"
+	CheckMissingCatchAll  = "missing-catch-all"
+)
+
+// Diagnostic is a single warning about a manifest line.
+type Diagnostic struct {
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Check   string `json:"check"`
+	Message string `json:"message"`
+}
+
+// String formats the diagnostic in the conventional "file:line: message" form.
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%s:%d: %s [%s]", d.File, d.Line, d.Message, d.Check)
+}
+
+// Manifest loads the manifest at path and checks it against the source tree in
+// src. Relative merge files are resolved against baseDir, as they are when
+// slicing. The diagnostics are sorted by file and line. Only rsync manifests
+// can be linted; the checks rely on first-match-wins ordering.
+func Manifest(path, baseDir string, format filter.Format, fsys filter.FileSystem, src fs.FS) ([]Diagnostic, error) {
+	format, err := filter.DetectFormat(path, format, fsys)
+	if err != nil {
+		return nil, err
+	}
+	if format != filter.FormatRsync {
+		return nil, fmt.Errorf("lint supports rsync manifests only, not %s", format)
+	}
+	rules, problems, err := filter.LoadAll(path, baseDir, format, fsys)
+	if err != nil {
+		return nil, err
+	}
+
+	var diags []Diagnostic
+	for _, p := range problems {
+		check := CheckSyntax
+		if errors.Is(p.Err, filter.ErrUnsupported) {
+			check = CheckUnsupported
+		}
+		diags = append(diags, Diagnostic{File: p.File, Line: p.Line, Check: check, Message: p.Err.Error()})
+	}
+
+	if src != nil {
+		if err := deps.Resolve(rules, src); err != nil {
+			return nil, err
+		}
+	}
+	ruleDiags, err := Rules(rules, src)
+	if err != nil {
+		return nil, err
+	}
+	diags = append(diags, ruleDiags...)
+
+	sort.SliceStable(diags, func(i, j int) bool {
+		if diags[i].File != diags[j].File {
+			return diags[i].File < diags[j].File
+		}
+		return diags[i].Line < diags[j].Line
+	})
+	return diags, nil
+}
+
+// Rules checks an already loaded rule list. Checks that depend on the files
+// being sliced are skipped when src is nil.
+func Rules(rules filter.Rules, src fs.FS) ([]Diagnostic, error) {
+	var diags []Diagnostic
+	warn := func(r *filter.Rule, check, format string, args ...any) {
+		diags = append(diags, Diagnostic{File: r.File, Line: r.Line, Check: check, Message: fmt.Sprintf(format, args...)})
+	}
+
+	unreachable := make([]bool, len(rules))
+	for i := range rules {
+		r := &rules[i]
+		if strings.TrimRight(r.Pattern, " \t") != r.Pattern {
+			warn(r, CheckTrailingSpace, "pattern %q ends with whitespace, which rsync treats as part of the name", r.Pattern)
+		}
+		if j := coveredBy(rules, i); j >= 0 {
+			unreachable[i] = true
+			warn(r, CheckUnreachable, "rule can never match: line %d (%s) matches every path it would", rules[j].Line, strings.TrimSpace(rules[j].Text))
+		}
+	}
+
+	if src != nil {
+		u, err := usage(rules, src)
+		if err != nil {
+			return nil, err
+		}
+		for i := range rules {
+			r := &rules[i]
+			switch {
+			case unreachable[i]:
+			case u[i].matched == 0:
+				warn(r, CheckNoMatch, "pattern %q matches no files in the source", r.Pattern)
+			case u[i].blocked != "":
+				warn(r, CheckMissingTraversal, "%s would be included, but its parent %s/ is excluded first; add a rule such as \"+ **/\" before it", u[i].blockedPath, u[i].blocked)
+			case u[i].decided == 0 && u[i].reachable > 0:
+				w := &rules[u[i].shadowedBy]
+				warn(r, CheckShadowed, "rule never takes effect: every path it matches is decided earlier, e.g. %s by line %d (%s)", u[i].shadowedPath, w.Line, strings.TrimSpace(w.Text))
+			}
+		}
+	}
+
+	if hasInclude(rules) && !hasCatchAllExclude(rules) {
+		warn(&rules[len(rules)-1], CheckMissingCatchAll, "manifest does not end with \"- *\", so every path not matched by a rule is included")
+	}
+	return diags, nil
+}
+
+// ruleUsage records how a rule interacted with the paths of a source tree.
+type ruleUsage struct {
+	matched      int    // Paths the rule matches at all.
+	reachable    int    // Matched paths not hidden by an excluded parent.
+	decided      int    // Paths for which the rule was the first match.
+	shadowedBy   int    // The rule that decided the first reachable match instead.
+	shadowedPath string // That path.
+	blocked      string // An excluded parent that hid a path this rule includes.
+	blockedPath  string // That path.
+}
+
+// usage walks the whole source tree, including excluded directories, and
+// records how each rule was used.
+func usage(rules filter.Rules, src fs.FS) ([]ruleUsage, error) {
+	u := make([]ruleUsage, len(rules))
+	walkFn := func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == "." {
+			return nil
+		}
+		decision := rules.Explain(path, d.IsDir())
+		for i := range rules {
+			r := &rules[i]
+			if !r.Matches(path, d.IsDir()) {
+				continue
+			}
+			u[i].matched++
+			if decision.Parent != "" {
+				if decision.Own == r && r.Action == filter.Include && u[i].blocked == "" {
+					u[i].blocked, u[i].blockedPath = decision.Parent, path
+				}
+				continue
+			}
+			u[i].reachable++
+			switch {
+			case decision.Rule == r:
+				u[i].decided++
+			case u[i].shadowedPath == "":
+				u[i].shadowedBy = ruleIndex(rules, decision.Rule)
+				u[i].shadowedPath = path
+			}
+		}
+		return nil
+	}
+	if err := fs.WalkDir(src, ".", walkFn); err != nil {
+		return nil, fmt.Errorf("failed to walk source: %w", err)
+	}
+	return u, nil
+}
+
+// coveredBy returns the index of an earlier rule that matches every path rule i
+// could match, or -1 if there is none. Only patterns whose coverage can be
+// decided without looking at the source are considered: exact duplicates and
+// catch-all patterns.
+func coveredBy(rules filter.Rules, i int) int {
+	r := &rules[i]
+	for j := 0; j < i; j++ {
+		e := &rules[j]
+		if e.Negate {
+			continue
+		}
+		if !r.Negate && e.Pattern == r.Pattern {
+			return j
+		}
+		if matchesEverything(e) && (!isDirOnly(e) || isDirOnly(r)) {
+			return j
+		}
+	}
+	return -1
+}
+
+// matchesEverything reports whether a rule's pattern matches any path, such as
+// "*", "**" or their directory-only forms.
+func matchesEverything(r *filter.Rule) bool {
+	if r.Negate {
+		return false
+	}
+	p := strings.TrimSuffix(r.Pattern, "/")
+	return p == "*" || p == "**" || p == "/**"
+}
+
+func isDirOnly(r *filter.Rule) bool {
+	return len(r.Pattern) > 1 && strings.HasSuffix(r.Pattern, "/")
+}
+
+func hasInclude(rules filter.Rules) bool {
+	for _, r := range rules {
+		if r.Action == filter.Include {
+			return true
+		}
+	}
+	return false
+}
+
+func hasCatchAllExclude(rules filter.Rules) bool {
+	for i := range rules {
+		if rules[i].Action == filter.Exclude && matchesEverything(&rules[i]) && !isDirOnly(&rules[i]) {
+			return true
+		}
+	}
+	return false
+}
+
+func ruleIndex(rules filter.Rules, r *filter.Rule) int {
+	for i := range rules {
+		if &rules[i] == r {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,150 @@
+package lint
+
+import (
+	"io/fs"
+	"testing"
+	"testing/fstest"
+)
+
+// mockFS is a map-backed implementation of the filter.FileSystem interface.
+type mockFS map[string]string
+
+func (m mockFS) ReadFile(name string) ([]byte, error) {
+	data, ok := m[name]
+	if !ok {
+		return nil, fs.ErrNotExist
+	}
+	return []byte(data), nil
+}
+
+var testSource = fstest.MapFS{
+	"README.md":           {Data: []byte("readme")},
+	"main.go":             {Data: []byte("package main")},
+	"src/app/app.go":      {Data: []byte("package app")},
+	"src/app/app_test.go": {Data: []byte("package app")},
+}
+
+func TestManifest(t *testing.T) {
+	testCases := []struct {
+		name     string
+		manifest string
+		want     []Diagnostic
+	}{
+		{
+			name:     "Clean manifest",
+			manifest: "- *_test.go\n+ *.go\n+ **/\n- *\n",
+			want:     nil,
+		},
+		{
+			name:     "Missing parent traversal",
+			manifest: "+ /src/app/app.go\n+ /main.go\n- *\n",
+			want: []Diagnostic{
+				{Line: 1, Check: CheckMissingTraversal},
+			},
+		},
+		{
+			name:     "Specific rule after general rule",
+			manifest: "+ **/\n+ *.go\n- *_test.go\n- *\n",
+			want: []Diagnostic{
+				{Line: 3, Check: CheckShadowed},
+			},
+		},
+		{
+			name:     "Rule after catch-all",
+			manifest: "+ **/\n+ *.go\n- *\n+ README.md\n",
+			want: []Diagnostic{
+				{Line: 4, Check: CheckUnreachable},
+			},
+		},
+		{
+			name:     "Duplicate rule",
+			manifest: "+ **/\n+ *.go\n+ *.go\n- *\n",
+			want: []Diagnostic{
+				{Line: 3, Check: CheckUnreachable},
+			},
+		},
+		{
+			name:     "Excluding directories after including them all",
+			manifest: "+ **/\n- src/\n+ *.go\n- *\n",
+			want: []Diagnostic{
+				{Line: 2, Check: CheckUnreachable},
+			},
+		},
+		{
+			name:     "Pattern matching nothing",
+			manifest: "+ *.rs\n+ *.go\n+ **/\n- *\n",
+			want: []Diagnostic{
+				{Line: 1, Check: CheckNoMatch},
+			},
+		},
+		{
+			name:     "Trailing whitespace",
+			manifest: "+ main.go \n+ main.go\n- *\n",
+			want: []Diagnostic{
+				{Line: 1, Check: CheckTrailingSpace},
+				{Line: 1, Check: CheckNoMatch},
+			},
+		},
+		{
+			name:     "Missing catch-all",
+			manifest: "+ *.go\n",
+			want: []Diagnostic{
+				{Line: 1, Check: CheckMissingCatchAll},
+			},
+		},
+		{
+			name:     "Unsupported modifier and syntax error",
+			manifest: "-C foo\n* nope\n+ *.go\n+ **/\n- *\n",
+			want: []Diagnostic{
+				{Line: 1, Check: CheckUnsupported},
+				{Line: 2, Check: CheckSyntax},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fsys := mockFS{"/m.txt": tc.manifest}
+			got, err := Manifest("/m.txt", "/src", "", fsys, testSource)
+			if err != nil {
+				t.Fatalf("Manifest() returned an unexpected error: %v", err)
+			}
+			if len(got) != len(tc.want) {
+				t.Fatalf("Manifest() = %v, want %d diagnostics", got, len(tc.want))
+			}
+			for i, d := range got {
+				if d.File != "/m.txt" || d.Line != tc.want[i].Line || d.Check != tc.want[i].Check {
+					t.Errorf("diagnostic %d = %v, want line %d [%s]", i, d, tc.want[i].Line, tc.want[i].Check)
+				}
+			}
+		})
+	}
+}
+
+func TestManifestErrors(t *testing.T) {
+	if _, err := Manifest("/missing.txt", "/src", "", mockFS{}, testSource); err == nil {
+		t.Error("Manifest() did not return an error for a missing manifest")
+	}
+	fsys := mockFS{"/m.txt": "# format: gitignore\n*.log\n"}
+	if _, err := Manifest("/m.txt", "/src", "", fsys, testSource); err == nil {
+		t.Error("Manifest() did not return an error for a gitignore manifest")
+	}
+}
+
+func TestRulesWithoutSource(t *testing.T) {
+	fsys := mockFS{"/m.txt": "+ *.rs\n- *\n"}
+	got, err := Manifest("/m.txt", "/src", "", fsys, nil)
+	if err != nil {
+		t.Fatalf("Manifest() returned an unexpected error: %v", err)
+	}
+	if len(got) != 0 {
+		t.Errorf("source-dependent checks ran without a source: %v", got)
+	}
+}
+
+func TestDiagnosticString(t *testing.T) {
+	d := Diagnostic{File: "m.txt", Line: 3, Check: CheckNoMatch, Message: "nothing"}
+	if got, want := d.String(), "m.txt:3: nothing [no-match]"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
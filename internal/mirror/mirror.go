@@ -0,0 +1,95 @@
+// file: internal/mirror/mirror.go
+
+// Package mirror makes an output directory hold exactly the current slice, by
+// deleting whatever an earlier slice left behind that the manifest no longer
+// selects.
+//
+// Deleting files is only safe in a directory repo-slice created, so a
+// directory must hold a previous slice's provenance record before anything in
+// it is removed.
+package mirror
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+
+	"github.com/AlienHeadwars/repo-slice/internal/provenance"
+)
+
+// Check reports whether the slice can be mirrored into dir: it must not exist
+// yet, be empty, or hold a provenance record at the slash-separated path
+// marker. It returns the record it found, or nil if dir holds nothing.
+func Check(dir, marker string) (*provenance.Record, error) {
+	items, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read output directory: %w", err)
+	}
+	if len(items) == 0 {
+		return nil, nil
+	}
+
+	f, err := os.Open(filepath.Join(dir, filepath.FromSlash(marker)))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("refusing to mirror into %s: it is not empty and has no slice provenance record at %s; empty it or choose another output directory", dir, marker)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read provenance: %w", err)
+	}
+	defer f.Close()
+	rec, err := provenance.Read(f)
+	if err != nil {
+		return nil, fmt.Errorf("refusing to mirror into %s: %s: %w", dir, marker, err)
+	}
+	return &rec, nil
+}
+
+// Prune removes everything beneath dir except the slash-separated paths in
+// keep and the directories containing them. It returns the paths it removed,
+// in lexical order, with a trailing slash on directories, whose contents are
+// not listed.
+func Prune(dir string, keep []string) ([]string, error) {
+	kept := make(map[string]bool)
+	for _, name := range keep {
+		for ; name != "." && name != "/" && !kept[name]; name = path.Dir(name) {
+			kept[name] = true
+		}
+	}
+
+	var removed []string
+	walkFn := func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(rel)
+		if name == "." || kept[name] {
+			return nil
+		}
+		if err := os.RemoveAll(p); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", name, err)
+		}
+		if d.IsDir() {
+			removed = append(removed, name+"/")
+			return fs.SkipDir
+		}
+		removed = append(removed, name)
+		return nil
+	}
+
+	if err := filepath.WalkDir(dir, walkFn); err != nil {
+		return nil, err
+	}
+	sort.Strings(removed)
+	return removed, nil
+}
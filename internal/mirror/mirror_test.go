@@ -0,0 +1,121 @@
+package mirror
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/AlienHeadwars/repo-slice/internal/provenance"
+)
+
+// writeFiles creates the named files, and their directories, beneath dir.
+func writeFiles(t *testing.T, dir string, names ...string) {
+	t.Helper()
+	for _, name := range names {
+		file := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// listFiles returns the slash-separated paths of the files beneath dir.
+func listFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	var names []string
+	err := filepath.WalkDir(dir, func(p string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, p)
+		names = append(names, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestCheck(t *testing.T) {
+	slice := t.TempDir()
+	if err := provenance.Write(slice, provenance.DefaultPath, provenance.Record{Schema: provenance.Schema, Files: 3}); err != nil {
+		t.Fatal(err)
+	}
+	unmarked := t.TempDir()
+	writeFiles(t, unmarked, "notes.txt")
+	corrupt := t.TempDir()
+	writeFiles(t, corrupt, provenance.DefaultPath)
+
+	testCases := []struct {
+		name      string
+		dir       string
+		wantFiles int // Files in the record found, or -1 for none.
+		wantErr   string
+	}{
+		{"Missing directory", filepath.Join(t.TempDir(), "out"), -1, ""},
+		{"Empty directory", t.TempDir(), -1, ""},
+		{"Previous slice", slice, 3, ""},
+		{"Directory without a record", unmarked, -1, "has no slice provenance record"},
+		{"Unreadable record", corrupt, -1, "failed to parse provenance"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec, err := Check(tc.dir, provenance.DefaultPath)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("Check() error = %v, want it to contain %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Check() unexpected error: %v", err)
+			}
+			switch {
+			case tc.wantFiles < 0 && rec != nil:
+				t.Errorf("Check() = %+v, want no record", rec)
+			case tc.wantFiles >= 0 && (rec == nil || rec.Files != tc.wantFiles):
+				t.Errorf("Check() = %+v, want a record of %d files", rec, tc.wantFiles)
+			}
+		})
+	}
+}
+
+func TestPrune(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir,
+		"main.go",
+		"src/app.ts",
+		"src/app.tsx",
+		"src/old/legacy.ts",
+		"docs/guide.md",
+		provenance.DefaultPath,
+	)
+	if err := os.Mkdir(filepath.Join(dir, "empty"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	removed, err := Prune(dir, []string{"main.go", "src/app.tsx", "empty"})
+	if err != nil {
+		t.Fatalf("Prune() unexpected error: %v", err)
+	}
+
+	wantRemoved := []string{".repo-slice/", "docs/", "src/app.ts", "src/old/"}
+	if !reflect.DeepEqual(removed, wantRemoved) {
+		t.Errorf("Prune() removed = %v, want %v", removed, wantRemoved)
+	}
+	if got, want := listFiles(t, dir), []string{"main.go", "src/app.tsx"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("files after Prune() = %v, want %v", got, want)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "empty")); err != nil {
+		t.Errorf("kept directory was removed: %v", err)
+	}
+}
@@ -12,13 +12,14 @@ import (
 type MockFileInfo struct {
 	FileName  string
 	IsDirBool bool
+	FileSize  int64
 }
 
 // Name returns the name of the file.
 func (m MockFileInfo) Name() string { return m.FileName }
 
 // Size returns the size of the file.
-func (m MockFileInfo) Size() int64 { return 0 }
+func (m MockFileInfo) Size() int64 { return m.FileSize }
 
 // Mode returns the file mode.
 func (m MockFileInfo) Mode() fs.FileMode { return 0 }
@@ -34,7 +35,8 @@ func (m MockFileInfo) Sys() interface{} { return nil }
 
 // MockFS implements the FS interface for testing purposes.
 type MockFS struct {
-	Files      map[string]bool // path -> isDir
+	Files      map[string]bool  // path -> isDir
+	Sizes      map[string]int64 // path -> size; files not listed are empty.
 	RenameErr  error
 	WalkErr    error
 	WalkFnErr  error // New field to simulate an error passed to the walk function.
@@ -48,7 +50,7 @@ func (m *MockFS) Stat(name string) (fs.FileInfo, error) {
 	if !ok {
 		return nil, fs.ErrNotExist
 	}
-	return MockFileInfo{FileName: name, IsDirBool: isDir}, nil
+	return MockFileInfo{FileName: name, IsDirBool: isDir, FileSize: m.Sizes[name]}, nil
 }
 
 // WalkDir simulates walking a directory structure.
@@ -57,7 +59,7 @@ func (m *MockFS) WalkDir(root string, fn fs.WalkDirFunc) error {
 		return m.WalkErr
 	}
 	for path, isDir := range m.Files {
-		d := fs.FileInfoToDirEntry(MockFileInfo{FileName: path, IsDirBool: isDir})
+		d := fs.FileInfoToDirEntry(MockFileInfo{FileName: path, IsDirBool: isDir, FileSize: m.Sizes[path]})
 		// Pass the WalkFnErr to the callback to simulate a file system error during iteration.
 		if err := fn(path, d, m.WalkFnErr); err != nil {
 			return err
@@ -0,0 +1,125 @@
+// file: internal/pack/markdown.go
+package pack
+
+import (
+	"bufio"
+	"fmt"
+	"path"
+	"strings"
+	"unicode"
+)
+
+// languages maps file extensions, and a few well-known file names, to the
+// language hint of a fenced code block.
+var languages = map[string]string{
+	".bash": "bash", ".c": "c", ".cc": "cpp", ".cpp": "cpp", ".cs": "csharp",
+	".css": "css", ".dart": "dart", ".go": "go", ".graphql": "graphql",
+	".h": "c", ".hpp": "cpp", ".html": "html", ".java": "java", ".js": "javascript",
+	".json": "json", ".jsx": "jsx", ".kt": "kotlin", ".lua": "lua", ".md": "markdown",
+	".mdx": "mdx", ".php": "php", ".proto": "protobuf", ".ps1": "powershell",
+	".py": "python", ".rb": "ruby", ".rs": "rust", ".scala": "scala", ".scss": "scss",
+	".sh": "bash", ".sql": "sql", ".svelte": "svelte", ".swift": "swift",
+	".toml": "toml", ".ts": "typescript", ".tsx": "tsx", ".vue": "vue",
+	".xml": "xml", ".yaml": "yaml", ".yml": "yaml", ".zsh": "zsh",
+	"Dockerfile": "dockerfile", "Makefile": "makefile", "go.mod": "go-mod",
+}
+
+// language returns the language hint for the file at p, or "" if unknown.
+func language(p string) string {
+	if lang, ok := languages[path.Base(p)]; ok {
+		return lang
+	}
+	return languages[strings.ToLower(path.Ext(p))]
+}
+
+func renderMarkdown(w *bufio.Writer, files []file) error {
+	docs := contents(files)
+	var total int64
+	for _, f := range docs {
+		total += f.size
+	}
+
+	// Anchors are generated the way GitHub does, so the table of contents
+	// links work when the pack is viewed there.
+	anchors := newSlugger("repository-slice", "directory-tree", "table-of-contents", "files")
+
+	fmt.Fprintf(w, "# Repository Slice\n\n%d files, %d bytes.\n\n", len(docs), total)
+	fmt.Fprintf(w, "## Directory Tree\n\n```text\n%s```\n\n", tree(files))
+	fmt.Fprint(w, "## Table of Contents\n\n")
+	var headings []string
+	for i, f := range docs {
+		heading := "`" + f.path + "`"
+		headings = append(headings, heading)
+		fmt.Fprintf(w, "%d. [%s](#%s)\n", i+1, heading, anchors.slug(heading))
+	}
+	fmt.Fprint(w, "\n## Files\n")
+
+	for i, f := range docs {
+		fmt.Fprintf(w, "\n### %s\n\n", headings[i])
+		if n := note(f); n != "" {
+			fmt.Fprintf(w, "%s\n", n)
+			continue
+		}
+		content := string(f.content)
+		if content != "" && !strings.HasSuffix(content, "\n") {
+			content += "\n"
+		}
+		fence := fenceFor(content)
+		fmt.Fprintf(w, "%s%s\n%s%s\n", fence, language(f.path), content, fence)
+	}
+	return nil
+}
+
+// fenceFor returns a backtick fence longer than any run of backticks in
+// content, so the content cannot close its own code block.
+func fenceFor(content string) string {
+	longest, run := 0, 0
+	for _, r := range content {
+		if r == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	return strings.Repeat("`", max(3, longest+1))
+}
+
+// slugger generates unique heading anchors.
+type slugger struct {
+	used map[string]int
+}
+
+// newSlugger returns a slugger that treats the given anchors as taken.
+func newSlugger(taken ...string) *slugger {
+	s := &slugger{used: make(map[string]int)}
+	for _, t := range taken {
+		s.used[t] = 1
+	}
+	return s
+}
+
+// slug returns the anchor of a heading: lower case, with spaces replaced by
+// hyphens and punctuation other than hyphens and underscores removed. Repeated
+// anchors have "-1", "-2" and so on appended.
+func (s *slugger) slug(heading string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(heading) {
+		switch {
+		case unicode.IsLetter(r), unicode.IsDigit(r), r == '-', r == '_':
+			b.WriteRune(r)
+		case r == ' ':
+			b.WriteRune('-')
+		}
+	}
+	base := b.String()
+	slug := base
+	for n := s.used[base]; s.used[slug] > 0; n++ {
+		slug = fmt.Sprintf("%s-%d", base, n)
+	}
+	s.used[base]++
+	if slug != base {
+		s.used[slug]++
+	}
+	return slug
+}
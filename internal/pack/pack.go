@@ -0,0 +1,169 @@
+// file: internal/pack/pack.go
+
+// Package pack renders a slice as a single document, a "context pack", that
+// can be pasted or uploaded into a chat assistant. A pack opens with a
+// directory tree and a table of contents, followed by every file in the slice
+// in lexical order.
+package pack
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+	"unicode/utf8"
+)
+
+// Format identifies a pack format.
+type Format string
+
+const (
+	// FormatMarkdown renders each file as a fenced code block under a heading
+	// holding its path.
+	FormatMarkdown Format = "markdown"
+	// FormatXML renders each file as a <document path="..."> element.
+	FormatXML Format = "xml"
+)
+
+// Valid reports whether f names a supported pack format.
+func (f Format) Valid() bool {
+	return f == FormatMarkdown || f == FormatXML
+}
+
+// LinkReader is implemented by source file systems that can report the target
+// of a symbolic link.
+type LinkReader interface {
+	ReadLink(name string) (string, error)
+}
+
+// file is an entry of the slice.
+type file struct {
+	path    string
+	isDir   bool
+	size    int64
+	isLink  bool
+	target  string // The destination of a symbolic link.
+	binary  bool   // Not valid UTF-8, so not shown.
+	content []byte
+}
+
+// Write renders every file in src as a pack in the given format. Files that
+// are not valid UTF-8 text are listed but their content is omitted.
+func Write(w io.Writer, src fs.FS, format Format) error {
+	var render func(*bufio.Writer, []file) error
+	switch format {
+	case FormatMarkdown:
+		render = renderMarkdown
+	case FormatXML:
+		render = renderXML
+	default:
+		return fmt.Errorf("unknown pack format: %q", format)
+	}
+	files, err := collect(src)
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(w)
+	if err := render(bw, files); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// collect walks src in lexical order and reads every file.
+func collect(src fs.FS) ([]file, error) {
+	var files []file
+	walkFn := func(p string, d fs.DirEntry, err error) error {
+		if err != nil || p == "." {
+			return err
+		}
+		switch t := d.Type(); {
+		case d.IsDir():
+			files = append(files, file{path: p, isDir: true})
+		case t&fs.ModeSymlink != 0:
+			lr, ok := src.(LinkReader)
+			if !ok {
+				return fmt.Errorf("failed to read %s: source cannot read symbolic links", p)
+			}
+			target, err := lr.ReadLink(p)
+			if err != nil {
+				return fmt.Errorf("failed to read %s: %w", p, err)
+			}
+			files = append(files, file{path: p, isLink: true, target: target})
+		case t.IsRegular():
+			data, err := fs.ReadFile(src, p)
+			if err != nil {
+				return fmt.Errorf("failed to read %s: %w", p, err)
+			}
+			files = append(files, file{path: p, size: int64(len(data)), binary: !utf8.Valid(data), content: data})
+		}
+		return nil
+	}
+	if err := fs.WalkDir(src, ".", walkFn); err != nil {
+		return nil, fmt.Errorf("failed to walk slice: %w", err)
+	}
+	return files, nil
+}
+
+// tree draws the directory structure of files in the style of the tree
+// command, with directories marked by a trailing slash.
+func tree(files []file) string {
+	// Files are in walk order, so the last entry seen with each parent is that
+	// parent's last child.
+	lastChild := make(map[string]string)
+	for _, f := range files {
+		lastChild[path.Dir(f.path)] = f.path
+	}
+	isLast := func(p string) bool { return lastChild[path.Dir(p)] == p }
+
+	var b strings.Builder
+	b.WriteString(".\n")
+	for _, f := range files {
+		parts := strings.Split(f.path, "/")
+		// Each ancestor's column continues with a bar while that ancestor has
+		// siblings still to come.
+		for i := 1; i < len(parts); i++ {
+			if isLast(strings.Join(parts[:i], "/")) {
+				b.WriteString("    ")
+			} else {
+				b.WriteString("│   ")
+			}
+		}
+		if isLast(f.path) {
+			b.WriteString("└── ")
+		} else {
+			b.WriteString("├── ")
+		}
+		b.WriteString(parts[len(parts)-1])
+		if f.isDir {
+			b.WriteString("/")
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// contents returns the files whose content appears in the pack, which is
+// every entry except directories.
+func contents(files []file) []file {
+	var out []file
+	for _, f := range files {
+		if !f.isDir {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// note describes an entry whose content is not shown, or returns "".
+func note(f file) string {
+	switch {
+	case f.isLink:
+		return "Symbolic link to " + f.target + "."
+	case f.binary:
+		return fmt.Sprintf("Binary file, %d bytes, not shown.", f.size)
+	}
+	return ""
+}
@@ -0,0 +1,169 @@
+// file: internal/pack/pack_test.go
+package pack
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"io/fs"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// testTree is a small slice with nested directories, a symbolic link, a binary
+// file and a file containing a code fence.
+func testTree() fstest.MapFS {
+	return fstest.MapFS{
+		"README.md":         {Data: []byte("# Demo\n\n```sh\nmake\n```\n")},
+		"go.mod":            {Data: []byte("module demo")},
+		"assets/logo.png":   {Data: []byte{0x89, 'P', 'N', 'G', 0xff}},
+		"src/app/main.ts":   {Data: []byte("export {}\n")},
+		"src/app/util.ts":   {Data: []byte("export const x = 1\n")},
+		"src/index.ts":      {Data: []byte("import './app/main'\n")},
+		"src/latest.ts":     {Data: []byte("app/main.ts"), Mode: fs.ModeSymlink | 0777},
+		"src/app/empty.txt": {},
+	}
+}
+
+func TestTree(t *testing.T) {
+	files, err := collect(testTree())
+	if err != nil {
+		t.Fatalf("collect() returned an unexpected error: %v", err)
+	}
+	want := `.
+├── README.md
+├── assets/
+│   └── logo.png
+├── go.mod
+└── src/
+    ├── app/
+    │   ├── empty.txt
+    │   ├── main.ts
+    │   └── util.ts
+    ├── index.ts
+    └── latest.ts
+`
+	if got := tree(files); got != want {
+		t.Errorf("tree() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestWriteMarkdown(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, testTree(), FormatMarkdown); err != nil {
+		t.Fatalf("Write() returned an unexpected error: %v", err)
+	}
+	want := "# Repository Slice\n\n8 files, 88 bytes.\n\n" +
+		"## Directory Tree\n\n```text\n" +
+		".\n├── README.md\n├── assets/\n│   └── logo.png\n├── go.mod\n└── src/\n" +
+		"    ├── app/\n    │   ├── empty.txt\n    │   ├── main.ts\n    │   └── util.ts\n" +
+		"    ├── index.ts\n    └── latest.ts\n```\n\n" +
+		"## Table of Contents\n\n" +
+		"1. [`README.md`](#readmemd)\n" +
+		"2. [`assets/logo.png`](#assetslogopng)\n" +
+		"3. [`go.mod`](#gomod)\n" +
+		"4. [`src/app/empty.txt`](#srcappemptytxt)\n" +
+		"5. [`src/app/main.ts`](#srcappmaints)\n" +
+		"6. [`src/app/util.ts`](#srcapputilts)\n" +
+		"7. [`src/index.ts`](#srcindexts)\n" +
+		"8. [`src/latest.ts`](#srclatestts)\n" +
+		"\n## Files\n" +
+		"\n### `README.md`\n\n````markdown\n# Demo\n\n```sh\nmake\n```\n````\n" +
+		"\n### `assets/logo.png`\n\nBinary file, 5 bytes, not shown.\n" +
+		"\n### `go.mod`\n\n```go-mod\nmodule demo\n```\n" +
+		"\n### `src/app/empty.txt`\n\n```\n```\n" +
+		"\n### `src/app/main.ts`\n\n```typescript\nexport {}\n```\n" +
+		"\n### `src/app/util.ts`\n\n```typescript\nexport const x = 1\n```\n" +
+		"\n### `src/index.ts`\n\n```typescript\nimport './app/main'\n```\n" +
+		"\n### `src/latest.ts`\n\nSymbolic link to app/main.ts.\n"
+	if got := buf.String(); got != want {
+		t.Errorf("Write() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestWriteXML(t *testing.T) {
+	tree := testTree()
+	tree["src/cdata.ts"] = &fstest.MapFile{Data: []byte("const s = \"]]>\" && a < b\n")}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, tree, FormatXML); err != nil {
+		t.Fatalf("Write() returned an unexpected error: %v", err)
+	}
+
+	// Decoding the pack proves it is well-formed and that content survives.
+	var got struct {
+		Tree     string `xml:"directory_tree"`
+		Contents []struct {
+			Path string `xml:"path,attr"`
+		} `xml:"table_of_contents>file"`
+		Documents []struct {
+			Path    string `xml:"path,attr"`
+			Symlink string `xml:"symlink,attr"`
+			Binary  bool   `xml:"binary,attr"`
+			Content string `xml:",chardata"`
+		} `xml:"document"`
+	}
+	if err := xml.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("pack is not well-formed XML: %v\n%s", err, buf.String())
+	}
+	if len(got.Contents) != 9 || len(got.Documents) != 9 {
+		t.Fatalf("pack lists %d files and %d documents, want 9", len(got.Contents), len(got.Documents))
+	}
+	if !strings.HasPrefix(strings.TrimSpace(got.Tree), ".\n├── README.md\n") {
+		t.Errorf("directory tree = %q, want it to start at the root", got.Tree)
+	}
+	byPath := make(map[string]int)
+	for i, d := range got.Documents {
+		byPath[d.Path] = i
+		if d.Path != got.Contents[i].Path {
+			t.Errorf("document %d is %s, but the table of contents lists %s", i, d.Path, got.Contents[i].Path)
+		}
+	}
+	if d := got.Documents[byPath["src/cdata.ts"]]; d.Content != "\n"+string(tree["src/cdata.ts"].Data)+"\n" {
+		t.Errorf("src/cdata.ts content = %q", d.Content)
+	}
+	if d := got.Documents[byPath["src/latest.ts"]]; d.Symlink != "app/main.ts" {
+		t.Errorf("src/latest.ts symlink = %q, want app/main.ts", d.Symlink)
+	}
+	if d := got.Documents[byPath["assets/logo.png"]]; !d.Binary || d.Content != "" {
+		t.Errorf("assets/logo.png = %+v, want a binary document without content", d)
+	}
+}
+
+func TestWriteErrors(t *testing.T) {
+	if err := Write(io.Discard, testTree(), "html"); err == nil {
+		t.Error("Write() did not reject an unknown format")
+	}
+	// Embedding hides the ReadLink method, so the symbolic link cannot be read.
+	if err := Write(io.Discard, struct{ fs.FS }{testTree()}, FormatXML); err == nil {
+		t.Error("Write() did not report a symbolic link it cannot read")
+	}
+}
+
+func TestFenceFor(t *testing.T) {
+	testCases := map[string]string{
+		"plain":         "```",
+		"inline `code`": "```",
+		"```go\n```":    "````",
+		"`````\n":       "``````",
+	}
+	for content, want := range testCases {
+		if got := fenceFor(content); got != want {
+			t.Errorf("fenceFor(%q) = %q, want %q", content, got, want)
+		}
+	}
+}
+
+func TestSlug(t *testing.T) {
+	s := newSlugger("files")
+	var got []string
+	for _, heading := range []string{"`files`", "`src/a.go`", "`src/a.go`", "`src/a-1.go`", "Hello World"} {
+		got = append(got, s.slug(heading))
+	}
+	want := []string{"files-1", "srcago", "srcago-1", "srca-1go", "hello-world"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("slug() = %v, want %v", got, want)
+	}
+}
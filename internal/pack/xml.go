@@ -0,0 +1,52 @@
+// file: internal/pack/xml.go
+package pack
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+func renderXML(w *bufio.Writer, files []file) error {
+	docs := contents(files)
+
+	fmt.Fprint(w, "<pack>\n<directory_tree>\n")
+	writeCDATA(w, tree(files))
+	fmt.Fprint(w, "\n</directory_tree>\n<table_of_contents>\n")
+	for _, f := range docs {
+		fmt.Fprintf(w, "<file path=\"%s\" size=\"%d\"/>\n", attr(f.path), f.size)
+	}
+	fmt.Fprint(w, "</table_of_contents>\n")
+
+	for _, f := range docs {
+		switch {
+		case f.isLink:
+			fmt.Fprintf(w, "<document path=\"%s\" symlink=\"%s\"/>\n", attr(f.path), attr(f.target))
+		case f.binary:
+			fmt.Fprintf(w, "<document path=\"%s\" size=\"%d\" binary=\"true\"/>\n", attr(f.path), f.size)
+		default:
+			fmt.Fprintf(w, "<document path=\"%s\">\n", attr(f.path))
+			writeCDATA(w, string(f.content))
+			fmt.Fprint(w, "\n</document>\n")
+		}
+	}
+	fmt.Fprint(w, "</pack>\n")
+	return nil
+}
+
+// attr escapes s for use in a double-quoted attribute value.
+func attr(s string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(s)) // A strings.Builder never fails.
+	return b.String()
+}
+
+// writeCDATA writes s as a CDATA section, so file content keeps its original
+// characters while the pack remains well-formed XML. A "]]>" in s is split
+// across two sections.
+func writeCDATA(w *bufio.Writer, s string) {
+	w.WriteString("<![CDATA[")
+	w.WriteString(strings.ReplaceAll(s, "]]>", "]]]]><![CDATA[>"))
+	w.WriteString("]]>")
+}
@@ -0,0 +1,142 @@
+// file: internal/priority/priority.go
+
+// Package priority decides which files to drop when a slice is over its
+// budget. A priority list gives files weights; when the slice overflows, the
+// files with the lowest weight go first, largest first among equals.
+package priority
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/AlienHeadwars/repo-slice/internal/filter"
+)
+
+// Weight assigns a priority to the paths a pattern matches.
+type Weight struct {
+	Rule  filter.Rule // The pattern, with the location it was read from.
+	Value int
+}
+
+// List is an ordered priority list. The first weight whose pattern matches a
+// file, or one of its parent directories, decides the file's priority.
+type List []Weight
+
+// Load reads the priority list at path.
+func Load(path string, fsys filter.FileSystem) (List, error) {
+	data, err := fsys.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read priority list: %w", err)
+	}
+	return Parse(data, path)
+}
+
+// Parse reads a priority list. Each line holds an integer weight followed by a
+// pattern in the manifest's rsync syntax, such as "100 /cmd/" or
+// "-10 *_test.go". Blank lines and lines starting with "#" are ignored.
+func Parse(data []byte, file string) (List, error) {
+	var list List
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		weight, pattern, ok := strings.Cut(line, " ")
+		pattern = strings.TrimSpace(pattern)
+		if !ok || pattern == "" {
+			return nil, fmt.Errorf("%s:%d: expected a weight and a pattern", file, n)
+		}
+		value, err := strconv.Atoi(weight)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid weight %q", file, n, weight)
+		}
+		rule := filter.NewRule(filter.Include, pattern)
+		rule.File, rule.Line, rule.Text = file, n, line
+		list = append(list, Weight{Rule: rule, Value: value})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read priority list: %w", err)
+	}
+	return list, nil
+}
+
+// Of returns the priority of the file at the slash-separated path. Files no
+// weight matches have priority zero.
+func (l List) Of(name string) int {
+	for i := range l {
+		rule := &l[i].Rule
+		if rule.Matches(name, false) {
+			return l[i].Value
+		}
+		for dir := path.Dir(name); dir != "."; dir = path.Dir(dir) {
+			if rule.Matches(dir, true) {
+				return l[i].Value
+			}
+		}
+	}
+	return 0
+}
+
+// File is a file in a slice that may be dropped.
+type File struct {
+	Path     string
+	Size     int64
+	Tokens   int
+	Priority int
+}
+
+// Budget bounds a slice. A zero value disables a limit.
+type Budget struct {
+	MaxFiles  int
+	MaxSize   int64
+	MaxTokens int
+}
+
+// Fit returns the files to drop so that the rest fit the budget: those with
+// the lowest priority first and, among equal priorities, the largest. Nothing
+// is dropped from a slice that already fits.
+func Fit(files []File, budget Budget) []File {
+	count, size, tokens := len(files), int64(0), 0
+	for _, f := range files {
+		size += f.Size
+		tokens += f.Tokens
+	}
+	fits := func() bool {
+		return (budget.MaxFiles <= 0 || count <= budget.MaxFiles) &&
+			(budget.MaxSize <= 0 || size <= budget.MaxSize) &&
+			(budget.MaxTokens <= 0 || tokens <= budget.MaxTokens)
+	}
+
+	order := append([]File(nil), files...)
+	sort.SliceStable(order, func(i, j int) bool {
+		a, b := order[i], order[j]
+		if a.Priority != b.Priority {
+			return a.Priority < b.Priority
+		}
+		if a.Size != b.Size {
+			return a.Size > b.Size
+		}
+		if a.Tokens != b.Tokens {
+			return a.Tokens > b.Tokens
+		}
+		return a.Path < b.Path
+	})
+
+	var dropped []File
+	for _, f := range order {
+		if fits() {
+			break
+		}
+		dropped = append(dropped, f)
+		count--
+		size -= f.Size
+		tokens -= f.Tokens
+	}
+	return dropped
+}
@@ -0,0 +1,87 @@
+// file: internal/priority/priority_test.go
+package priority
+
+import (
+	"reflect"
+	"testing"
+)
+
+const testList = `
+# Keep the entry points and docs; tests go first.
+100 /cmd/
+50  *.md
+-10 *_test.go
+-10 /testdata/***
+`
+
+func TestOf(t *testing.T) {
+	list, err := Parse([]byte(testList), "priorities.txt")
+	if err != nil {
+		t.Fatalf("Parse() returned an unexpected error: %v", err)
+	}
+	if len(list) != 4 || list[2].Rule.Location() != "priorities.txt:5" {
+		t.Fatalf("Parse() = %+v, want 4 weights with their locations", list)
+	}
+
+	testCases := map[string]int{
+		"cmd/repo-slice/main.go":      100,
+		"cmd/repo-slice/main_test.go": 100, // The first matching weight wins.
+		"README.md":                   50,
+		"docs/guide.md":               50,
+		"internal/slicer/slicer.go":   0,
+		"internal/slicer/x_test.go":   -10,
+		"testdata/golden/out.txt":     -10,
+		"src/testdata/out.txt":        0,
+	}
+	for name, want := range testCases {
+		if got := list.Of(name); got != want {
+			t.Errorf("Of(%q) = %d, want %d", name, got, want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, data := range []string{"100\n", "high /cmd/\n", "1.5 *.go\n"} {
+		if _, err := Parse([]byte(data), "p.txt"); err == nil {
+			t.Errorf("Parse(%q) did not return an error", data)
+		}
+	}
+}
+
+func TestFit(t *testing.T) {
+	files := []File{
+		{Path: "README.md", Size: 100, Tokens: 30, Priority: 50},
+		{Path: "main.go", Size: 400, Tokens: 100, Priority: 100},
+		{Path: "a_test.go", Size: 300, Tokens: 90, Priority: -10},
+		{Path: "b_test.go", Size: 200, Tokens: 60, Priority: -10},
+		{Path: "util.go", Size: 250, Tokens: 70, Priority: 0},
+	}
+	paths := func(fs []File) []string {
+		var out []string
+		for _, f := range fs {
+			out = append(out, f.Path)
+		}
+		return out
+	}
+
+	testCases := []struct {
+		name   string
+		budget Budget
+		want   []string
+	}{
+		{"Fits", Budget{MaxFiles: 5, MaxSize: 1250, MaxTokens: 350}, nil},
+		{"No limits", Budget{}, nil},
+		{"File count", Budget{MaxFiles: 4}, []string{"a_test.go"}},
+		{"Size", Budget{MaxSize: 700}, []string{"a_test.go", "b_test.go", "util.go"}},
+		{"Tokens", Budget{MaxTokens: 290}, []string{"a_test.go"}},
+		{"Every limit", Budget{MaxFiles: 4, MaxSize: 1000, MaxTokens: 200}, []string{"a_test.go", "b_test.go"}},
+		{"High priority last", Budget{MaxSize: 1}, []string{"a_test.go", "b_test.go", "util.go", "README.md", "main.go"}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := paths(Fit(files, tc.budget)); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("Fit() dropped %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
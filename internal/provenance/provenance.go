@@ -0,0 +1,126 @@
+// file: internal/provenance/provenance.go
+
+// Package provenance records where a slice came from. Once a slice has been
+// pushed to a context branch, the record is the only way to tell which source
+// commit, manifest and tool version produced it.
+package provenance
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultPath is where the record is written in a slice, unless configured
+// otherwise.
+const DefaultPath = ".repo-slice/provenance.json"
+
+// Off is the path that disables the record.
+const Off = "off"
+
+// Schema is the version of the record's format, incremented whenever a field
+// changes meaning.
+const Schema = 1
+
+// Record describes how a slice was produced.
+type Record struct {
+	Schema       int               `json:"schema"`
+	Version      string            `json:"version"`          // The version of repo-slice.
+	Created      time.Time         `json:"created,omitzero"` // Omitted if the source has no commit.
+	Source       Source            `json:"source"`
+	Manifest     Manifest          `json:"manifest"`
+	ExtensionMap map[string]string `json:"extensionMap,omitempty"`
+	Files        int               `json:"files"` // Files in the slice, not counting the record.
+	Bytes        int64             `json:"bytes"` // Their total size.
+}
+
+// Source identifies the source tree a slice was taken from.
+type Source struct {
+	// Commit is the full hash of the commit that was sliced, or that was
+	// checked out when the working tree was sliced. It is empty if the source
+	// is not in a git repository.
+	Commit string `json:"commit,omitempty"`
+	// Dirty reports that the working tree was sliced while it differed from
+	// Commit, so the slice may hold changes no commit does.
+	Dirty bool   `json:"dirty"`
+	Ref   string `json:"ref,omitempty"` // The ref that was sliced, if any.
+}
+
+// Manifest identifies the manifest a slice was produced with.
+type Manifest struct {
+	Path   string `json:"path,omitempty"` // Empty for rules given inline in a config file.
+	SHA256 string `json:"sha256"`         // Hex-encoded hash of its content.
+}
+
+// CheckPath reports whether name can hold the record: a slash-separated path
+// inside the slice.
+func CheckPath(name string) error {
+	clean := path.Clean(name)
+	if name == "" || path.IsAbs(name) || filepath.IsAbs(name) || clean == "." || clean == ".." || strings.HasPrefix(clean, "../") {
+		return fmt.Errorf("invalid provenance path %q: must be a relative path inside the slice", name)
+	}
+	return nil
+}
+
+// Created returns the time to record as a slice's creation time: the time in
+// the SOURCE_DATE_EPOCH environment variable or, failing that, committed, the
+// committer time of the source commit. The clock is never read, so slicing the
+// same commit twice gives byte-for-byte identical records and archives.
+func Created(committed time.Time) (time.Time, error) {
+	epoch := os.Getenv("SOURCE_DATE_EPOCH")
+	if epoch == "" {
+		return committed, nil
+	}
+	seconds, err := strconv.ParseInt(epoch, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid SOURCE_DATE_EPOCH %q: must be a number of seconds", epoch)
+	}
+	return time.Unix(seconds, 0).UTC(), nil
+}
+
+// Hash returns the hex-encoded SHA-256 hash of data.
+func Hash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Write stores the record at the slash-separated name inside the slice at dir,
+// creating any directories it needs.
+func Write(dir, name string, r Record) error {
+	if err := CheckPath(name); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	file := filepath.Join(dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return fmt.Errorf("failed to write provenance: %w", err)
+	}
+	if err := os.WriteFile(file, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write provenance: %w", err)
+	}
+	return nil
+}
+
+// Read parses a record. Records written by a newer version of repo-slice, with
+// a schema this one does not know, are rejected rather than misread.
+func Read(r io.Reader) (Record, error) {
+	var rec Record
+	if err := json.NewDecoder(r).Decode(&rec); err != nil {
+		return Record{}, fmt.Errorf("failed to parse provenance: %w", err)
+	}
+	if rec.Schema < 1 || rec.Schema > Schema {
+		return Record{}, fmt.Errorf("unsupported provenance schema %d", rec.Schema)
+	}
+	return rec, nil
+}
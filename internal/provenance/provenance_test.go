@@ -0,0 +1,90 @@
+package provenance
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteAndRead(t *testing.T) {
+	dir := t.TempDir()
+	rec := Record{
+		Schema:       Schema,
+		Version:      "v1.2.5",
+		Created:      time.Unix(1700000000, 0).UTC(),
+		Source:       Source{Commit: "0123456789abcdef0123456789abcdef01234567", Dirty: true},
+		Manifest:     Manifest{Path: "allow-list.txt", SHA256: Hash([]byte("+ *\n"))},
+		ExtensionMap: map[string]string{".tsx": ".ts"},
+		Files:        3,
+		Bytes:        1024,
+	}
+	if err := Write(dir, DefaultPath, rec); err != nil {
+		t.Fatalf("Write() returned an unexpected error: %v", err)
+	}
+	f, err := os.Open(filepath.Join(dir, ".repo-slice", "provenance.json"))
+	if err != nil {
+		t.Fatalf("record not written: %v", err)
+	}
+	defer f.Close()
+	got, err := Read(f)
+	if err != nil {
+		t.Fatalf("Read() returned an unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(got, rec) {
+		t.Errorf("Read() = %+v, want %+v", got, rec)
+	}
+}
+
+func TestRead(t *testing.T) {
+	for _, data := range []string{"not json", `{"schema": 0}`, `{"schema": 99}`} {
+		if _, err := Read(strings.NewReader(data)); err == nil {
+			t.Errorf("Read(%q) did not return an error", data)
+		}
+	}
+}
+
+func TestCheckPath(t *testing.T) {
+	for name, valid := range map[string]bool{
+		DefaultPath: true, "meta/slice.json": true, "slice.json": true,
+		"": false, ".": false, "..": false, "../slice.json": false, "/tmp/slice.json": false,
+	} {
+		if err := CheckPath(name); (err == nil) != valid {
+			t.Errorf("CheckPath(%q) = %v, want valid %v", name, err, valid)
+		}
+	}
+}
+
+func TestCreated(t *testing.T) {
+	committed := time.Unix(1600000000, 0).UTC()
+	t.Setenv("SOURCE_DATE_EPOCH", "")
+	if got, err := Created(committed); err != nil || !got.Equal(committed) {
+		t.Errorf("Created() = %v, %v, want the commit time", got, err)
+	}
+	t.Setenv("SOURCE_DATE_EPOCH", "1700000000")
+	if got, err := Created(committed); err != nil || !got.Equal(time.Unix(1700000000, 0)) {
+		t.Errorf("Created() = %v, %v, want the SOURCE_DATE_EPOCH time", got, err)
+	}
+	t.Setenv("SOURCE_DATE_EPOCH", "yesterday")
+	if _, err := Created(committed); err == nil {
+		t.Error("Created() did not reject an invalid SOURCE_DATE_EPOCH")
+	}
+}
+
+// TestWriteOmitsUnknownCreated verifies that a slice of a source without a
+// commit records no creation time rather than the zero time.
+func TestWriteOmitsUnknownCreated(t *testing.T) {
+	dir := t.TempDir()
+	if err := Write(dir, DefaultPath, Record{Schema: Schema}); err != nil {
+		t.Fatalf("Write() returned an unexpected error: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, ".repo-slice", "provenance.json"))
+	if err != nil {
+		t.Fatalf("record not written: %v", err)
+	}
+	if strings.Contains(string(data), "created") {
+		t.Errorf("record holds a creation time:\n%s", data)
+	}
+}
@@ -5,6 +5,7 @@
 package remapper
 
 import (
+	"errors"
 	"fmt"
 	"io/fs"
 	"os"
@@ -68,6 +69,17 @@ func ParseExtensionMap(mapStr string) (map[string]string, error) {
 	return extMap, nil
 }
 
+// RemapPath returns the path a file will have once the extension map has been
+// applied, or the path unchanged if its extension is not remapped.
+func RemapPath(path string, extMap map[string]string) string {
+	currentExt := filepath.Ext(path)
+	newExt, shouldRemap := extMap[currentExt]
+	if !shouldRemap {
+		return path
+	}
+	return strings.TrimSuffix(path, currentExt) + newExt
+}
+
 // RemapExtensions walks a directory and renames files based on the provided
 // extension map.
 func RemapExtensions(dir string, extMap map[string]string, fsys FileSystem) error {
@@ -79,12 +91,7 @@ func RemapExtensions(dir string, extMap map[string]string, fsys FileSystem) erro
 			return nil // Skip directories.
 		}
 
-		currentExt := filepath.Ext(path)
-		newExt, shouldRemap := extMap[currentExt]
-
-		if shouldRemap {
-			base := strings.TrimSuffix(path, currentExt)
-			newPath := base + newExt
+		if newPath := RemapPath(path, extMap); newPath != path {
 			if err := fsys.Rename(path, newPath); err != nil {
 				return fmt.Errorf("failed to rename %s to %s: %w", path, newPath, err)
 			}
@@ -94,3 +101,21 @@ func RemapExtensions(dir string, extMap map[string]string, fsys FileSystem) erro
 
 	return fsys.WalkDir(dir, walkFn)
 }
+
+// RemapFiles renames the files at the slash-separated paths beneath dir based
+// on the provided extension map, leaving every other file alone. Paths that
+// no longer exist are ignored.
+func RemapFiles(dir string, paths []string, extMap map[string]string, fsys FileSystem) error {
+	for _, p := range paths {
+		newPath := RemapPath(p, extMap)
+		if newPath == p {
+			continue
+		}
+		oldName := filepath.Join(dir, filepath.FromSlash(p))
+		newName := filepath.Join(dir, filepath.FromSlash(newPath))
+		if err := fsys.Rename(oldName, newName); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("failed to rename %s to %s: %w", oldName, newName, err)
+		}
+	}
+	return nil
+}
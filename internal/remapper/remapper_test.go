@@ -3,6 +3,8 @@ package remapper
 
 import (
 	"errors"
+	"io/fs"
+	"path/filepath"
 	"reflect"
 	"testing"
 
@@ -35,6 +37,28 @@ func TestParseExtensionMap(t *testing.T) {
 	}
 }
 
+func TestRemapPath(t *testing.T) {
+	extMap := map[string]string{".tsx": ".ts"}
+
+	testCases := []struct {
+		name string
+		path string
+		want string
+	}{
+		{"Remaps matching extension", "src/component.tsx", "src/component.ts"},
+		{"Leaves other extensions alone", "src/style.css", "src/style.css"},
+		{"Leaves files without extension alone", "Makefile", "Makefile"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := RemapPath(tc.path, extMap); got != tc.want {
+				t.Errorf("RemapPath(%q) = %q, want %q", tc.path, got, tc.want)
+			}
+		})
+	}
+}
+
 func TestRemapExtensions(t *testing.T) {
 	baseExtMap := map[string]string{".tsx": ".ts"}
 
@@ -103,3 +127,33 @@ func TestRemapExtensions(t *testing.T) {
 		})
 	}
 }
+
+func TestRemapFiles(t *testing.T) {
+	extMap := map[string]string{".tsx": ".ts"}
+
+	testCases := []struct {
+		name           string
+		mockFS         *mocks.MockFS
+		paths          []string
+		wantErr        bool
+		expectedRename string
+	}{
+		{"renames listed file", &mocks.MockFS{}, []string{"src/component.tsx"}, false, filepath.Join("out", "src", "component.ts")},
+		{"ignores non-matching file", &mocks.MockFS{}, []string{"style.css"}, false, ""},
+		{"ignores missing file", &mocks.MockFS{RenameErr: fs.ErrNotExist}, []string{"component.tsx"}, false, filepath.Join("out", "component.ts")},
+		{"handles rename error", &mocks.MockFS{RenameErr: errors.New("rename failed")}, []string{"component.tsx"}, true, filepath.Join("out", "component.ts")},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := RemapFiles("out", tc.paths, extMap, tc.mockFS)
+
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("RemapFiles() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if tc.mockFS.RenameTo != tc.expectedRename {
+				t.Errorf("Expected rename to '%s', got '%s'", tc.expectedRename, tc.mockFS.RenameTo)
+			}
+		})
+	}
+}
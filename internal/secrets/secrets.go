@@ -0,0 +1,292 @@
+// file: internal/secrets/secrets.go
+
+// Package secrets finds credentials in a slice before it is shared. Slices are
+// pushed to branches that third-party assistants read, so a leaked key in a
+// slice is as bad as a leaked key in a public repository.
+//
+// Findings identify a secret by its path, line and kind only. The value itself
+// is never reported, so that logs cannot leak it a second time.
+package secrets
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/AlienHeadwars/repo-slice/internal/binary"
+)
+
+// Mode selects what happens to a slice that contains secrets.
+type Mode string
+
+const (
+	// ModeOff disables scanning.
+	ModeOff Mode = "off"
+	// ModeFail fails the run if any secret is found.
+	ModeFail Mode = "fail"
+	// ModeRedact replaces each secret with Redacted.
+	ModeRedact Mode = "redact"
+	// ModeExclude removes every file containing a secret from the slice.
+	ModeExclude Mode = "exclude"
+)
+
+// Valid reports whether m names a supported mode.
+func (m Mode) Valid() bool {
+	return m == ModeOff || m == ModeFail || m == ModeRedact || m == ModeExclude
+}
+
+// Redacted replaces each secret in a redacted file.
+const Redacted = "REDACTED"
+
+// Allow is a marker that, anywhere on a line, stops secrets on that line from
+// being reported. It is meant for test fixtures and documented examples.
+const Allow = "repo-slice:allow-secret"
+
+// Finding is a possible secret.
+type Finding struct {
+	Path string // Slash-separated path relative to the slice root.
+	Line int
+	Kind string // What the secret appears to be, such as "GitHub token".
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%s:%d: %s", f.Path, f.Line, f.Kind)
+}
+
+// detector finds one kind of secret. The value is the submatch at group, or
+// the whole match if group is zero, and is only a secret if valid accepts it.
+type detector struct {
+	kind  string
+	re    *regexp.Regexp
+	group int
+	valid func(value []byte) bool
+}
+
+// detectors are tried in order, and a match overlapping an earlier one is
+// ignored, so specific patterns come before the generic one.
+var detectors = []detector{
+	{
+		kind:  "private key",
+		re:    regexp.MustCompile(`(?s)-----BEGIN[ A-Z0-9]*PRIVATE KEY(?: BLOCK)?-----(.*?)(?:-----END[ A-Z0-9]*PRIVATE KEY(?: BLOCK)?-----|\z)`),
+		group: 1,
+	},
+	{
+		kind:  "AWS access key ID",
+		re:    regexp.MustCompile(`\b((?:AKIA|ASIA|AGPA|AIDA|AROA|ANPA|ANVA|AIPA)[0-9A-Z]{16})\b`),
+		group: 1,
+	},
+	{
+		kind:  "AWS secret access key",
+		re:    regexp.MustCompile(`(?i)\baws_?secret_?(?:access_?)?key\b["']?\s*[:=]+\s*["']?([A-Za-z0-9/+]{40})\b`),
+		group: 1,
+	},
+	{
+		kind:  "GitHub token",
+		re:    regexp.MustCompile(`\b((?:ghp|gho|ghu|ghs|ghr)_[A-Za-z0-9]{36,255}|github_pat_[A-Za-z0-9_]{22,255})\b`),
+		group: 1,
+	},
+	{
+		kind:  "high-entropy secret",
+		re:    regexp.MustCompile(`(?i)[\w.-]*(?:secret|token|passw(?:or)?d|api_?key|access_?key|private_?key|credential)s?[\w.-]*["']?\s*[:=]+\s*["']?([A-Za-z0-9+/=_\-.~!@#%^&*]{16,})`),
+		group: 1,
+		valid: highEntropy,
+	},
+}
+
+// envValue matches the value of an assignment in a .env file.
+var envValue = regexp.MustCompile(`(?m)^[ \t]*(?:export[ \t]+)?[A-Za-z_][A-Za-z0-9_.]*[ \t]*=[ \t]*([^\r\n]*?)[ \t]*\r?$`)
+
+// minEntropy is the least Shannon entropy, in bits per character, of a
+// high-entropy secret. Random keys score well above it, and words and
+// identifiers below it.
+const minEntropy = 3.5
+
+// highEntropy reports whether value looks randomly generated: it mixes letters
+// and digits, and its characters are spread widely enough.
+func highEntropy(value []byte) bool {
+	var letters, digits bool
+	counts := make(map[byte]int)
+	for _, c := range value {
+		counts[c]++
+		switch {
+		case c >= '0' && c <= '9':
+			digits = true
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z':
+			letters = true
+		}
+	}
+	if !letters || !digits {
+		return false
+	}
+	var entropy float64
+	for _, n := range counts {
+		p := float64(n) / float64(len(value))
+		entropy -= p * math.Log2(p)
+	}
+	return entropy >= minEntropy
+}
+
+// IsEnvFile reports whether the file at the slash-separated name is a dotenv
+// file, such as .env or .env.production. Templates such as .env.example hold
+// no real values and are not.
+func IsEnvFile(name string) bool {
+	base := path.Base(name)
+	if base == ".env" {
+		return true
+	}
+	suffix, ok := strings.CutPrefix(base, ".env.")
+	if !ok {
+		return false
+	}
+	switch suffix {
+	case "example", "sample", "template", "dist", "defaults":
+		return false
+	}
+	return true
+}
+
+// match is the location of a secret value in a file.
+type match struct {
+	start, end int
+	kind       string
+}
+
+// find returns the secrets in the file at name, in order of their position.
+func find(name string, data []byte) []match {
+	var found []match
+	overlaps := func(start, end int) bool {
+		for _, m := range found {
+			if start < m.end && m.start < end {
+				return true
+			}
+		}
+		return false
+	}
+	add := func(start, end int, kind string) {
+		if start == end || overlaps(start, end) || allowed(data, start) {
+			return
+		}
+		found = append(found, match{start, end, kind})
+	}
+
+	for _, d := range detectors {
+		for _, loc := range d.re.FindAllSubmatchIndex(data, -1) {
+			start, end := loc[2*d.group], loc[2*d.group+1]
+			if start < 0 || (d.valid != nil && !d.valid(data[start:end])) {
+				continue
+			}
+			add(start, end, d.kind)
+		}
+	}
+	if IsEnvFile(name) {
+		for _, loc := range envValue.FindAllSubmatchIndex(data, -1) {
+			start, end := loc[2], loc[3]
+			if len(bytes.Trim(data[start:end], `"'`)) == 0 {
+				continue
+			}
+			add(start, end, "environment file value")
+		}
+	}
+	sort.Slice(found, func(i, j int) bool { return found[i].start < found[j].start })
+	return found
+}
+
+// allowed reports whether the line holding offset carries the Allow marker.
+func allowed(data []byte, offset int) bool {
+	start := bytes.LastIndexByte(data[:offset], '\n') + 1
+	end := len(data)
+	if i := bytes.IndexByte(data[offset:], '\n'); i >= 0 {
+		end = offset + i
+	}
+	return bytes.Contains(data[start:end], []byte(Allow))
+}
+
+// Scan returns the possible secrets in the file at the slash-separated name.
+// Binary files are not scanned.
+func Scan(name string, data []byte) []Finding {
+	if _, isBinary := binary.Detect(name, data); isBinary {
+		return nil
+	}
+	var findings []Finding
+	for _, m := range find(name, data) {
+		line := 1 + bytes.Count(data[:m.start], []byte("\n"))
+		findings = append(findings, Finding{Path: name, Line: line, Kind: m.kind})
+	}
+	return findings
+}
+
+// Redact returns data with every secret Scan finds replaced by Redacted.
+func Redact(name string, data []byte) []byte {
+	if _, isBinary := binary.Detect(name, data); isBinary {
+		return data
+	}
+	var out []byte
+	last := 0
+	for _, m := range find(name, data) {
+		out = append(out, data[last:m.start]...)
+		if m.kind == "private key" {
+			out = append(out, "\n"+Redacted+"\n"...)
+		} else {
+			out = append(out, Redacted...)
+		}
+		last = m.end
+	}
+	return append(out, data[last:]...)
+}
+
+// Apply scans every regular file in the slice at dir and, as mode requires,
+// redacts the secrets in place or removes the files that contain them. It
+// returns what it found, in lexical order of path.
+func Apply(dir string, mode Mode) ([]Finding, error) {
+	var findings []Finding
+	fsys := os.DirFS(dir)
+	walkFn := func(name string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		found := Scan(name, data)
+		if len(found) == 0 {
+			return nil
+		}
+		findings = append(findings, found...)
+		file := filepath.Join(dir, filepath.FromSlash(name))
+		switch mode {
+		case ModeRedact:
+			return rewrite(file, Redact(name, data), d)
+		case ModeExclude:
+			return os.Remove(file)
+		}
+		return nil
+	}
+	if err := fs.WalkDir(fsys, ".", walkFn); err != nil {
+		return nil, fmt.Errorf("failed to scan slice for secrets: %w", err)
+	}
+	return findings, nil
+}
+
+// rewrite replaces the content of file, keeping its permissions even if they
+// do not allow writing.
+func rewrite(file string, data []byte, d fs.DirEntry) error {
+	info, err := d.Info()
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(file); err != nil {
+		return err
+	}
+	if err := os.WriteFile(file, data, 0600); err != nil {
+		return err
+	}
+	return os.Chmod(file, info.Mode().Perm())
+}
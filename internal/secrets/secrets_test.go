@@ -0,0 +1,136 @@
+package secrets
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// The fixtures are assembled at run time so that this file does not itself
+// look like it leaks credentials.
+var (
+	awsKeyID     = "AKIA" + "IOSFODNN7EXAMPLE"
+	awsSecret    = "wJalrXUtnFEMI/K7MDENG/" + "bPxRfiCYEXAMPLEKEY"
+	githubToken  = "ghp_" + strings.Repeat("a1B2c3D4e5", 4)
+	privateKey   = "-----BEGIN RSA " + "PRIVATE KEY-----\nMIIEowIBAAKCAQEA\nq2Fz\n-----END RSA " + "PRIVATE KEY-----\n"
+	randomSecret = "Zm9v" + "YmFy9X2kQ7pLw3rT"
+)
+
+func TestScan(t *testing.T) {
+	testCases := []struct {
+		name string
+		file string
+		data string
+		want []Finding
+	}{
+		{"Clean file", "main.go", "package main\n\nfunc main() {}\n", nil},
+		{"AWS access key ID", "config.go", "package config\n\nconst id = \"" + awsKeyID + "\"\n", []Finding{{"config.go", 3, "AWS access key ID"}}},
+		{"AWS secret access key", "credentials", "[default]\naws_secret_access_key = " + awsSecret + "\n", []Finding{{"credentials", 2, "AWS secret access key"}}},
+		{"GitHub token", "ci.sh", "#!/bin/sh\nexport GH=" + githubToken + "\n", []Finding{{"ci.sh", 2, "GitHub token"}}},
+		{"Private key", "deploy/id_rsa", "# key\n" + privateKey, []Finding{{"deploy/id_rsa", 2, "private key"}}},
+		{"High-entropy value", "app.yaml", "db:\n  password: \"" + randomSecret + "\"\n", []Finding{{"app.yaml", 2, "high-entropy secret"}}},
+		{"Low-entropy value", "app.yaml", "token_path: /var/run/secrets/token\npassword: getPasswordFromVault\n", nil},
+		{"Environment file", "web/.env", "# comment\nDATABASE_URL=postgres://u:p@db/app\nEMPTY=\nQUOTED=\"\"\n", []Finding{{"web/.env", 2, "environment file value"}}},
+		{"Environment template", "web/.env.example", "DATABASE_URL=postgres://u:p@db/app\n", nil},
+		{"Allowed line", "README.md", "Use `" + awsKeyID + "` # " + Allow + "\n", nil},
+		{"Binary file", "blob.bin", awsKeyID + "\x00", nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := Scan(tc.file, []byte(tc.data))
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("Scan() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRedact(t *testing.T) {
+	data := "id: " + awsKeyID + "\n" + privateKey + "token = \"" + githubToken + "\"\n"
+	want := "id: REDACTED\n-----BEGIN RSA " + "PRIVATE KEY-----\nREDACTED\n-----END RSA " + "PRIVATE KEY-----\ntoken = \"REDACTED\"\n"
+	if got := string(Redact("config.yaml", []byte(data))); got != want {
+		t.Errorf("Redact() = %q, want %q", got, want)
+	}
+}
+
+func TestFindingHidesValue(t *testing.T) {
+	for _, f := range Scan("config.go", []byte("const id = \""+awsKeyID+"\"\n")) {
+		if s := f.String(); strings.Contains(s, awsKeyID) || s != "config.go:1: AWS access key ID" {
+			t.Errorf("Finding.String() = %q", s)
+		}
+	}
+}
+
+func TestIsEnvFile(t *testing.T) {
+	for name, want := range map[string]bool{
+		".env": true, "api/.env.production": true, ".env.local": true,
+		".env.example": false, ".env.sample": false, "env.go": false, ".envrc": false,
+	} {
+		if got := IsEnvFile(name); got != want {
+			t.Errorf("IsEnvFile(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestApply(t *testing.T) {
+	files := map[string]string{
+		"main.go":      "package main\n",
+		"conf/app.ini": "key = " + awsKeyID + "\n",
+		".env":         "TOKEN=abc\n",
+	}
+	setup := func(t *testing.T) string {
+		dir := t.TempDir()
+		for name, data := range files {
+			p := filepath.Join(dir, filepath.FromSlash(name))
+			if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(p, []byte(data), 0444); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return dir
+	}
+	want := []Finding{{".env", 1, "environment file value"}, {"conf/app.ini", 1, "AWS access key ID"}}
+
+	t.Run("Fail", func(t *testing.T) {
+		dir := setup(t)
+		got, err := Apply(dir, ModeFail)
+		if err != nil || !reflect.DeepEqual(got, want) {
+			t.Fatalf("Apply() = %v, %v, want %v", got, err, want)
+		}
+		if data, _ := os.ReadFile(filepath.Join(dir, ".env")); string(data) != files[".env"] {
+			t.Errorf("Apply() changed .env to %q", data)
+		}
+	})
+
+	t.Run("Redact", func(t *testing.T) {
+		dir := setup(t)
+		if _, err := Apply(dir, ModeRedact); err != nil {
+			t.Fatalf("Apply() returned an error: %v", err)
+		}
+		data, _ := os.ReadFile(filepath.Join(dir, "conf", "app.ini"))
+		if string(data) != "key = REDACTED\n" {
+			t.Errorf("conf/app.ini = %q, want the key redacted", data)
+		}
+		if info, err := os.Stat(filepath.Join(dir, "conf", "app.ini")); err != nil || info.Mode().Perm() != 0444 {
+			t.Errorf("conf/app.ini mode = %v, %v, want 0444", info.Mode(), err)
+		}
+	})
+
+	t.Run("Exclude", func(t *testing.T) {
+		dir := setup(t)
+		if _, err := Apply(dir, ModeExclude); err != nil {
+			t.Fatalf("Apply() returned an error: %v", err)
+		}
+		for name, want := range map[string]bool{"main.go": true, "conf/app.ini": false, ".env": false} {
+			_, err := os.Stat(filepath.Join(dir, filepath.FromSlash(name)))
+			if exists := err == nil; exists != want {
+				t.Errorf("%s exists = %v, want %v", name, exists, want)
+			}
+		}
+	})
+}
@@ -0,0 +1,73 @@
+// file: internal/skeleton/skeleton.go
+
+// Package skeleton reduces source files to their declarations, so that an
+// assistant reasoning about the shape of a codebase can see the API of many
+// packages without spending its context on their implementations.
+package skeleton
+
+import (
+	"bytes"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+)
+
+// Go returns the Go source file with the body of every function and method
+// removed, keeping the package clause, imports, types, interfaces, constants,
+// variables, signatures and doc comments. Comments inside the removed bodies
+// are dropped with them. Function literals in variable declarations are
+// emptied rather than removed, since a literal cannot be written without a
+// body. The result is formatted as gofmt would.
+func Go(name string, src []byte) ([]byte, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, name, src, parser.ParseComments|parser.SkipObjectResolution)
+	if err != nil {
+		return nil, err
+	}
+
+	var removed []*ast.BlockStmt
+	for _, decl := range f.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if d.Body != nil {
+				removed = append(removed, d.Body)
+				d.Body = nil
+			}
+		case *ast.GenDecl:
+			ast.Inspect(d, func(n ast.Node) bool {
+				if lit, ok := n.(*ast.FuncLit); ok {
+					removed = append(removed, lit.Body)
+					lit.Body = &ast.BlockStmt{Lbrace: lit.Body.Lbrace, Rbrace: lit.Body.Lbrace + 1}
+					return false
+				}
+				return true
+			})
+		}
+	}
+
+	comments := f.Comments[:0]
+	for _, c := range f.Comments {
+		if !within(c, removed) {
+			comments = append(comments, c)
+		}
+	}
+	f.Comments = comments
+
+	var buf bytes.Buffer
+	cfg := printer.Config{Mode: printer.UseSpaces | printer.TabIndent, Tabwidth: 8}
+	if err := cfg.Fprint(&buf, fset, f); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// within reports whether the comment lies inside one of the blocks.
+func within(c *ast.CommentGroup, blocks []*ast.BlockStmt) bool {
+	for _, b := range blocks {
+		if c.Pos() > b.Lbrace && c.End() <= b.Rbrace {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,95 @@
+package skeleton
+
+import "testing"
+
+const source = `// Package shapes draws shapes.
+package shapes
+
+import (
+	"fmt"
+	"math"
+)
+
+// Pi is close enough.
+const Pi = math.Pi
+
+// Shape is anything with an area.
+type Shape interface {
+	// Area returns the area.
+	Area() float64
+}
+
+// Circle is a round shape.
+type Circle struct {
+	R float64 // Radius.
+}
+
+// Area returns the area of the circle.
+func (c Circle) Area() float64 {
+	// Squaring is cheaper than math.Pow.
+	return Pi * c.R * c.R
+}
+
+// Describe formats a shape for people.
+func Describe(s Shape) string {
+	/* Two decimal places are plenty. */
+	return fmt.Sprintf("%.2f", s.Area())
+}
+
+// Unit is the unit circle.
+var Unit = Circle{R: 1}
+
+var hook = func(s Shape) {
+	fmt.Println(s) // Debugging only.
+}
+`
+
+const want = `// Package shapes draws shapes.
+package shapes
+
+import (
+	"fmt"
+	"math"
+)
+
+// Pi is close enough.
+const Pi = math.Pi
+
+// Shape is anything with an area.
+type Shape interface {
+	// Area returns the area.
+	Area() float64
+}
+
+// Circle is a round shape.
+type Circle struct {
+	R float64 // Radius.
+}
+
+// Area returns the area of the circle.
+func (c Circle) Area() float64
+
+// Describe formats a shape for people.
+func Describe(s Shape) string
+
+// Unit is the unit circle.
+var Unit = Circle{R: 1}
+
+var hook = func(s Shape) {}
+`
+
+func TestGo(t *testing.T) {
+	got, err := Go("shapes.go", []byte(source))
+	if err != nil {
+		t.Fatalf("Go() returned an unexpected error: %v", err)
+	}
+	if string(got) != want {
+		t.Errorf("Go() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestGoInvalidSource(t *testing.T) {
+	if _, err := Go("broken.go", []byte("package broken\n\nfunc {")); err == nil {
+		t.Error("Go() did not return an error for invalid source")
+	}
+}
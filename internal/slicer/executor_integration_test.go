@@ -54,3 +54,24 @@ func TestCmdExecutorRunFails(t *testing.T) {
 		}
 	})
 }
+
+// TestCmdExecutorOutput verifies that Output returns stdout and reports
+// failures with their stderr.
+func TestCmdExecutorOutput(t *testing.T) {
+	executor := CmdExecutor{}
+
+	out, err := executor.Output(".", "sh", "-c", "printf 'a\\000b'")
+	if err != nil {
+		t.Fatalf("CmdExecutor.Output() returned an unexpected error: %v", err)
+	}
+	if string(out) != "a\x00b" {
+		t.Errorf("CmdExecutor.Output() = %q, want %q", out, "a\x00b")
+	}
+
+	if _, err := executor.Output(".", "sh", "-c", "echo oops >&2; exit 3"); err == nil || !strings.Contains(err.Error(), "oops") {
+		t.Errorf("CmdExecutor.Output() error = %v, want one including stderr", err)
+	}
+	if _, err := executor.Output(".", "sh", "-c", "echo warning >&2"); err == nil {
+		t.Error("CmdExecutor.Output() did not return an error for stderr output")
+	}
+}
@@ -0,0 +1,120 @@
+// file: internal/slicer/explain.go
+package slicer
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/AlienHeadwars/repo-slice/internal/deps"
+	"github.com/AlienHeadwars/repo-slice/internal/filter"
+)
+
+// Explain reports which manifest rule decides the fate of each path. When no
+// paths are given, every path in the source tree is explained, including the
+// contents of directories the manifest excludes, so that files lost to an
+// excluded parent can be found. Directories removed by a prefilter, such as
+// ignored build output, are reported but not descended into.
+func Explain(opts Options, paths []string, exec Executor) ([]filter.Decision, error) {
+	rules, err := loadRules(opts)
+	if err != nil {
+		return nil, err
+	}
+	src, err := openSource(opts, exec)
+	if err != nil {
+		return nil, err
+	}
+	if err := deps.Resolve(rules, src.fsys); err != nil {
+		return nil, err
+	}
+	pre, err := prefilter(opts, src, exec)
+	if err != nil {
+		return nil, err
+	}
+	return explainPaths(src.fsys, rules, pre, paths)
+}
+
+func explainPaths(src fs.FS, rules filter.Rules, pre Prefilter, paths []string) ([]filter.Decision, error) {
+	var decisions []filter.Decision
+	if len(paths) == 0 {
+		walkFn := func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if p == "." {
+				return nil
+			}
+			decision, prefiltered, err := explainPath(rules, pre, p, d.IsDir())
+			if err != nil {
+				return err
+			}
+			decisions = append(decisions, decision)
+			if prefiltered && d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if err := fs.WalkDir(src, ".", walkFn); err != nil {
+			return nil, fmt.Errorf("failed to walk source: %w", err)
+		}
+		return decisions, nil
+	}
+
+	for _, p := range paths {
+		name := cleanPath(p)
+		if name == "." {
+			return nil, fmt.Errorf("cannot explain the source root")
+		}
+		// Paths that do not exist are still explained, so a manifest can be
+		// checked for files that have not been written yet. A trailing slash
+		// marks such a path as a directory.
+		isDir := strings.HasSuffix(filepath.ToSlash(p), "/")
+		if info, err := fs.Stat(src, name); err == nil {
+			isDir = info.IsDir()
+		}
+		decision, _, err := explainPath(rules, pre, name, isDir)
+		if err != nil {
+			return nil, err
+		}
+		decisions = append(decisions, decision)
+	}
+	return decisions, nil
+}
+
+// explainPath explains a single path and reports whether the prefilter
+// decided it. The prefilter is applied first, to the path's ancestors and then
+// to the path itself, because it removes candidates before the manifest is
+// consulted.
+func explainPath(rules filter.Rules, pre Prefilter, name string, isDir bool) (filter.Decision, bool, error) {
+	if pre != nil {
+		parts := strings.Split(name, "/")
+		for i := 1; i <= len(parts); i++ {
+			p := strings.Join(parts[:i], "/")
+			rule, err := pre.Excluded(p, isDir || i < len(parts))
+			if err != nil {
+				return filter.Decision{}, false, err
+			}
+			if rule == nil {
+				continue
+			}
+			d := filter.Decision{Path: name, IsDir: isDir, Rule: rule}
+			if i < len(parts) {
+				d.Parent = p
+			}
+			return d, true, nil
+		}
+	}
+	return rules.Explain(name, isDir), false, nil
+}
+
+// cleanPath converts a user-supplied path into the slash-separated form,
+// relative to the source root, that rules are matched against.
+func cleanPath(p string) string {
+	name := path.Clean("/" + filepath.ToSlash(p))
+	if name == "/" {
+		return "."
+	}
+	return strings.TrimPrefix(name, "/")
+}
@@ -0,0 +1,101 @@
+package slicer
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/AlienHeadwars/repo-slice/internal/filter"
+)
+
+func TestExplainPaths(t *testing.T) {
+	src := fstest.MapFS{
+		"main.go":        {Data: []byte("package main")},
+		"src/app/app.go": {Data: []byte("package app")},
+	}
+	rules := filter.Rules{
+		filter.NewRule(filter.Include, "*.go"),
+		filter.NewRule(filter.Exclude, "*"),
+	}
+
+	t.Run("explains every path when none are given", func(t *testing.T) {
+		decisions, err := explainPaths(src, rules, nil, nil)
+		if err != nil {
+			t.Fatalf("explainPaths() returned an unexpected error: %v", err)
+		}
+		var got []string
+		for _, d := range decisions {
+			got = append(got, d.Path)
+		}
+		want := "main.go src src/app src/app/app.go"
+		if strings.Join(got, " ") != want {
+			t.Errorf("explainPaths() paths = %v, want %s", got, want)
+		}
+		if decisions[3].Parent != "src" {
+			t.Errorf("nested file should be blocked by src, got %+v", decisions[3])
+		}
+	})
+
+	t.Run("explains the given paths", func(t *testing.T) {
+		decisions, err := explainPaths(src, rules, nil, []string{"./src/", "missing.go", "new/"})
+		if err != nil {
+			t.Fatalf("explainPaths() returned an unexpected error: %v", err)
+		}
+		if len(decisions) != 3 {
+			t.Fatalf("explainPaths() returned %d decisions, want 3", len(decisions))
+		}
+		if decisions[0].Path != "src" || !decisions[0].IsDir || decisions[0].Included {
+			t.Errorf("src decision = %+v, want an excluded directory", decisions[0])
+		}
+		if decisions[1].IsDir || !decisions[1].Included {
+			t.Errorf("missing.go decision = %+v, want an included file", decisions[1])
+		}
+		if !decisions[2].IsDir {
+			t.Errorf("new/ decision = %+v, want a directory", decisions[2])
+		}
+	})
+
+	t.Run("rejects the source root", func(t *testing.T) {
+		if _, err := explainPaths(src, rules, nil, []string{"."}); err == nil {
+			t.Error("explainPaths() did not return an error for the source root")
+		}
+	})
+}
+
+func TestExplainPathsWithPrefilter(t *testing.T) {
+	src := fstest.MapFS{
+		".gitignore":            {Data: []byte("dist/\n")},
+		"main.go":               {Data: []byte("package main")},
+		"dist/bundle/app.js":    {Data: []byte("bundle")},
+		"dist/bundle/vendor.js": {Data: []byte("vendor")},
+	}
+	rules := filter.Rules{filter.NewRule(filter.Include, "**")}
+	pre := filter.NewGitIgnore(src, ".")
+
+	t.Run("does not descend into prefiltered directories", func(t *testing.T) {
+		decisions, err := explainPaths(src, rules, pre, nil)
+		if err != nil {
+			t.Fatalf("explainPaths() returned an unexpected error: %v", err)
+		}
+		var got []string
+		for _, d := range decisions {
+			got = append(got, d.Path)
+		}
+		if want := ".gitignore dist main.go"; strings.Join(got, " ") != want {
+			t.Errorf("explainPaths() paths = %v, want %s", got, want)
+		}
+		if decisions[1].Included || decisions[1].Rule.Location() != ".gitignore:1" {
+			t.Errorf("dist decision = %+v, want it ignored by .gitignore:1", decisions[1])
+		}
+	})
+
+	t.Run("reports a prefiltered parent", func(t *testing.T) {
+		decisions, err := explainPaths(src, rules, pre, []string{"dist/bundle/app.js"})
+		if err != nil {
+			t.Fatalf("explainPaths() returned an unexpected error: %v", err)
+		}
+		if d := decisions[0]; d.Included || d.Parent != "dist" || d.Own != nil {
+			t.Errorf("decision = %+v, want excluded by the ignored dist parent", d)
+		}
+	})
+}
@@ -0,0 +1,586 @@
+// file: internal/slicer/native.go
+package slicer
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/AlienHeadwars/repo-slice/internal/binary"
+	"github.com/AlienHeadwars/repo-slice/internal/cache"
+	"github.com/AlienHeadwars/repo-slice/internal/deps"
+	"github.com/AlienHeadwars/repo-slice/internal/filter"
+	"github.com/AlienHeadwars/repo-slice/internal/gitrepo"
+	"github.com/AlienHeadwars/repo-slice/internal/skeleton"
+	"github.com/AlienHeadwars/repo-slice/internal/truncate"
+)
+
+// Entry describes a single file, directory or symlink selected for a slice.
+type Entry struct {
+	Path    string // Slash-separated path relative to the source root.
+	Mode    fs.FileMode
+	Size    int64
+	ModTime time.Time
+	// Binary is the type of a file detected as binary, or "" if it is text or
+	// binary files are kept without being detected.
+	Binary string
+	// Oversized marks a file over the per-file size limits.
+	Oversized bool
+	// Skip leaves the file out of the slice. It is still listed, so that a dry
+	// run can report it.
+	Skip bool
+	// Stub replaces a binary file with a text file describing it, named with
+	// binary.StubSuffix appended.
+	Stub bool
+	// Transform names the manifest transform applied to the file, such as
+	// filter.TransformGoAPI, or is "" if its content is copied as it is.
+	Transform string
+	// Content, when not nil, is written to the slice in place of the file's
+	// own content: the description of a stub, the output of a transform, or
+	// the start of an oversized file.
+	Content []byte
+	// Hash is the hex-encoded SHA-256 hash of the file in the source, set
+	// when slicing incrementally.
+	Hash string
+	// Reused marks a file whose copy from a previous slice is still in the
+	// output, unchanged, so it is not copied again.
+	Reused bool
+}
+
+// IsDir reports whether the entry is a directory.
+func (e Entry) IsDir() bool { return e.Mode.IsDir() }
+
+// SlicePath returns the slash-separated path the entry has in the slice,
+// which differs from its path in the source when the entry is a stub.
+func (e Entry) SlicePath() string {
+	if e.Stub {
+		return e.Path + binary.StubSuffix
+	}
+	return e.Path
+}
+
+// LinkReader is implemented by source file systems that can report the target
+// of a symbolic link, which is needed to reproduce links the way rsync's
+// archive mode does.
+type LinkReader interface {
+	ReadLink(name string) (string, error)
+}
+
+// DirFS is an fs.FS rooted at a directory on the local disk that can also read
+// symbolic links.
+type DirFS struct {
+	fs.FS
+	root string
+}
+
+// NewDirFS returns a DirFS for the directory at root.
+func NewDirFS(root string) DirFS {
+	return DirFS{FS: os.DirFS(root), root: root}
+}
+
+// ReadLink returns the destination of the named symbolic link.
+func (d DirFS) ReadLink(name string) (string, error) {
+	return os.Readlink(filepath.Join(d.root, filepath.FromSlash(name)))
+}
+
+// Prefilter narrows the candidate paths of a slice before the manifest rules
+// are applied.
+type Prefilter interface {
+	// Excluded returns the rule that removes the path from the candidates, or
+	// nil if the path remains a candidate.
+	Excluded(name string, isDir bool) (*filter.Rule, error)
+}
+
+// Select walks src and returns the entries that the prefilter, if any, and
+// then the rules keep, in lexical order. As with rsync, an excluded directory
+// is never descended into, so its contents are dropped even if a later rule
+// would include them.
+func Select(src fs.FS, rules filter.Rules, pre Prefilter) ([]Entry, error) {
+	var entries []Entry
+	walkFn := func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == "." {
+			return nil
+		}
+		included := true
+		if pre != nil {
+			rule, err := pre.Excluded(path, d.IsDir())
+			if err != nil {
+				return err
+			}
+			included = rule == nil
+		}
+		if !included || !rules.Included(path, d.IsDir()) {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if !d.IsDir() && !d.Type().IsRegular() && d.Type()&fs.ModeSymlink == 0 {
+			return nil // Devices, sockets and pipes have no place in a slice.
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		entries = append(entries, Entry{Path: path, Mode: info.Mode(), Size: info.Size(), ModTime: info.ModTime()})
+		return nil
+	}
+
+	if err := fs.WalkDir(src, ".", walkFn); err != nil {
+		return nil, fmt.Errorf("failed to walk source: %w", err)
+	}
+	return entries, nil
+}
+
+// Copy recreates the selected entries from src beneath output, preserving
+// permissions and modification times in the same way as rsync's archive mode.
+func Copy(src fs.FS, output string, entries []Entry) error {
+	if err := os.MkdirAll(output, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	for _, e := range entries {
+		if e.Skip || e.Reused {
+			continue
+		}
+		dst := filepath.Join(output, filepath.FromSlash(e.SlicePath()))
+		var err error
+		switch {
+		case e.IsDir():
+			err = makeDir(dst, e.Mode.Perm()|0700)
+		case e.Mode&fs.ModeSymlink != 0:
+			err = copyLink(src, e, dst)
+		default:
+			err = copyFile(src, e, dst)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to copy %s: %w", e.Path, err)
+		}
+	}
+
+	// Directory times are restored last, and deepest first, because creating
+	// their contents updates them.
+	for i := len(entries) - 1; i >= 0; i-- {
+		e := entries[i]
+		if !e.IsDir() {
+			continue
+		}
+		dst := filepath.Join(output, filepath.FromSlash(e.Path))
+		if err := os.Chmod(dst, e.Mode.Perm()); err != nil {
+			return fmt.Errorf("failed to set permissions on %s: %w", e.Path, err)
+		}
+		if err := os.Chtimes(dst, e.ModTime, e.ModTime); err != nil {
+			return fmt.Errorf("failed to set times on %s: %w", e.Path, err)
+		}
+	}
+	return nil
+}
+
+func copyFile(src fs.FS, e Entry, dst string) error {
+	var in io.Reader = bytes.NewReader(e.Content)
+	if e.Content == nil {
+		f, err := src.Open(e.Path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+
+	// Removing any previous copy first means read-only files from an earlier
+	// slice can be replaced.
+	if err := removeEntry(dst); err != nil {
+		return err
+	}
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	perm := e.Mode.Perm()
+	if e.Stub {
+		perm &^= 0111 // A stub is never executable.
+	}
+	if err := os.Chmod(dst, perm); err != nil {
+		return err
+	}
+	return os.Chtimes(dst, e.ModTime, e.ModTime)
+}
+
+func copyLink(src fs.FS, e Entry, dst string) error {
+	lr, ok := src.(LinkReader)
+	if !ok {
+		return fmt.Errorf("source cannot read symbolic links")
+	}
+	target, err := lr.ReadLink(e.Path)
+	if err != nil {
+		return err
+	}
+	if err := removeEntry(dst); err != nil {
+		return err
+	}
+	return os.Symlink(target, dst)
+}
+
+// makeDir creates the directory dst, replacing a file or link an earlier
+// slice left at the same path.
+func makeDir(dst string, perm fs.FileMode) error {
+	if info, err := os.Lstat(dst); err == nil && !info.IsDir() {
+		if err := os.Remove(dst); err != nil {
+			return err
+		}
+	}
+	return os.MkdirAll(dst, perm)
+}
+
+// removeEntry removes whatever an earlier slice left at dst so a file or link
+// can take its place, including a directory and everything beneath it.
+func removeEntry(dst string) error {
+	info, err := os.Lstat(dst)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return os.RemoveAll(dst)
+	}
+	return os.Remove(dst)
+}
+
+// List evaluates the manifest against the source directory and returns the
+// entries a slice would contain, including any it skips, without writing
+// anything. The native engine is always used, as it applies the same rules
+// rsync would. The executor runs git when the source is a commit or is
+// filtered using git.
+func List(opts Options, exec Executor) ([]Entry, error) {
+	_, entries, err := selectSource(opts, exec)
+	return entries, err
+}
+
+// selectSource opens the source described by opts and selects its entries.
+func selectSource(opts Options, exec Executor) (fs.FS, []Entry, error) {
+	if opts.Binary != "" && !opts.Binary.Valid() {
+		return nil, nil, fmt.Errorf("unknown binary file mode: %q", opts.Binary)
+	}
+	if opts.Oversized != "" && !opts.Oversized.Valid() {
+		return nil, nil, fmt.Errorf("unknown oversized file mode: %q", opts.Oversized)
+	}
+	if opts.MaxFileSize < 0 || opts.MaxFileLines < 0 {
+		return nil, nil, fmt.Errorf("per-file limits must not be negative")
+	}
+	rules, err := loadRules(opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	src, err := openSource(opts, exec)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := deps.Resolve(rules, src.fsys); err != nil {
+		return nil, nil, err
+	}
+	pre, err := prefilter(opts, src, exec)
+	if err != nil {
+		return nil, nil, err
+	}
+	entries, err := Select(src.fsys, rules, pre)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := transformFiles(src.fsys, entries, rules); err != nil {
+		return nil, nil, err
+	}
+	if err := detectBinary(src.fsys, entries, opts.Binary); err != nil {
+		return nil, nil, err
+	}
+	limits := truncate.Limits{MaxBytes: opts.MaxFileSize, MaxLines: opts.MaxFileLines}
+	if err := limitFiles(src.fsys, entries, limits, opts.Oversized); err != nil {
+		return nil, nil, err
+	}
+	return src.fsys, entries, nil
+}
+
+// transformFiles applies the transform of the rule that includes each file,
+// recording the result as the entry's content. Files a transform does not
+// apply to, such as non-Go files or Go files that do not parse under goapi,
+// are copied as they are.
+func transformFiles(src fs.FS, entries []Entry, rules filter.Rules) error {
+	if !rules.Transformed() {
+		return nil
+	}
+	for i := range entries {
+		e := &entries[i]
+		if !e.Mode.IsRegular() {
+			continue
+		}
+		transform := rules.Transform(e.Path)
+		if transform != filter.TransformGoAPI || !strings.HasSuffix(e.Path, ".go") {
+			continue
+		}
+		data, err := fs.ReadFile(src, e.Path)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", e.Path, err)
+		}
+		content, err := skeleton.Go(e.Path, data)
+		if err != nil {
+			continue
+		}
+		e.Transform, e.Content = transform, content
+	}
+	return nil
+}
+
+// limitFiles finds the entries over the per-file limits and marks them to be
+// skipped or truncated as mode requires. Binary files cannot be cut short in
+// a meaningful way, so they are skipped in either mode. Files are only read
+// when their size alone cannot decide.
+func limitFiles(src fs.FS, entries []Entry, limits truncate.Limits, mode truncate.Mode) error {
+	if limits.IsZero() {
+		return nil
+	}
+	for i := range entries {
+		e := &entries[i]
+		if !e.Mode.IsRegular() || e.Skip || e.Stub {
+			continue
+		}
+		size, data := e.Size, e.Content
+		if data != nil {
+			size = int64(len(data))
+		}
+		tooBig := limits.MaxBytes > 0 && size > limits.MaxBytes
+		if !tooBig && limits.MaxLines <= 0 {
+			continue
+		}
+		if tooBig && mode != truncate.ModeTruncate {
+			e.Oversized, e.Skip = true, true
+			continue
+		}
+		if data == nil {
+			var err error
+			if data, err = fs.ReadFile(src, e.Path); err != nil {
+				return fmt.Errorf("failed to read %s: %w", e.Path, err)
+			}
+		}
+		content, cut := truncate.Truncate(e.Path, data, limits)
+		if !cut {
+			continue
+		}
+		e.Oversized = true
+		if _, isBinary := binary.Detect(e.Path, data); isBinary || mode != truncate.ModeTruncate {
+			e.Skip = true
+			continue
+		}
+		e.Content = content
+	}
+	return nil
+}
+
+// detectBinary finds the binary files among entries and marks them to be
+// skipped or stubbed as mode requires. Nothing is read when binary files are
+// kept.
+func detectBinary(src fs.FS, entries []Entry, mode binary.Mode) error {
+	if mode == "" || mode == binary.ModeKeep {
+		return nil
+	}
+	for i := range entries {
+		e := &entries[i]
+		if !e.Mode.IsRegular() || e.Transform != "" {
+			continue
+		}
+		head, err := readHead(src, e.Path, binary.SniffLen)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", e.Path, err)
+		}
+		kind, ok := binary.Detect(e.Path, head)
+		if !ok {
+			continue
+		}
+		e.Binary = kind
+		if mode == binary.ModeSkip {
+			e.Skip = true
+		} else {
+			e.Stub, e.Content = true, binary.Stub(e.Path, kind, e.Size)
+		}
+	}
+	return nil
+}
+
+// readHead returns up to the first n bytes of the named file.
+func readHead(src fs.FS, name string, n int) ([]byte, error) {
+	f, err := src.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	head := make([]byte, n)
+	m, err := io.ReadFull(f, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, err
+	}
+	return head[:m], nil
+}
+
+// source is the tree a slice is read from.
+type source struct {
+	fsys   fs.FS  // The slice source.
+	repo   fs.FS  // For a commit, its whole tree, which holds the ignore files.
+	prefix string // For a commit, the location of the slice source in repo.
+}
+
+// openSource returns the source directory, or with opts.Ref set, the same
+// directory in the tree of that commit.
+func openSource(opts Options, exec Executor) (source, error) {
+	if opts.Ref == "" {
+		return source{fsys: NewDirFS(opts.Source)}, nil
+	}
+	tree, err := gitrepo.OpenTree(opts.Source, opts.Ref, exec)
+	if err != nil {
+		return source{}, err
+	}
+	prefix, err := gitrepo.Prefix(opts.Source, exec)
+	if err != nil {
+		return source{}, err
+	}
+	sub, err := tree.Sub(prefix)
+	if err != nil {
+		return source{}, fmt.Errorf("%s does not exist at %s: %w", prefix, opts.Ref, err)
+	}
+	return source{fsys: sub, repo: tree, prefix: prefix}, nil
+}
+
+// loadRules reads the manifest named in opts.
+func loadRules(opts Options) (filter.Rules, error) {
+	return filter.Load(opts.ManifestPath, opts.Source, opts.ManifestFormat, filter.LiveFS{})
+}
+
+// prefilter returns the prefilters selected in opts combined into one, or nil
+// if every file in the source is a candidate. Every file in a commit is
+// tracked, so TrackedOnly has no effect on a commit, and its ignore files are
+// read from the commit's tree.
+func prefilter(opts Options, src source, exec Executor) (Prefilter, error) {
+	var chain prefilters
+	if opts.TrackedOnly && src.repo == nil {
+		tracked, err := trackedFiles(opts.Source, exec)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, tracked)
+	}
+	if opts.Gitignore {
+		repo, prefix := src.repo, src.prefix
+		if repo == nil {
+			root, rel, err := findWorkTree(opts.Source)
+			if err != nil {
+				return nil, err
+			}
+			repo, prefix = os.DirFS(root), rel
+		}
+		chain = append(chain, filter.NewGitIgnore(repo, prefix))
+	}
+	if len(chain) == 0 {
+		return nil, nil
+	}
+	return chain, nil
+}
+
+// prefilters applies several prefilters in turn; the first to exclude a path
+// decides.
+type prefilters []Prefilter
+
+func (ps prefilters) Excluded(name string, isDir bool) (*filter.Rule, error) {
+	for _, p := range ps {
+		if rule, err := p.Excluded(name, isDir); rule != nil || err != nil {
+			return rule, err
+		}
+	}
+	return nil, nil
+}
+
+// findWorkTree returns the root of the git working tree containing dir and the
+// slash-separated path of dir within it. A directory outside any repository is
+// treated as the root of its own working tree, so its .gitignore files still
+// apply.
+func findWorkTree(dir string) (root, prefix string, err error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to resolve source path: %w", err)
+	}
+	for root = abs; ; {
+		if _, err := os.Lstat(filepath.Join(root, ".git")); err == nil {
+			rel, err := filepath.Rel(root, abs)
+			if err != nil {
+				return "", "", err
+			}
+			return root, filepath.ToSlash(rel), nil
+		}
+		parent := filepath.Dir(root)
+		if parent == root {
+			return abs, ".", nil
+		}
+		root = parent
+	}
+}
+
+// sliceNative evaluates the manifest in-process, copies the selection and
+// returns the entries it selected.
+func sliceNative(opts Options, exec Executor) ([]Entry, error) {
+	src, entries, err := selectSource(opts, exec)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Cache != nil {
+		if err := reuseFiles(src, opts.Output, entries, opts.Cache); err != nil {
+			return nil, err
+		}
+	}
+	return entries, Copy(src, opts.Output, entries)
+}
+
+// reuseFiles hashes the regular files among entries and marks those that the
+// cache shows are already in the output, unchanged, as reused. A file whose
+// size and modification time match the cache is taken to be unchanged without
+// reading it.
+func reuseFiles(src fs.FS, output string, entries []Entry, c *cache.Cache) error {
+	for i := range entries {
+		e := &entries[i]
+		if e.Skip || !e.Mode.IsRegular() {
+			continue
+		}
+		f, ok := c.Lookup(e.Path)
+		if ok && f.Size == e.Size && f.ModTime.Equal(e.ModTime) {
+			e.Hash = f.SHA256
+		} else {
+			var err error
+			if e.Hash, err = hashFile(src, e.Path); err != nil {
+				return fmt.Errorf("failed to hash %s: %w", e.Path, err)
+			}
+		}
+		e.Reused = ok && e.Hash == f.SHA256 && c.Unchanged(output, f)
+	}
+	return nil
+}
+
+func hashFile(src fs.FS, name string) (string, error) {
+	f, err := src.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	return cache.Hash(f)
+}
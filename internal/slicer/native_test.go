@@ -0,0 +1,342 @@
+package slicer
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/AlienHeadwars/repo-slice/internal/binary"
+	"github.com/AlienHeadwars/repo-slice/internal/cache"
+	"github.com/AlienHeadwars/repo-slice/internal/filter"
+	"github.com/AlienHeadwars/repo-slice/internal/truncate"
+)
+
+func TestSelect(t *testing.T) {
+	src := fstest.MapFS{
+		"README.md":           {Data: []byte("readme")},
+		"main.go":             {Data: []byte("package main")},
+		"docs/guide.md":       {Data: []byte("guide")},
+		"src/app/app.go":      {Data: []byte("package app")},
+		"src/app/app_test.go": {Data: []byte("package app")},
+	}
+
+	testCases := []struct {
+		name  string
+		rules filter.Rules
+		want  []string
+	}{
+		{
+			name:  "No rules selects everything",
+			rules: nil,
+			want:  []string{"README.md", "docs", "docs/guide.md", "main.go", "src", "src/app", "src/app/app.go", "src/app/app_test.go"},
+		},
+		{
+			name: "Excluded directories are not traversed",
+			rules: filter.Rules{
+				filter.NewRule(filter.Include, "*.go"),
+				filter.NewRule(filter.Exclude, "*"),
+			},
+			want: []string{"main.go"},
+		},
+		{
+			name: "Directory traversal rule reaches nested files",
+			rules: filter.Rules{
+				filter.NewRule(filter.Exclude, "*_test.go"),
+				filter.NewRule(filter.Include, "*.go"),
+				filter.NewRule(filter.Include, "**/"),
+				filter.NewRule(filter.Exclude, "*"),
+			},
+			want: []string{"docs", "main.go", "src", "src/app", "src/app/app.go"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			entries, err := Select(src, tc.rules, nil)
+			if err != nil {
+				t.Fatalf("Select() returned an unexpected error: %v", err)
+			}
+			var got []string
+			for _, e := range entries {
+				got = append(got, e.Path)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("Select() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSelectRecordsSizes(t *testing.T) {
+	src := fstest.MapFS{"main.go": {Data: []byte("package main")}}
+	entries, err := Select(src, nil, nil)
+	if err != nil {
+		t.Fatalf("Select() returned an unexpected error: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Size != int64(len("package main")) || entries[0].IsDir() {
+		t.Errorf("Select() = %+v, want a single 12-byte file", entries)
+	}
+}
+
+func TestSelectAppliesPrefilter(t *testing.T) {
+	src := fstest.MapFS{
+		".gitignore":                  {Data: []byte("node_modules/\n*.log\n")},
+		".git/HEAD":                   {Data: []byte("ref: refs/heads/main\n")},
+		"main.go":                     {Data: []byte("package main")},
+		"trace.log":                   {Data: []byte("log")},
+		"web/node_modules/x/index.js": {Data: []byte("module")},
+		"web/app.js":                  {Data: []byte("app")},
+	}
+	rules := filter.Rules{filter.NewRule(filter.Exclude, "/.gitignore")}
+
+	entries, err := Select(src, rules, filter.NewGitIgnore(src, "."))
+	if err != nil {
+		t.Fatalf("Select() returned an unexpected error: %v", err)
+	}
+	var got []string
+	for _, e := range entries {
+		got = append(got, e.Path)
+	}
+	want := []string{"main.go", "web", "web/app.js"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Select() = %v, want %v", got, want)
+	}
+}
+
+func TestFindWorkTree(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, ".git"), 0755); err != nil {
+		t.Fatalf("failed to create .git: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(root, "web", "app"), 0755); err != nil {
+		t.Fatalf("failed to create source: %v", err)
+	}
+
+	gotRoot, prefix, err := findWorkTree(filepath.Join(root, "web", "app"))
+	if err != nil {
+		t.Fatalf("findWorkTree() returned an unexpected error: %v", err)
+	}
+	if gotRoot != root || prefix != "web/app" {
+		t.Errorf("findWorkTree() = %q, %q, want %q, %q", gotRoot, prefix, root, "web/app")
+	}
+
+	gotRoot, prefix, err = findWorkTree(root)
+	if err != nil || gotRoot != root || prefix != "." {
+		t.Errorf("findWorkTree() at the root = %q, %q, %v", gotRoot, prefix, err)
+	}
+}
+
+func TestDetectBinary(t *testing.T) {
+	src := fstest.MapFS{
+		"main.go":         {Data: []byte("package main")},
+		"assets/logo.png": {Data: []byte("\x89PNG\r\n")},
+		"data.bin":        {Data: []byte("a\x00b")},
+		"tool":            {Data: []byte("\x7fELF\x00"), Mode: 0755},
+	}
+
+	testCases := []struct {
+		mode     binary.Mode
+		wantSkip []string
+		wantStub []string
+	}{
+		{binary.ModeKeep, nil, nil},
+		{binary.ModeSkip, []string{"assets/logo.png", "data.bin", "tool"}, nil},
+		{binary.ModeStub, nil, []string{"assets/logo.png.txt", "data.bin.txt", "tool.txt"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(string(tc.mode), func(t *testing.T) {
+			entries, err := Select(src, nil, nil)
+			if err != nil {
+				t.Fatalf("Select() returned an unexpected error: %v", err)
+			}
+			if err := detectBinary(src, entries, tc.mode); err != nil {
+				t.Fatalf("detectBinary() returned an unexpected error: %v", err)
+			}
+			var skipped, stubbed []string
+			for _, e := range entries {
+				if e.Skip {
+					skipped = append(skipped, e.Path)
+				}
+				if e.Stub {
+					stubbed = append(stubbed, e.SlicePath())
+				}
+			}
+			if !reflect.DeepEqual(skipped, tc.wantSkip) || !reflect.DeepEqual(stubbed, tc.wantStub) {
+				t.Errorf("skipped %v and stubbed %v, want %v and %v", skipped, stubbed, tc.wantSkip, tc.wantStub)
+			}
+		})
+	}
+}
+
+func TestCopyWritesStubs(t *testing.T) {
+	src := fstest.MapFS{
+		"logo.png": {Data: []byte("\x89PNG\r\n")},
+		"skip.png": {Data: []byte("\x89PNG\r\n")},
+	}
+	stub := binary.Stub("logo.png", "PNG image", 6)
+	entries := []Entry{
+		{Path: "logo.png", Mode: 0755, Size: 6, Binary: "PNG image", Stub: true, Content: stub},
+		{Path: "skip.png", Mode: 0644, Size: 6, Binary: "PNG image", Skip: true},
+	}
+	output := t.TempDir()
+	if err := Copy(src, output, entries); err != nil {
+		t.Fatalf("Copy() returned an unexpected error: %v", err)
+	}
+
+	got, err := os.ReadFile(filepath.Join(output, "logo.png.txt"))
+	if err != nil || string(got) != string(stub) {
+		t.Errorf("stub = %q, %v, want %q", got, err, stub)
+	}
+	if info, err := os.Stat(filepath.Join(output, "logo.png.txt")); err == nil && info.Mode().Perm()&0111 != 0 {
+		t.Errorf("stub mode = %v, want it not to be executable", info.Mode())
+	}
+	for _, name := range []string{"logo.png", "skip.png"} {
+		if _, err := os.Lstat(filepath.Join(output, name)); !os.IsNotExist(err) {
+			t.Errorf("%s was copied to the slice", name)
+		}
+	}
+}
+
+func TestLimitFiles(t *testing.T) {
+	src := fstest.MapFS{
+		"small.go":     {Data: []byte("package a\n")},
+		"big.json":     {Data: []byte("[\n1,\n2,\n3\n]\n")},
+		"long.py":      {Data: []byte("a\nb\nc\nd\n")},
+		"blob":         {Data: []byte("a\x00b\x00c\x00d\x00e\x00f\x00")},
+		"logo.png":     {Data: []byte("0123456789abcdef")},
+		"assets/a.txt": {Data: []byte("fits\n")},
+	}
+
+	testCases := []struct {
+		name          string
+		limits        truncate.Limits
+		mode          truncate.Mode
+		wantSkip      []string
+		wantTruncated map[string]string
+	}{
+		{"No limits", truncate.Limits{}, truncate.ModeTruncate, nil, nil},
+		{"Skip by size", truncate.Limits{MaxBytes: 10}, truncate.ModeSkip, []string{"big.json", "blob", "logo.png"}, nil},
+		{"Skip by lines", truncate.Limits{MaxLines: 3}, "", []string{"big.json", "long.py"}, nil},
+		{
+			"Truncate", truncate.Limits{MaxBytes: 10, MaxLines: 3}, truncate.ModeTruncate,
+			[]string{"blob", "logo.png"},
+			map[string]string{
+				"big.json": "[\n1,\n2,\n[repo-slice: truncated, showing the first 8 of 12 bytes]\n",
+				"long.py":  "a\nb\nc\n# repo-slice: truncated, showing the first 6 of 8 bytes\n",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			entries, err := Select(src, nil, nil)
+			if err != nil {
+				t.Fatalf("Select() returned an unexpected error: %v", err)
+			}
+			if err := limitFiles(src, entries, tc.limits, tc.mode); err != nil {
+				t.Fatalf("limitFiles() returned an unexpected error: %v", err)
+			}
+			var skipped []string
+			truncated := map[string]string{}
+			for _, e := range entries {
+				if e.Skip && !e.Oversized {
+					t.Errorf("%s is skipped but not oversized", e.Path)
+				}
+				if e.Skip {
+					skipped = append(skipped, e.Path)
+				} else if e.Content != nil {
+					truncated[e.Path] = string(e.Content)
+				}
+			}
+			if tc.wantTruncated == nil {
+				tc.wantTruncated = map[string]string{}
+			}
+			if !reflect.DeepEqual(skipped, tc.wantSkip) || !reflect.DeepEqual(truncated, tc.wantTruncated) {
+				t.Errorf("skipped %v and truncated %q, want %v and %q", skipped, truncated, tc.wantSkip, tc.wantTruncated)
+			}
+		})
+	}
+}
+
+func TestTransformFiles(t *testing.T) {
+	src := fstest.MapFS{
+		"api/api.go":      {Data: []byte("package api\n\n// Run runs.\nfunc Run() {\n\tpanic(1)\n}\n")},
+		"api/README.md":   {Data: []byte("readme")},
+		"api/broken.go":   {Data: []byte("package {")},
+		"cmd/main.go":     {Data: []byte("package main\n\nfunc main() {}\n")},
+		"cmd/generate.go": {Data: []byte("package main\n")},
+	}
+	api := filter.NewRule(filter.Include, "/api/**")
+	api.Transform = filter.TransformGoAPI
+	rules := filter.Rules{api, filter.NewRule(filter.Include, "**")}
+
+	entries, err := Select(src, rules, nil)
+	if err != nil {
+		t.Fatalf("Select() returned an unexpected error: %v", err)
+	}
+	if err := transformFiles(src, entries, rules); err != nil {
+		t.Fatalf("transformFiles() returned an unexpected error: %v", err)
+	}
+	got := make(map[string]string)
+	for _, e := range entries {
+		if e.Transform != "" {
+			got[e.Path] = e.Transform + ": " + string(e.Content)
+		}
+	}
+	want := map[string]string{"api/api.go": "goapi: package api\n\n// Run runs.\nfunc Run()\n"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("transformed files = %q, want %q", got, want)
+	}
+}
+
+func TestReuseFiles(t *testing.T) {
+	then := time.Unix(1700000000, 0)
+	later := then.Add(time.Hour)
+	src := fstest.MapFS{
+		"same.go":    {Data: []byte("same"), ModTime: then},
+		"touched.go": {Data: []byte("touched"), ModTime: later},
+		"edited.go":  {Data: []byte("edited!"), ModTime: later},
+		"gone.go":    {Data: []byte("gone"), ModTime: then},
+		"new.go":     {Data: []byte("new"), ModTime: then},
+	}
+	output := t.TempDir()
+	c := cache.New("key")
+	for name, content := range map[string]string{"same.go": "same", "touched.go": "touched", "edited.go": "edited", "gone.go": "gone"} {
+		hash, _ := cache.Hash(strings.NewReader(content))
+		c.Files[name] = cache.File{Size: int64(len(content)), ModTime: then, SHA256: hash, Output: name, OutputSize: int64(len(content)), OutputModTime: then}
+		if name == "gone.go" {
+			continue // Its copy was removed from the output.
+		}
+		file := filepath.Join(output, name)
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(file, then, then); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	entries, err := Select(src, filter.Rules{}, nil)
+	if err != nil {
+		t.Fatalf("Select() returned an unexpected error: %v", err)
+	}
+	if err := reuseFiles(src, output, entries, c); err != nil {
+		t.Fatalf("reuseFiles() returned an unexpected error: %v", err)
+	}
+	got := make(map[string]bool)
+	for _, e := range entries {
+		if e.Hash == "" {
+			t.Errorf("%s has no hash", e.Path)
+		}
+		got[e.Path] = e.Reused
+	}
+	want := map[string]bool{"same.go": true, "touched.go": true, "edited.go": false, "gone.go": false, "new.go": false}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("reused files = %v, want %v", got, want)
+	}
+}
@@ -5,12 +5,21 @@ import (
 	"bytes"
 	"fmt"
 	"os/exec"
+	"time"
+
+	"github.com/AlienHeadwars/repo-slice/internal/binary"
+	"github.com/AlienHeadwars/repo-slice/internal/cache"
+	"github.com/AlienHeadwars/repo-slice/internal/filter"
+	"github.com/AlienHeadwars/repo-slice/internal/gitrepo"
+	"github.com/AlienHeadwars/repo-slice/internal/truncate"
 )
 
 // Executor defines an interface for running external commands from a specific
 // working directory.
 type Executor interface {
 	Run(workDir, command string, args ...string) error
+	// Output runs a command like Run and returns its standard output.
+	Output(workDir, command string, args ...string) ([]byte, error)
 }
 
 // CmdExecutor is a concrete implementation of the Executor interface.
@@ -35,20 +44,164 @@ func (e CmdExecutor) Run(workDir, command string, args ...string) error {
 	return nil
 }
 
-// Slice constructs and executes an rsync command to copy files based on a
-// manifest file that uses rsync filter-rule syntax.
-func Slice(source, output, manifestPath string, exec Executor) error {
+// Output executes a command from the given working directory and returns what
+// it wrote to stdout. Failures are reported in the same way as by Run.
+func (e CmdExecutor) Output(workDir, command string, args ...string) ([]byte, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command(command, args...)
+	cmd.Dir = workDir
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err == nil && stderr.Len() > 0 {
+		return nil, fmt.Errorf("command produced stderr output:\n%s", stderr.String())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("command failed with exit code: %w\nSTDERR:\n%s", err, stderr.String())
+	}
+	return out, nil
+}
+
+// Engine names a backend that evaluates a manifest and copies the selection.
+type Engine string
+
+const (
+	// EngineNative evaluates rsync filter rules in-process and needs no
+	// external tools.
+	EngineNative Engine = "native"
+	// EngineRsync delegates filtering and copying to an installed rsync.
+	EngineRsync Engine = "rsync"
+)
+
+// Options holds the inputs for a single slice operation.
+type Options struct {
+	Source       string
+	Output       string
+	ManifestPath string
+	Engine       Engine // Defaults to EngineNative when empty.
+	// ManifestFormat selects the manifest dialect. When empty it is read from
+	// the manifest header, defaulting to rsync syntax.
+	ManifestFormat filter.Format
+	// Gitignore applies the source repository's .gitignore files and
+	// .git/info/exclude before the manifest, and skips the .git directory.
+	Gitignore bool
+	// TrackedOnly restricts the candidates to the files git tracks in the
+	// source, as listed by "git ls-files".
+	TrackedOnly bool
+	// Ref, when set, names a commit whose tree is sliced instead of the
+	// working tree. Source still locates the repository and the directory
+	// within it to slice.
+	Ref string
+	// Binary selects what happens to binary files. When empty they are kept.
+	Binary binary.Mode
+	// MaxFileSize and MaxFileLines limit the size of each file, when greater
+	// than zero. Oversized selects what happens to files over either limit;
+	// when empty they are skipped.
+	MaxFileSize  int64
+	MaxFileLines int
+	Oversized    truncate.Mode
+	// Cache, when set, holds the files a previous slice copied into Output.
+	// Files it shows are unchanged are not copied again.
+	Cache *cache.Cache
+}
+
+// Slice copies the files selected by the manifest from the source directory
+// to the output directory using the backend named in opts, and returns the
+// entries it selected. The executor is used to run rsync and git, and the
+// rsync backend only understands rsync manifests and reports no entries.
+func Slice(opts Options, exec Executor) ([]Entry, error) {
+	switch opts.Engine {
+	case EngineNative, "":
+		return sliceNative(opts, exec)
+	case EngineRsync:
+		format, err := filter.DetectFormat(opts.ManifestPath, opts.ManifestFormat, filter.LiveFS{})
+		if err != nil {
+			return nil, err
+		}
+		if format != filter.FormatRsync {
+			return nil, fmt.Errorf("the rsync engine cannot read %s manifests; use the native engine", format)
+		}
+		rules, _, err := filter.LoadAll(opts.ManifestPath, opts.Source, format, filter.LiveFS{})
+		if err != nil {
+			return nil, err
+		}
+		if len(rules.Sets()) > 0 {
+			return nil, fmt.Errorf("the rsync engine cannot resolve %s: rules; use the native engine", rules.Sets()[0].Kind)
+		}
+		if rules.Transformed() {
+			return nil, fmt.Errorf("the rsync engine cannot transform files; use the native engine")
+		}
+		if opts.Gitignore || opts.TrackedOnly {
+			return nil, fmt.Errorf("the rsync engine cannot filter files using git; use the native engine")
+		}
+		if opts.Ref != "" {
+			return nil, fmt.Errorf("the rsync engine cannot slice a git ref; use the native engine")
+		}
+		if opts.Binary != "" && opts.Binary != binary.ModeKeep {
+			return nil, fmt.Errorf("the rsync engine cannot detect binary files; use the native engine")
+		}
+		if opts.Cache != nil {
+			return nil, fmt.Errorf("the rsync engine cannot slice incrementally; use the native engine")
+		}
+		if opts.MaxFileSize > 0 || opts.MaxFileLines > 0 {
+			return nil, fmt.Errorf("the rsync engine cannot limit the size of each file; use the native engine")
+		}
+		return nil, sliceRsync(opts, exec)
+	default:
+		return nil, fmt.Errorf("unknown slicing engine: %q", opts.Engine)
+	}
+}
+
+// sliceRsync constructs and executes an rsync command to copy files based on
+// a manifest file that uses rsync filter-rule syntax.
+func sliceRsync(opts Options, exec Executor) error {
 	args := []string{
 		"-a", // Archive mode to preserve permissions, ownership, etc.
 		"--filter",
-		fmt.Sprintf("merge %s", manifestPath),
-		".",    // Source directory (relative to the workDir)
-		output, // Destination directory
+		fmt.Sprintf("merge %s", opts.ManifestPath),
+		".",         // Source directory (relative to the workDir)
+		opts.Output, // Destination directory
 	}
 
-	if err := exec.Run(source, "rsync", args...); err != nil {
+	if err := exec.Run(opts.Source, "rsync", args...); err != nil {
 		return fmt.Errorf("rsync command failed: %w", err)
 	}
 
 	return nil
 }
+
+// Revision identifies the commit a slice is taken from.
+type Revision struct {
+	Commit string    // Full hash, or "" if the source is not in a git repository.
+	Time   time.Time // The committer time of Commit.
+	Dirty  bool      // The working tree beneath the source differs from Commit.
+}
+
+// SourceRevision returns the commit the slice described by opts is taken
+// from: the commit opts.Ref names, or else the commit checked out in the
+// repository containing the source, and whether the source has uncommitted
+// changes. A source outside any repository, or in one without commits, has
+// no revision and is not an error.
+func SourceRevision(opts Options, exec Executor) (Revision, error) {
+	if opts.Ref != "" {
+		commit, err := gitrepo.ResolveCommit(opts.Source, opts.Ref, exec)
+		if err != nil {
+			return Revision{}, err
+		}
+		committed, err := gitrepo.CommitTime(opts.Source, commit, exec)
+		return Revision{Commit: commit, Time: committed}, err
+	}
+	commit, err := gitrepo.ResolveCommit(opts.Source, "HEAD", exec)
+	if err != nil {
+		return Revision{}, nil
+	}
+	committed, err := gitrepo.CommitTime(opts.Source, commit, exec)
+	if err != nil {
+		return Revision{}, err
+	}
+	dirty, err := gitrepo.Dirty(opts.Source, exec)
+	if err != nil {
+		return Revision{}, err
+	}
+	return Revision{Commit: commit, Time: committed, Dirty: dirty}, nil
+}
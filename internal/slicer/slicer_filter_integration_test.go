@@ -4,10 +4,15 @@
 package slicer
 
 import (
+	"io/fs"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
+
+	"github.com/AlienHeadwars/repo-slice/internal/remapper"
 )
 
 const (
@@ -184,27 +189,318 @@ func TestSliceWithFiltering(t *testing.T) {
 		},
 	}
 
-	for _, tc := range testCases {
-		t.Run(tc.name, func(t *testing.T) {
-			manifestPath := filepath.Join(sourceDir, tc.manifestFilename)
-			// Use the helper to clean the manifest content before writing
-			_ = os.WriteFile(manifestPath, []byte(cleanManifest(tc.manifestContent)), 0644)
+	for _, engine := range availableEngines(t) {
+		for _, tc := range testCases {
+			t.Run(string(engine)+"/"+tc.name, func(t *testing.T) {
+				manifestPath := filepath.Join(sourceDir, tc.manifestFilename)
+				// Use the helper to clean the manifest content before writing
+				_ = os.WriteFile(manifestPath, []byte(cleanManifest(tc.manifestContent)), 0644)
 
-			err := Slice(sourceDir, outputDir, manifestPath, &CmdExecutor{})
-			if err != nil {
-				t.Fatalf("Slice() returned an unexpected error: %v", err)
-			}
+				opts := Options{Source: sourceDir, Output: outputDir, ManifestPath: manifestPath, Engine: engine}
+				_, err := Slice(opts, &CmdExecutor{})
+				if err != nil {
+					t.Fatalf("Slice() returned an unexpected error: %v", err)
+				}
 
-			for _, file := range tc.expectedToExist {
-				assertFileExists(t, filepath.Join(outputDir, file))
-			}
-			for _, file := range tc.expectedToNotExist {
-				assertFileDoesNotExist(t, filepath.Join(outputDir, file))
+				for _, file := range tc.expectedToExist {
+					assertFileExists(t, filepath.Join(outputDir, file))
+				}
+				for _, file := range tc.expectedToNotExist {
+					assertFileDoesNotExist(t, filepath.Join(outputDir, file))
+				}
+
+				// Clean the output directory for the next run
+				os.RemoveAll(outputDir)
+				os.Mkdir(outputDir, 0755)
+			})
+		}
+	}
+}
+
+func TestSliceWithGitignoreManifest(t *testing.T) {
+	sourceDir, outputDir, cleanup := setupCommonTestStructure(t)
+	defer cleanup()
+
+	manifestPath := filepath.Join(sourceDir, "slice.gitignore")
+	manifest := "# format: gitignore\n*.log\n*_test.go\n!/main_test.go\n/slice.gitignore\n"
+	_ = os.WriteFile(manifestPath, []byte(manifest), 0644)
+
+	if _, err := Slice(Options{Source: sourceDir, Output: outputDir, ManifestPath: manifestPath}, &CmdExecutor{}); err != nil {
+		t.Fatalf("Slice() returned an unexpected error: %v", err)
+	}
+
+	for _, file := range []string{readmeMdFile, mainGoFile, mainTestGoFile, appGoFile, guideMdFile} {
+		assertFileExists(t, filepath.Join(outputDir, file))
+	}
+	for _, file := range []string{traceLogFile, appTestGoFile, "slice.gitignore"} {
+		assertFileDoesNotExist(t, filepath.Join(outputDir, file))
+	}
+}
+
+func TestSliceHonoursGitignore(t *testing.T) {
+	sourceDir, outputDir, cleanup := setupCommonTestStructure(t)
+	defer cleanup()
+
+	files := map[string]string{
+		".gitignore":                    "*.log\nnode_modules/\n",
+		".git/info/exclude":             "/common.txt\n",
+		".git/HEAD":                     "ref: refs/heads/main\n",
+		"src/.gitignore":                "*_test.go\n",
+		"web/node_modules/lib/index.js": "module.exports = {}\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(sourceDir, filepath.FromSlash(name))
+		_ = os.MkdirAll(filepath.Dir(path), 0755)
+		_ = os.WriteFile(path, []byte(content), 0644)
+	}
+	manifestPath := filepath.Join(outputDir, "..", "manifest.txt")
+	_ = os.WriteFile(manifestPath, []byte("+ **\n"), 0644)
+
+	opts := Options{Source: sourceDir, Output: outputDir, ManifestPath: manifestPath, Gitignore: true}
+	if _, err := Slice(opts, &CmdExecutor{}); err != nil {
+		t.Fatalf("Slice() returned an unexpected error: %v", err)
+	}
+
+	for _, file := range []string{readmeMdFile, mainGoFile, mainTestGoFile, appGoFile, ".gitignore", "web"} {
+		assertFileExists(t, filepath.Join(outputDir, file))
+	}
+	for _, file := range []string{traceLogFile, commonTxtFile, appTestGoFile, ".git", "web/node_modules"} {
+		assertFileDoesNotExist(t, filepath.Join(outputDir, file))
+	}
+}
+
+func TestSliceGoPackage(t *testing.T) {
+	sourceDir, outputDir, cleanup := setupIntegrationTest(t)
+	defer cleanup()
+
+	files := map[string]string{
+		"go.mod":              "module example.com/app\n",
+		"cmd/app/main.go":     "package main\n\nimport \"example.com/app/lib\"\n\nfunc main() { lib.Run() }\n",
+		"cmd/app/app_test.go": "package main\n",
+		"lib/lib.go":          "package lib\n\nfunc Run() {}\n",
+		"other/other.go":      "package other\n",
+		"README.md":           "readme\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(sourceDir, filepath.FromSlash(name))
+		_ = os.MkdirAll(filepath.Dir(path), 0755)
+		_ = os.WriteFile(path, []byte(content), 0644)
+	}
+	manifestPath := filepath.Join(outputDir, "..", "manifest.txt")
+	_ = os.WriteFile(manifestPath, []byte("+ /README.md\n+ gopkg:./cmd/app\n- *\n"), 0644)
+
+	if _, err := Slice(Options{Source: sourceDir, Output: outputDir, ManifestPath: manifestPath}, &CmdExecutor{}); err != nil {
+		t.Fatalf("Slice() returned an unexpected error: %v", err)
+	}
+	for _, file := range []string{"README.md", "go.mod", "cmd/app/main.go", "lib/lib.go"} {
+		assertFileExists(t, filepath.Join(outputDir, file))
+	}
+	for _, file := range []string{"cmd/app/app_test.go", "other"} {
+		assertFileDoesNotExist(t, filepath.Join(outputDir, file))
+	}
+
+	opts := Options{Source: sourceDir, Output: outputDir, ManifestPath: manifestPath, Engine: EngineRsync}
+	if _, err := Slice(opts, &CmdExecutor{}); err == nil || !strings.Contains(err.Error(), "native engine") {
+		t.Errorf("Slice() with the rsync engine = %v, want an error naming the native engine", err)
+	}
+}
+
+func TestSliceJSEntryThenRemap(t *testing.T) {
+	sourceDir, outputDir, cleanup := setupIntegrationTest(t)
+	defer cleanup()
+
+	files := map[string]string{
+		"src/pages/Home.tsx":        "import { Button } from \"../components/Button\";\n",
+		"src/pages/About.tsx":       "export {};\n",
+		"src/components/Button.tsx": "export const Button = () => null;\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(sourceDir, filepath.FromSlash(name))
+		_ = os.MkdirAll(filepath.Dir(path), 0755)
+		_ = os.WriteFile(path, []byte(content), 0644)
+	}
+	manifestPath := filepath.Join(outputDir, "..", "manifest.txt")
+	_ = os.WriteFile(manifestPath, []byte("+ js:./src/pages/Home.tsx\n- *\n"), 0644)
+
+	if _, err := Slice(Options{Source: sourceDir, Output: outputDir, ManifestPath: manifestPath}, &CmdExecutor{}); err != nil {
+		t.Fatalf("Slice() returned an unexpected error: %v", err)
+	}
+	// The closure is computed from the source, so renaming the slice
+	// afterwards keeps every file it found.
+	extMap, _ := remapper.ParseExtensionMap("tsx:ts")
+	if err := remapper.RemapExtensions(outputDir, extMap, &remapper.LiveFS{}); err != nil {
+		t.Fatalf("RemapExtensions() returned an unexpected error: %v", err)
+	}
+	for _, file := range []string{"src/pages/Home.ts", "src/components/Button.ts"} {
+		assertFileExists(t, filepath.Join(outputDir, file))
+	}
+	for _, file := range []string{"src/pages/About.tsx", "src/pages/About.ts"} {
+		assertFileDoesNotExist(t, filepath.Join(outputDir, file))
+	}
+}
+
+func TestSliceTrackedOnly(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found in PATH")
+	}
+	sourceDir, outputDir, cleanup := setupCommonTestStructure(t)
+	defer cleanup()
+
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		cmd.Dir = sourceDir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, out)
+		}
+	}
+	git("init", "-q")
+	git("add", mainGoFile, appGoFile, guideMdFile)
+	git("commit", "-q", "-m", "initial")
+	_ = os.WriteFile(filepath.Join(sourceDir, "scratch.txt"), []byte("notes"), 0644)
+
+	manifestPath := filepath.Join(outputDir, "..", "manifest.txt")
+	_ = os.WriteFile(manifestPath, []byte("- /docs/\n+ **\n"), 0644)
+
+	opts := Options{Source: sourceDir, Output: outputDir, ManifestPath: manifestPath, TrackedOnly: true}
+	if _, err := Slice(opts, &CmdExecutor{}); err != nil {
+		t.Fatalf("Slice() returned an unexpected error: %v", err)
+	}
+
+	assertFileExists(t, filepath.Join(outputDir, mainGoFile))
+	assertFileExists(t, filepath.Join(outputDir, appGoFile))
+	for _, file := range []string{guideMdFile, readmeMdFile, appTestGoFile, "scratch.txt", ".git"} {
+		assertFileDoesNotExist(t, filepath.Join(outputDir, file))
+	}
+}
+
+func TestSliceFromRef(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found in PATH")
+	}
+	sourceDir, outputDir, cleanup := setupCommonTestStructure(t)
+	defer cleanup()
+
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		cmd.Dir = sourceDir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, out)
+		}
+	}
+	_ = os.WriteFile(filepath.Join(sourceDir, "src", ".gitignore"), []byte("*_test.go\n"), 0644)
+	_ = os.Symlink("app.go", filepath.Join(sourceDir, "src", "app", "link.go"))
+	git("init", "-q")
+	git("add", "-A", "-f")
+	git("commit", "-q", "-m", "initial")
+	git("tag", "v1")
+	_ = os.WriteFile(filepath.Join(sourceDir, appGoFile), []byte("changed"), 0644)
+	git("rm", "-q", mainGoFile)
+	git("commit", "-q", "-am", "second")
+
+	manifestPath := filepath.Join(outputDir, "..", "manifest.txt")
+	_ = os.WriteFile(manifestPath, []byte("- /docs/\n+ **\n"), 0644)
+
+	opts := Options{Source: filepath.Join(sourceDir, "src"), Output: outputDir, ManifestPath: manifestPath, Ref: "v1", Gitignore: true}
+	if _, err := Slice(opts, &CmdExecutor{}); err != nil {
+		t.Fatalf("Slice() returned an unexpected error: %v", err)
+	}
+
+	got := listTree(t, outputDir)
+	want := []string{".gitignore", "app/", "app/app.go", "app/link.go"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("slice = %v, want %v", got, want)
+	}
+	if content, err := os.ReadFile(filepath.Join(outputDir, "app", "app.go")); err != nil || len(content) != 0 {
+		t.Errorf("app.go = %q, %v; want the committed, empty file", content, err)
+	}
+	if target, err := os.Readlink(filepath.Join(outputDir, "app", "link.go")); err != nil || target != "app.go" {
+		t.Errorf("link.go = %q, %v; want a link to app.go", target, err)
+	}
+	if content, _ := os.ReadFile(filepath.Join(sourceDir, appGoFile)); string(content) != "changed" {
+		t.Error("slicing a ref changed the working tree")
+	}
+}
+
+// availableEngines returns the engines that can run on this machine. The rsync
+// backend is skipped when rsync is not installed so that the native backend
+// can still be verified.
+func availableEngines(t *testing.T) []Engine {
+	t.Helper()
+	engines := []Engine{EngineNative}
+	if _, err := exec.LookPath("rsync"); err == nil {
+		engines = append(engines, EngineRsync)
+	} else {
+		t.Log("rsync not found in PATH; only the native engine will be tested")
+	}
+	return engines
+}
+
+// listTree returns every path beneath root, relative to root, with a trailing
+// slash marking directories.
+func listTree(t *testing.T, root string) []string {
+	t.Helper()
+	var paths []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || path == root {
+			return err
+		}
+		rel, _ := filepath.Rel(root, path)
+		if d.IsDir() {
+			rel += "/"
+		}
+		paths = append(paths, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to list %s: %v", root, err)
+	}
+	return paths
+}
+
+// TestEnginesProduceIdenticalTrees runs every manifest through both backends
+// and compares the resulting directory trees entry by entry.
+func TestEnginesProduceIdenticalTrees(t *testing.T) {
+	if _, err := exec.LookPath("rsync"); err != nil {
+		t.Skip("rsync not found in PATH")
+	}
+	sourceDir, outputDir, cleanup := setupCommonTestStructure(t)
+	defer cleanup()
+
+	manifests := []string{
+		"+ /main.go\n+ /docs/\n- /docs/guide.md\n- *",
+		"+ **/*.md\n+ **/\n- *",
+		"- *.log\n- *_test.go\n+ **",
+		"- /src/app/app_test.go\n+ /src/**",
+		"+ */\n+ *.go\n- *",
+		"+ /src/***\n- *",
+		"- app/\n+ **",
+		"+ [cm]*.*\n- *",
+		"-! */\n+ **/",
+		"+ src/app/*.go\n+ */\n- *",
+	}
+
+	manifestPath := filepath.Join(filepath.Dir(sourceDir), "manifest.txt")
+	for i, manifest := range manifests {
+		if err := os.WriteFile(manifestPath, []byte(manifest), 0644); err != nil {
+			t.Fatalf("failed to write manifest: %v", err)
+		}
+
+		var trees [][]string
+		for _, engine := range []Engine{EngineNative, EngineRsync} {
+			out := filepath.Join(outputDir, string(engine))
+			opts := Options{Source: sourceDir, Output: out, ManifestPath: manifestPath, Engine: engine}
+			if _, err := Slice(opts, &CmdExecutor{}); err != nil {
+				t.Fatalf("manifest %d: %s Slice() returned an unexpected error: %v", i, engine, err)
 			}
+			trees = append(trees, listTree(t, out))
+		}
+		if !reflect.DeepEqual(trees[0], trees[1]) {
+			t.Errorf("manifest %d: engines disagree\nnative: %v\nrsync:  %v", i, trees[0], trees[1])
+		}
 
-			// Clean the output directory for the next run
-			os.RemoveAll(outputDir)
-			os.Mkdir(outputDir, 0755)
-		})
+		os.RemoveAll(outputDir)
+		os.Mkdir(outputDir, 0755)
 	}
 }
@@ -4,14 +4,21 @@ package slicer
 import (
 	"errors"
 	"fmt"
+	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/AlienHeadwars/repo-slice/internal/binary"
+	"github.com/AlienHeadwars/repo-slice/internal/cache"
+	"github.com/AlienHeadwars/repo-slice/internal/filter"
 )
 
 // mockExecutor implements the Executor interface to capture command calls.
 type mockExecutor struct {
 	returnErr bool
+	output    []byte
 	workDir   string
 	command   string
 	args      []string
@@ -27,6 +34,26 @@ func (m *mockExecutor) Run(workDir, command string, args ...string) error {
 	return nil
 }
 
+func (m *mockExecutor) Output(workDir, command string, args ...string) ([]byte, error) {
+	m.workDir = workDir
+	m.command = command
+	m.args = args
+	if m.returnErr {
+		return nil, errors.New("mock executor error")
+	}
+	return m.output, nil
+}
+
+// writeManifest writes a manifest to a temporary file and returns its path.
+func writeManifest(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "manifest.txt")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write manifest: %v", err)
+	}
+	return path
+}
+
 // TestCmdExecutorRunFailsOnStderr verifies that the executor returns an error
 // if a command writes to stderr, even if it returns a zero exit code.
 // Note: This test can't be an integration test as it's hard to find a real
@@ -53,10 +80,10 @@ func TestCmdExecutorRunFailsOnStderr(t *testing.T) {
 func TestSliceConstructsCorrectFilterArgument(t *testing.T) {
 	sourceDir := "/source"
 	outputDir := "/output"
-	manifestPath := "/manifest.txt"
+	manifestPath := writeManifest(t, "+ *\n")
 	mockExec := &mockExecutor{}
 
-	err := Slice(sourceDir, outputDir, manifestPath, mockExec)
+	_, err := Slice(Options{Source: sourceDir, Output: outputDir, ManifestPath: manifestPath, Engine: EngineRsync}, mockExec)
 	if err != nil {
 		t.Fatalf("Slice() returned an unexpected error: %v", err)
 	}
@@ -78,7 +105,7 @@ func TestSliceConstructsCorrectFilterArgument(t *testing.T) {
 func TestSlice(t *testing.T) {
 	sourceDir := "/path/to/source"
 	outputDir := "/tmp/output"
-	manifestPath := "/path/to/manifest.txt"
+	manifestPath := writeManifest(t, "+ *\n")
 
 	testCases := []struct {
 		name    string
@@ -99,10 +126,78 @@ func TestSlice(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			err := Slice(sourceDir, outputDir, manifestPath, tc.exec)
+			_, err := Slice(Options{Source: sourceDir, Output: outputDir, ManifestPath: manifestPath, Engine: EngineRsync}, tc.exec)
 			if (err != nil) != tc.wantErr {
 				t.Errorf("Slice() error = %v, wantErr %v", err, tc.wantErr)
 			}
 		})
 	}
-}
\ No newline at end of file
+}
+
+// TestSliceRejectsUnknownEngine verifies that an engine name that is neither
+// native nor rsync is an error rather than a silent fallback.
+func TestSliceRejectsUnknownEngine(t *testing.T) {
+	opts := Options{Source: "/source", Output: "/output", ManifestPath: "/manifest.txt", Engine: "robocopy"}
+	if _, err := Slice(opts, &mockExecutor{}); err == nil {
+		t.Error("Slice() did not return an error for an unknown engine")
+	}
+}
+
+// TestSliceRsyncRejectsGitignoreManifests verifies that the rsync engine
+// refuses gitignore-syntax manifests, whether the format is given as an option
+// or in the manifest's header, instead of misreading them as rsync rules.
+func TestSliceRsyncRejectsGitignoreManifests(t *testing.T) {
+	testCases := []struct {
+		name     string
+		manifest string
+		format   filter.Format
+	}{
+		{"Format option", "*.log\n", filter.FormatGitignore},
+		{"Format header", "# format: gitignore\n*.log\n", ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockExec := &mockExecutor{}
+			opts := Options{Source: "/source", Output: "/output", ManifestPath: writeManifest(t, tc.manifest), Engine: EngineRsync, ManifestFormat: tc.format}
+			if _, err := Slice(opts, mockExec); err == nil {
+				t.Error("Slice() did not return an error for a gitignore manifest")
+			}
+			if mockExec.command != "" {
+				t.Errorf("Slice() ran %q for a gitignore manifest", mockExec.command)
+			}
+		})
+	}
+}
+
+// TestSliceRsyncRejectsNativeOnlyOptions verifies that the rsync engine
+// refuses every option only the native engine implements, without running
+// rsync, instead of ignoring it.
+func TestSliceRsyncRejectsNativeOnlyOptions(t *testing.T) {
+	testCases := []struct {
+		name string
+		opts Options
+	}{
+		{"Gitignore", Options{Gitignore: true}},
+		{"Tracked only", Options{TrackedOnly: true}},
+		{"Ref", Options{Ref: "HEAD"}},
+		{"Binary detection", Options{Binary: binary.ModeSkip}},
+		{"Per-file size limit", Options{MaxFileSize: 1024}},
+		{"Per-file line limit", Options{MaxFileLines: 100}},
+		{"Cache", Options{Cache: cache.New("")}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockExec := &mockExecutor{}
+			opts := tc.opts
+			opts.Source, opts.Output, opts.ManifestPath, opts.Engine = "/source", "/output", writeManifest(t, "+ *\n"), EngineRsync
+			if _, err := Slice(opts, mockExec); err == nil {
+				t.Error("Slice() did not return an error for an option rsync does not support")
+			}
+			if mockExec.command != "" {
+				t.Errorf("Slice() ran %q", mockExec.command)
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+// file: internal/slicer/tracked.go
+package slicer
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+
+	"github.com/AlienHeadwars/repo-slice/internal/filter"
+)
+
+// untrackedRule reports paths that git does not track.
+var untrackedRule = &filter.Rule{Action: filter.Exclude, File: "git ls-files", Text: "not tracked by git"}
+
+// Tracked is a prefilter that keeps only the files in a git index and the
+// directories that contain them.
+type Tracked struct {
+	files map[string]bool
+	dirs  map[string]bool
+}
+
+// NewTracked returns a Tracked prefilter for the given slash-separated paths.
+func NewTracked(paths []string) *Tracked {
+	t := &Tracked{files: make(map[string]bool), dirs: make(map[string]bool)}
+	for _, p := range paths {
+		t.files[p] = true
+		for dir := path.Dir(p); dir != "." && !t.dirs[dir]; dir = path.Dir(dir) {
+			t.dirs[dir] = true
+		}
+	}
+	return t
+}
+
+// Excluded returns a rule for any path that is not tracked.
+func (t *Tracked) Excluded(name string, isDir bool) (*filter.Rule, error) {
+	if isDir && t.dirs[name] || !isDir && t.files[name] {
+		return nil, nil
+	}
+	return untrackedRule, nil
+}
+
+// trackedFiles lists the files git tracks beneath source, relative to it.
+func trackedFiles(source string, exec Executor) (*Tracked, error) {
+	out, err := exec.Output(source, "git", "ls-files", "-z")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tracked files: %w", err)
+	}
+	var paths []string
+	for _, p := range bytes.Split(out, []byte{0}) {
+		if len(p) > 0 {
+			paths = append(paths, string(p))
+		}
+	}
+	return NewTracked(paths), nil
+}
@@ -0,0 +1,82 @@
+package slicer
+
+import (
+	"reflect"
+	"testing"
+	"testing/fstest"
+
+	"github.com/AlienHeadwars/repo-slice/internal/filter"
+)
+
+func TestTrackedExcluded(t *testing.T) {
+	tracked := NewTracked([]string{"main.go", "src/app/app.go"})
+
+	testCases := []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{"main.go", false, false},
+		{"src", true, false},
+		{"src/app", true, false},
+		{"src/app/app.go", false, false},
+		{"scratch.txt", false, true},
+		{"src/app/notes.md", false, true},
+		{"build", true, true},
+		{"main.go", true, true},
+		{"src", false, true},
+	}
+	for _, tc := range testCases {
+		rule, err := tracked.Excluded(tc.path, tc.isDir)
+		if err != nil {
+			t.Fatalf("Excluded() returned an unexpected error: %v", err)
+		}
+		if (rule != nil) != tc.want {
+			t.Errorf("Excluded(%q, %v) = %v, want excluded %v", tc.path, tc.isDir, rule, tc.want)
+		}
+	}
+}
+
+func TestTrackedFiles(t *testing.T) {
+	exec := &mockExecutor{output: []byte("main.go\x00src/app/app.go\x00odd name\n.txt\x00")}
+	tracked, err := trackedFiles("/repo/web", exec)
+	if err != nil {
+		t.Fatalf("trackedFiles() returned an unexpected error: %v", err)
+	}
+	if exec.workDir != "/repo/web" || exec.command != "git" || !reflect.DeepEqual(exec.args, []string{"ls-files", "-z"}) {
+		t.Errorf("trackedFiles() ran %s %v in %s", exec.command, exec.args, exec.workDir)
+	}
+	if rule, _ := tracked.Excluded("odd name\n.txt", false); rule != nil {
+		t.Error("file names must be read verbatim")
+	}
+
+	if _, err := trackedFiles("/repo", &mockExecutor{returnErr: true}); err == nil {
+		t.Error("trackedFiles() did not return an error when git failed")
+	}
+}
+
+func TestSelectWithPrefilterChain(t *testing.T) {
+	src := fstest.MapFS{
+		".gitignore":  {Data: []byte("*.gen.go\n")},
+		"main.go":     {Data: []byte("package main")},
+		"api.gen.go":  {Data: []byte("package main")},
+		"scratch.txt": {Data: []byte("notes")},
+		"tmp/out.txt": {Data: []byte("output")},
+	}
+	pre := prefilters{
+		NewTracked([]string{".gitignore", "main.go", "api.gen.go"}),
+		filter.NewGitIgnore(src, "."),
+	}
+
+	entries, err := Select(src, nil, pre)
+	if err != nil {
+		t.Fatalf("Select() returned an unexpected error: %v", err)
+	}
+	var got []string
+	for _, e := range entries {
+		got = append(got, e.Path)
+	}
+	if want := []string{".gitignore", "main.go"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Select() = %v, want %v", got, want)
+	}
+}
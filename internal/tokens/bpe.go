@@ -0,0 +1,105 @@
+// file: internal/tokens/bpe.go
+package tokens
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// pretokenize splits text into the words a byte-pair encoder merges
+// independently. It approximates the pattern used by OpenAI's cl100k
+// tokenizer within the limits of Go's regular expressions.
+var pretokenize = regexp.MustCompile(`(?i:'s|'t|'re|'ve|'m|'ll|'d)|[^\r\n\pL\pN]?\pL+|\pN{1,3}| ?[^\s\pL\pN]+[\r\n]*|\s*[\r\n]+|\s+`)
+
+// BPE counts tokens exactly as a byte-pair encoding tokenizer with the same
+// vocabulary would, apart from small differences in how text is split into
+// words.
+type BPE struct {
+	ranks map[string]int
+}
+
+// LoadBPE reads a vocabulary file from path. See ParseBPE for the format.
+func LoadBPE(path string) (*BPE, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open token vocabulary: %w", err)
+	}
+	defer f.Close()
+	return ParseBPE(f)
+}
+
+// ParseBPE reads a vocabulary in the tiktoken format used by OpenAI's
+// published encodings: one token per line, as base64-encoded bytes followed by
+// a space and the token's merge rank.
+func ParseBPE(r io.Reader) (*BPE, error) {
+	b := &BPE{ranks: make(map[string]int)}
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		token, rank, ok := strings.Cut(text, " ")
+		if !ok {
+			return nil, fmt.Errorf("token vocabulary line %d: expected a token and a rank", line)
+		}
+		data, err := base64.StdEncoding.DecodeString(token)
+		if err != nil {
+			return nil, fmt.Errorf("token vocabulary line %d: %w", line, err)
+		}
+		n, err := strconv.Atoi(rank)
+		if err != nil {
+			return nil, fmt.Errorf("token vocabulary line %d: invalid rank %q", line, rank)
+		}
+		b.ranks[string(data)] = n
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read token vocabulary: %w", err)
+	}
+	if len(b.ranks) == 0 {
+		return nil, fmt.Errorf("token vocabulary is empty")
+	}
+	return b, nil
+}
+
+// Count returns the number of tokens text encodes to.
+func (b *BPE) Count(text []byte) int {
+	count := 0
+	for _, word := range pretokenize.FindAll(text, -1) {
+		count += b.countWord(word)
+	}
+	return count
+}
+
+// countWord merges the bytes of word, lowest-ranked pair first, until no
+// adjacent pair is in the vocabulary, and returns the number of parts left.
+// Bytes missing from the vocabulary count as a token each.
+func (b *BPE) countWord(word []byte) int {
+	if _, ok := b.ranks[string(word)]; ok {
+		return 1
+	}
+	parts := make([]string, len(word))
+	for i := range word {
+		parts[i] = string(word[i : i+1])
+	}
+	for len(parts) > 1 {
+		best, bestRank := -1, 0
+		for i := 0; i+1 < len(parts); i++ {
+			if rank, ok := b.ranks[parts[i]+parts[i+1]]; ok && (best < 0 || rank < bestRank) {
+				best, bestRank = i, rank
+			}
+		}
+		if best < 0 {
+			break
+		}
+		parts[best] += parts[best+1]
+		parts = append(parts[:best+1], parts[best+2:]...)
+	}
+	return len(parts)
+}
@@ -0,0 +1,56 @@
+// file: internal/tokens/bpe_test.go
+package tokens
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// vocabulary builds a tiktoken-format vocabulary with the given tokens, ranked
+// in order.
+func vocabulary(tokens ...string) string {
+	var b strings.Builder
+	for i, tok := range tokens {
+		fmt.Fprintf(&b, "%s %d\n", base64.StdEncoding.EncodeToString([]byte(tok)), i)
+	}
+	return b.String()
+}
+
+func TestBPECount(t *testing.T) {
+	bpe, err := ParseBPE(strings.NewReader(vocabulary(
+		"a", "b", "c", "d", " ", "\n",
+		"ab", "cd", "abcd", " ab",
+	)))
+	if err != nil {
+		t.Fatalf("ParseBPE() returned an unexpected error: %v", err)
+	}
+
+	testCases := []struct {
+		text string
+		want int
+	}{
+		{"", 0},
+		{"abcd", 1},     // A whole word in the vocabulary.
+		{"abab", 2},     // ab + ab.
+		{"abc", 2},      // ab + c.
+		{"abcd ab", 2},  // abcd + " ab".
+		{"dcba", 4},     // No merges apply.
+		{"xyz", 3},      // Unknown bytes count one each.
+		{"ab\n\ncd", 4}, // ab + two newlines, which do not merge, + cd.
+	}
+	for _, tc := range testCases {
+		if got := bpe.Count([]byte(tc.text)); got != tc.want {
+			t.Errorf("Count(%q) = %d, want %d", tc.text, got, tc.want)
+		}
+	}
+}
+
+func TestParseBPEErrors(t *testing.T) {
+	for _, vocab := range []string{"", "YQ==\n", "!!! 1\n", "YQ== one\n"} {
+		if _, err := ParseBPE(strings.NewReader(vocab)); err == nil {
+			t.Errorf("ParseBPE(%q) did not return an error", vocab)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+// file: internal/tokens/builtin/builtin.go
+
+// Package builtin embeds the token vocabulary that is used when a BPE count
+// is wanted without a vocabulary file. It is kept apart from the tokens
+// package so that the generator that trains it can use that package even
+// when the vocabulary is missing.
+package builtin
+
+import (
+	_ "embed"
+	"strings"
+	"sync"
+
+	"github.com/AlienHeadwars/repo-slice/internal/tokens"
+)
+
+// Name is the vocabulary name that selects the built-in vocabulary instead of
+// a file.
+const Name = "builtin"
+
+// vocab is a vocabulary of 16384 tokens trained on the source of the Go
+// standard library. It is no model's own vocabulary, but it splits code and
+// prose much as theirs do, without a vocabulary file to download.
+//
+//go:generate go run ../trainvocab -size 16384 -o vocab.tiktoken $GOROOT/src
+//go:embed vocab.tiktoken
+var vocab string
+
+// BPE returns the built-in vocabulary, parsed on first use.
+var BPE = sync.OnceValues(func() (*tokens.BPE, error) {
+	return tokens.ParseBPE(strings.NewReader(vocab))
+})
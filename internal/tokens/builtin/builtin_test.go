@@ -0,0 +1,15 @@
+package builtin
+
+import "testing"
+
+func TestBPE(t *testing.T) {
+	bpe, err := BPE()
+	if err != nil {
+		t.Fatalf("BPE() returned an unexpected error: %v", err)
+	}
+	// Common words are single tokens, while rare ones are split.
+	text := []byte("func main() {\n\treturn nil\n}\n")
+	if got := bpe.Count(text); got < 5 || got > len(text)/2 {
+		t.Errorf("Count(%q) = %d, want a count between 5 and %d", text, got, len(text)/2)
+	}
+}
@@ -0,0 +1,111 @@
+// file: internal/tokens/tokens.go
+
+// Package tokens estimates how many tokens a slice will use in a language
+// model's context, which is what actually limits how much of a repository an
+// assistant can work with.
+package tokens
+
+import (
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Estimator counts the tokens in a piece of text.
+type Estimator interface {
+	Count(text []byte) int
+}
+
+// Heuristic estimates tokens without a vocabulary. Each run of letters and
+// digits counts one token per four bytes, rounded up, and every other
+// character apart from whitespace counts as one token. This tracks common
+// tokenizers closely enough to set budgets on source code and prose.
+type Heuristic struct{}
+
+// Count returns the estimated number of tokens in text.
+func (Heuristic) Count(text []byte) int {
+	count, word := 0, 0
+	for len(text) > 0 {
+		r, size := utf8.DecodeRune(text)
+		text = text[size:]
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' {
+			word += size
+			continue
+		}
+		count += (word + 3) / 4
+		word = 0
+		if !unicode.IsSpace(r) {
+			count++
+		}
+	}
+	return count + (word+3)/4
+}
+
+// FileCount is the estimated number of tokens in one file.
+type FileCount struct {
+	Path   string // Slash-separated path relative to the slice root.
+	Size   int64
+	Tokens int
+}
+
+// Report holds the token counts of every file in a slice.
+type Report struct {
+	Files []FileCount // Largest first, then by path.
+	Bytes int64
+	Total int
+}
+
+// Count estimates the tokens in every regular file in fsys.
+func Count(fsys fs.FS, est Estimator) (Report, error) {
+	var r Report
+	walkFn := func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		data, err := fs.ReadFile(fsys, path)
+		if err != nil {
+			return err
+		}
+		n := est.Count(data)
+		r.Files = append(r.Files, FileCount{Path: path, Size: int64(len(data)), Tokens: n})
+		r.Bytes += int64(len(data))
+		r.Total += n
+		return nil
+	}
+	if err := fs.WalkDir(fsys, ".", walkFn); err != nil {
+		return Report{}, fmt.Errorf("failed to count tokens: %w", err)
+	}
+	sort.SliceStable(r.Files, func(i, j int) bool { return r.Files[i].Tokens > r.Files[j].Tokens })
+	return r, nil
+}
+
+// Largest returns at most n of the files with the most tokens.
+func (r Report) Largest(n int) []FileCount {
+	if len(r.Files) < n {
+		return r.Files
+	}
+	return r.Files[:n]
+}
+
+// contributors is how many files a budget error lists.
+const contributors = 10
+
+// CheckBudget returns an error listing the largest files if the report is
+// over max tokens. A max of zero or less means no limit.
+func (r Report) CheckBudget(max int) error {
+	if max <= 0 || r.Total <= max {
+		return nil
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "an estimated %d tokens exceeds the limit of %d; the largest files are:", r.Total, max)
+	for _, f := range r.Largest(contributors) {
+		fmt.Fprintf(&b, "\n%10d  %s", f.Tokens, f.Path)
+	}
+	return fmt.Errorf("%s", b.String())
+}
@@ -0,0 +1,83 @@
+// file: internal/tokens/tokens_test.go
+package tokens
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestHeuristicCount(t *testing.T) {
+	testCases := []struct {
+		text string
+		want int
+	}{
+		{"", 0},
+		{"   \n\t", 0},
+		{"go", 1},
+		{"func", 1},
+		{"slicer", 2},
+		{"a, b", 3},
+		{"func main() {}", 6},
+		{"snake_case_name", 4},
+		{"日本語", 3},
+	}
+	for _, tc := range testCases {
+		if got := (Heuristic{}).Count([]byte(tc.text)); got != tc.want {
+			t.Errorf("Count(%q) = %d, want %d", tc.text, got, tc.want)
+		}
+	}
+}
+
+func TestCount(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.txt":      {Data: []byte("one two")},
+		"b.txt":      {Data: []byte("one")},
+		"src/c.go":   {Data: []byte("package main")},
+		"src/empty":  {},
+		"docs/d.txt": {Data: []byte("x y z w")},
+	}
+	r, err := Count(fsys, Heuristic{})
+	if err != nil {
+		t.Fatalf("Count() returned an unexpected error: %v", err)
+	}
+	want := Report{
+		Files: []FileCount{{"docs/d.txt", 7, 4}, {"src/c.go", 12, 3}, {"a.txt", 7, 2}, {"b.txt", 3, 1}, {"src/empty", 0, 0}},
+		Bytes: 29,
+		Total: 10,
+	}
+	if !reflect.DeepEqual(r, want) {
+		t.Errorf("Count() = %+v, want %+v", r, want)
+	}
+	if got := r.Largest(2); !reflect.DeepEqual(got, want.Files[:2]) {
+		t.Errorf("Largest(2) = %v, want %v", got, want.Files[:2])
+	}
+	if got := r.Largest(10); len(got) != 5 {
+		t.Errorf("Largest(10) returned %d files, want 5", len(got))
+	}
+}
+
+func TestCheckBudget(t *testing.T) {
+	r := Report{Total: 30}
+	for i := 0; i < 12; i++ {
+		r.Files = append(r.Files, FileCount{Path: "f" + string(rune('a'+i)), Tokens: 12 - i})
+	}
+
+	for _, max := range []int{0, 30, 100} {
+		if err := r.CheckBudget(max); err != nil {
+			t.Errorf("CheckBudget(%d) returned an unexpected error: %v", max, err)
+		}
+	}
+	err := r.CheckBudget(29)
+	if err == nil {
+		t.Fatal("CheckBudget(29) did not report a slice over budget")
+	}
+	msg := err.Error()
+	if !strings.Contains(msg, "30 tokens exceeds the limit of 29") || !strings.Contains(msg, "        12  fa") {
+		t.Errorf("unexpected error message:\n%s", msg)
+	}
+	if strings.Contains(msg, "fk") || strings.Count(msg, "\n") != contributors {
+		t.Errorf("error lists more than %d files:\n%s", contributors, msg)
+	}
+}
@@ -0,0 +1,193 @@
+// file: internal/tokens/train.go
+package tokens
+
+import (
+	"container/heap"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// Trainer learns a byte-pair encoding vocabulary from sample text, splitting
+// it into words exactly as BPE.Count does, so that the vocabulary merges the
+// same words it is later used to count.
+type Trainer struct {
+	words map[string]int // How often each word occurs.
+}
+
+// NewTrainer returns a Trainer with no sample text.
+func NewTrainer() *Trainer {
+	return &Trainer{words: make(map[string]int)}
+}
+
+// Add adds text to the sample.
+func (t *Trainer) Add(text []byte) {
+	for _, word := range pretokenize.FindAll(text, -1) {
+		t.words[string(word)]++
+	}
+}
+
+// pair is two adjacent tokens, by ID, that may be merged.
+type pair struct{ left, right int }
+
+// candidate is a pair and how often it occurred when it was queued.
+type candidate struct {
+	pair
+	count int
+}
+
+// candidates is a max-heap of pairs by count. Ties go to the pair whose
+// tokens sort first, so that the same sample always gives the same
+// vocabulary.
+type candidates struct {
+	items  []candidate
+	tokens []string
+}
+
+func (c *candidates) Len() int { return len(c.items) }
+func (c *candidates) Less(i, j int) bool {
+	a, b := c.items[i], c.items[j]
+	if a.count != b.count {
+		return a.count > b.count
+	}
+	if c.tokens[a.left] != c.tokens[b.left] {
+		return c.tokens[a.left] < c.tokens[b.left]
+	}
+	return c.tokens[a.right] < c.tokens[b.right]
+}
+func (c *candidates) Swap(i, j int) { c.items[i], c.items[j] = c.items[j], c.items[i] }
+func (c *candidates) Push(x any)    { c.items = append(c.items, x.(candidate)) }
+func (c *candidates) Pop() any {
+	last := c.items[len(c.items)-1]
+	c.items = c.items[:len(c.items)-1]
+	return last
+}
+
+// Train returns a vocabulary of at most size tokens: every single byte, then
+// the most frequent pair of adjacent tokens, merged, until the vocabulary is
+// full or no pair occurs twice. Words that occur only once are ignored, as
+// they would only teach the vocabulary the sample's typos and hashes.
+func (t *Trainer) Train(size int) *BPE {
+	tokens := make([]string, 256)
+	ids := make(map[string]int)
+	for i := range tokens {
+		tokens[i] = string([]byte{byte(i)})
+		ids[tokens[i]] = i
+	}
+
+	type word struct {
+		parts []int
+		count int
+	}
+	var words []word
+	samples := make([]string, 0, len(t.words))
+	for w, n := range t.words {
+		if n > 1 && len(w) > 1 {
+			samples = append(samples, w)
+		}
+	}
+	sort.Strings(samples)
+	counts := make(map[pair]int)
+	where := make(map[pair][]int) // The words a pair may occur in.
+	for _, s := range samples {
+		w := word{parts: make([]int, len(s)), count: t.words[s]}
+		for i := range len(s) {
+			w.parts[i] = int(s[i])
+		}
+		for i := 0; i+1 < len(w.parts); i++ {
+			p := pair{w.parts[i], w.parts[i+1]}
+			counts[p] += w.count
+			where[p] = append(where[p], len(words))
+		}
+		words = append(words, w)
+	}
+
+	queue := &candidates{tokens: tokens}
+	for p, n := range counts {
+		queue.items = append(queue.items, candidate{p, n})
+	}
+	heap.Init(queue)
+
+	for len(tokens) < size && queue.Len() > 0 {
+		next := heap.Pop(queue).(candidate)
+		if counts[next.pair] != next.count {
+			continue // Queued again with its current count.
+		}
+		if next.count < 2 {
+			break
+		}
+		merged := tokens[next.left] + tokens[next.right]
+		id, ok := ids[merged]
+		if !ok {
+			id = len(tokens)
+			tokens = append(tokens, merged)
+			ids[merged] = id
+			queue.tokens = tokens
+		}
+
+		changed := make(map[pair]bool)
+		for _, i := range where[next.pair] {
+			w := &words[i]
+			parts := make([]int, 0, len(w.parts))
+			found := false
+			for j := 0; j < len(w.parts); j++ {
+				if j+1 < len(w.parts) && w.parts[j] == next.left && w.parts[j+1] == next.right {
+					parts = append(parts, id)
+					found = true
+					j++
+				} else {
+					parts = append(parts, w.parts[j])
+				}
+			}
+			if !found {
+				continue // The pair was merged away by an earlier merge.
+			}
+			for j := 0; j+1 < len(w.parts); j++ {
+				p := pair{w.parts[j], w.parts[j+1]}
+				counts[p] -= w.count
+				changed[p] = true
+			}
+			for j := 0; j+1 < len(parts); j++ {
+				p := pair{parts[j], parts[j+1]}
+				counts[p] += w.count
+				if p.left == id || p.right == id {
+					where[p] = append(where[p], i)
+				}
+				changed[p] = true
+			}
+			w.parts = parts
+		}
+		delete(counts, next.pair)
+		delete(where, next.pair)
+		for p := range changed {
+			if n := counts[p]; n > 0 {
+				heap.Push(queue, candidate{p, n})
+			} else {
+				delete(counts, p)
+			}
+		}
+	}
+
+	b := &BPE{ranks: make(map[string]int, len(tokens))}
+	for rank, tok := range tokens {
+		b.ranks[tok] = rank
+	}
+	return b
+}
+
+// Write stores the vocabulary in the tiktoken format ParseBPE reads, in rank
+// order.
+func (b *BPE) Write(w io.Writer) error {
+	tokens := make([]string, 0, len(b.ranks))
+	for tok := range b.ranks {
+		tokens = append(tokens, tok)
+	}
+	sort.Slice(tokens, func(i, j int) bool { return b.ranks[tokens[i]] < b.ranks[tokens[j]] })
+	for _, tok := range tokens {
+		if _, err := fmt.Fprintf(w, "%s %d\n", base64.StdEncoding.EncodeToString([]byte(tok)), b.ranks[tok]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package tokens
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestTrain(t *testing.T) {
+	tr := NewTrainer()
+	tr.Add([]byte(strings.Repeat("return\n", 10) + strings.Repeat(" err\n", 3) + "unique"))
+	bpe := tr.Train(256 + 5)
+
+	if len(bpe.ranks) != 261 {
+		t.Fatalf("Train() learned %d tokens, want 261", len(bpe.ranks))
+	}
+	// The most frequent pairs are merged first, so the five merges build up
+	// the most frequent word before anything else.
+	if got := bpe.Count([]byte("return")); got != 1 {
+		t.Errorf("Count(return) = %d, want 1", got)
+	}
+	if got := bpe.Count([]byte(" err")); got != 4 {
+		t.Errorf("Count( err) = %d, want 4", got)
+	}
+
+	// Training is deterministic, and the vocabulary survives a round trip.
+	var first, second bytes.Buffer
+	if err := bpe.Write(&first); err != nil {
+		t.Fatalf("Write() returned an unexpected error: %v", err)
+	}
+	if err := tr.Train(256 + 5).Write(&second); err != nil {
+		t.Fatalf("Write() returned an unexpected error: %v", err)
+	}
+	if first.String() != second.String() {
+		t.Error("training the same sample twice gave different vocabularies")
+	}
+	parsed, err := ParseBPE(&first)
+	if err != nil {
+		t.Fatalf("ParseBPE() returned an unexpected error: %v", err)
+	}
+	if got := parsed.Count([]byte("return err\n")); got != bpe.Count([]byte("return err\n")) {
+		t.Errorf("parsed vocabulary counts %d tokens, want %d", got, bpe.Count([]byte("return err\n")))
+	}
+}
+
+func TestTrainStopsWithoutRepeatedPairs(t *testing.T) {
+	tr := NewTrainer()
+	tr.Add([]byte("ab\nab\nabc"))
+	if got := len(tr.Train(1000).ranks); got != 256+1 {
+		t.Errorf("Train() learned %d tokens, want the bytes and ab only", got)
+	}
+}
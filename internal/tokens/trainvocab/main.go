@@ -0,0 +1,76 @@
+// file: internal/tokens/trainvocab/main.go
+
+// Trainvocab trains the built-in token vocabulary from the source files in
+// the directories it is given. It is run by go generate in the tokens
+// package, on the source of the Go standard library.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/AlienHeadwars/repo-slice/internal/tokens"
+)
+
+// sampled are the extensions of the files trained on: source code, markup
+// and configuration, the text a slice is mostly made of.
+var sampled = map[string]bool{
+	".go": true, ".c": true, ".h": true, ".js": true, ".ts": true, ".py": true, ".sh": true,
+	".md": true, ".txt": true, ".html": true, ".css": true, ".json": true, ".yaml": true, ".yml": true,
+}
+
+func main() {
+	size := flag.Int("size", 16384, "The number of tokens in the vocabulary")
+	output := flag.String("o", "builtin.tiktoken", "The vocabulary file to write")
+	flag.Parse()
+	if flag.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "usage: trainvocab [-size n] [-o file] dir...")
+		os.Exit(2)
+	}
+	if err := train(*size, *output, flag.Args()); err != nil {
+		fmt.Fprintf(os.Stderr, "trainvocab: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// train writes a vocabulary of size tokens, learned from the files in dirs,
+// to output.
+func train(size int, output string, dirs []string) error {
+	t := tokens.NewTrainer()
+	for _, dir := range dirs {
+		err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			// Test fixtures are deliberately unusual text.
+			if d.IsDir() && (d.Name() == "testdata" || d.Name() == "vendor") {
+				return filepath.SkipDir
+			}
+			if d.IsDir() || !sampled[filepath.Ext(path)] {
+				return nil
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			t.Add(data)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	f, err := os.Create(output)
+	if err != nil {
+		return err
+	}
+	if err := t.Train(size).Write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
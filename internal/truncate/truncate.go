@@ -0,0 +1,125 @@
+// file: internal/truncate/truncate.go
+
+// Package truncate caps the size of individual files in a slice, so that one
+// large generated file cannot use up the budget of the whole slice. Oversized
+// files are either left out or cut short, with a comment marking where their
+// content stops.
+package truncate
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"strings"
+	"unicode/utf8"
+)
+
+// Mode selects what a slice does with files over the limits.
+type Mode string
+
+const (
+	// ModeSkip leaves oversized files out of the slice.
+	ModeSkip Mode = "skip"
+	// ModeTruncate keeps the start of oversized files.
+	ModeTruncate Mode = "truncate"
+)
+
+// Valid reports whether m names a supported mode.
+func (m Mode) Valid() bool {
+	return m == ModeSkip || m == ModeTruncate
+}
+
+// Limits bounds the size of each file. A zero value disables a limit.
+type Limits struct {
+	MaxBytes int64
+	MaxLines int
+}
+
+// IsZero reports whether no limit is set.
+func (l Limits) IsZero() bool {
+	return l.MaxBytes <= 0 && l.MaxLines <= 0
+}
+
+// Cut returns the longest run of whole lines at the start of data that is
+// within the limits, and whether anything was cut. A first line longer than
+// MaxBytes is cut at the last whole character that fits.
+func Cut(data []byte, limits Limits) ([]byte, bool) {
+	head := data
+	if limits.MaxLines > 0 {
+		end, lines := 0, 0
+		for end < len(head) && lines < limits.MaxLines {
+			i := bytes.IndexByte(head[end:], '\n')
+			if i < 0 {
+				end = len(head)
+				break
+			}
+			end += i + 1
+			lines++
+		}
+		head = head[:end]
+	}
+	if limits.MaxBytes > 0 && int64(len(head)) > limits.MaxBytes {
+		head = head[:limits.MaxBytes]
+		if i := bytes.LastIndexByte(head, '\n'); i >= 0 {
+			head = head[:i+1]
+		} else {
+			for len(head) > 0 && !utf8.RuneStart(data[len(head)]) {
+				head = head[:len(head)-1]
+			}
+		}
+	}
+	return head, len(head) < len(data)
+}
+
+// comments maps file extensions, and a few well-known file names, to the
+// opening and closing delimiters of a comment in that language.
+var comments = map[string][2]string{}
+
+func init() {
+	styles := []struct {
+		open, close string
+		names       string
+	}{
+		{"// ", "", ".c .cc .cjs .cpp .cs .dart .go .gradle .groovy .h .hpp .java .js .jsonc .jsx .kt .kts .mjs .php .proto .rs .scala .swift .ts .tsx"},
+		{"# ", "", ".bash .cfg .conf .env .mk .pl .ps1 .py .r .rb .sh .tf .toml .yaml .yml .zsh Dockerfile Makefile .gitignore"},
+		{"-- ", "", ".elm .hs .lua .sql"},
+		{"; ", "", ".asm .clj .el .ini .lisp"},
+		{"% ", "", ".erl .tex"},
+		{"<!-- ", " -->", ".htm .html .md .mdx .svelte .svg .vue .xml"},
+		{"/* ", " */", ".css .less .scss"},
+	}
+	for _, s := range styles {
+		for _, name := range strings.Fields(s.names) {
+			comments[name] = [2]string{s.open, s.close}
+		}
+	}
+}
+
+// Marker returns the line appended to a truncated copy of the file at the
+// slash-separated name, written as a comment in the file's language. Files
+// whose language is unknown, or has no comments, get a bracketed note.
+func Marker(name string, kept, size int64) []byte {
+	text := fmt.Sprintf("repo-slice: truncated, showing the first %d of %d bytes", kept, size)
+	style, ok := comments[path.Base(name)]
+	if !ok {
+		style, ok = comments[strings.ToLower(path.Ext(name))]
+	}
+	if !ok {
+		style = [2]string{"[", "]"}
+	}
+	return []byte(style[0] + text + style[1] + "\n")
+}
+
+// Truncate returns data cut to the limits, followed by a marker for the file
+// at name, and whether it was cut at all.
+func Truncate(name string, data []byte, limits Limits) ([]byte, bool) {
+	head, cut := Cut(data, limits)
+	if !cut {
+		return data, false
+	}
+	out := append([]byte(nil), head...)
+	if len(out) > 0 && out[len(out)-1] != '\n' {
+		out = append(out, '\n')
+	}
+	return append(out, Marker(name, int64(len(head)), int64(len(data)))...), true
+}
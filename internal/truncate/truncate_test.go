@@ -0,0 +1,70 @@
+package truncate
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCut(t *testing.T) {
+	testCases := []struct {
+		name    string
+		data    string
+		limits  Limits
+		want    string
+		wantCut bool
+	}{
+		{"Within limits", "a\nb\n", Limits{MaxBytes: 4, MaxLines: 2}, "a\nb\n", false},
+		{"No limits", "a\nb\n", Limits{}, "a\nb\n", false},
+		{"Lines", "a\nb\nc\n", Limits{MaxLines: 2}, "a\nb\n", true},
+		{"Bytes end on a whole line", "one\ntwo\nthree\n", Limits{MaxBytes: 10}, "one\ntwo\n", true},
+		{"Bytes and lines", "one\ntwo\nthree\n", Limits{MaxBytes: 6, MaxLines: 2}, "one\n", true},
+		{"Long first line", "abcdef", Limits{MaxBytes: 4}, "abcd", true},
+		{"Whole characters", "aé☕", Limits{MaxBytes: 5}, "aé", true},
+		{"Last line without a newline", "a\nb", Limits{MaxLines: 2}, "a\nb", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, cut := Cut([]byte(tc.data), tc.limits)
+			if string(got) != tc.want || cut != tc.wantCut {
+				t.Errorf("Cut(%q) = %q, %v, want %q, %v", tc.data, got, cut, tc.want, tc.wantCut)
+			}
+		})
+	}
+}
+
+func TestMarker(t *testing.T) {
+	testCases := []struct {
+		name string
+		want string
+	}{
+		{"cmd/main.go", "// repo-slice: truncated, showing the first 10 of 99 bytes\n"},
+		{"scripts/build.PY", "# repo-slice: truncated, showing the first 10 of 99 bytes\n"},
+		{"Dockerfile", "# repo-slice: truncated, showing the first 10 of 99 bytes\n"},
+		{"docs/index.html", "<!-- repo-slice: truncated, showing the first 10 of 99 bytes -->\n"},
+		{"styles/app.css", "/* repo-slice: truncated, showing the first 10 of 99 bytes */\n"},
+		{"fixtures/data.json", "[repo-slice: truncated, showing the first 10 of 99 bytes]\n"},
+	}
+	for _, tc := range testCases {
+		if got := string(Marker(tc.name, 10, 99)); got != tc.want {
+			t.Errorf("Marker(%q) = %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	data := []byte("package main\n\nfunc main() {}\n")
+	got, cut := Truncate("main.go", data, Limits{MaxLines: 1})
+	if want := "package main\n// repo-slice: truncated, showing the first 13 of 29 bytes\n"; !cut || string(got) != want {
+		t.Errorf("Truncate() = %q, %v, want %q, true", got, cut, want)
+	}
+
+	got, _ = Truncate("notes", []byte(strings.Repeat("x", 10)), Limits{MaxBytes: 4})
+	if want := "xxxx\n[repo-slice: truncated, showing the first 4 of 10 bytes]\n"; string(got) != want {
+		t.Errorf("Truncate() of a long line = %q, want %q", got, want)
+	}
+
+	if got, cut := Truncate("main.go", data, Limits{}); cut || string(got) != string(data) {
+		t.Errorf("Truncate() without limits = %q, %v, want the data unchanged", got, cut)
+	}
+}
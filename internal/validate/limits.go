@@ -0,0 +1,114 @@
+// file: internal/validate/limits.go
+package validate
+
+import (
+	"fmt"
+	"io/fs"
+	"math"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// WalkFS defines the file system operations needed to inspect a finished
+// slice, allowing for mock implementations in tests.
+type WalkFS interface {
+	WalkDir(root string, fn fs.WalkDirFunc) error
+}
+
+// WalkDir walks the file tree rooted at root using the filepath package.
+func (l LiveFS) WalkDir(root string, fn fs.WalkDirFunc) error {
+	return filepath.WalkDir(root, fn)
+}
+
+// Limits bounds the contents of a slice. A zero value disables a limit.
+type Limits struct {
+	MaxFiles int
+	MaxSize  int64 // In bytes.
+}
+
+// ValidateSlice checks the slice written to dir against the limits, counting
+// every file that is not a directory and summing their sizes.
+func ValidateSlice(dir string, limits Limits, fsys WalkFS) error {
+	if limits.MaxFiles == 0 && limits.MaxSize == 0 {
+		return nil
+	}
+
+	var files int
+	var size int64
+	walkFn := func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		files++
+		size += info.Size()
+		return nil
+	}
+	if err := fsys.WalkDir(dir, walkFn); err != nil {
+		return fmt.Errorf("failed to inspect slice '%s': %w", dir, err)
+	}
+
+	if limits.MaxFiles > 0 && files > limits.MaxFiles {
+		return fmt.Errorf("file count (%d) exceeds the maximum allowed (%d)", files, limits.MaxFiles)
+	}
+	if limits.MaxSize > 0 && size > limits.MaxSize {
+		return fmt.Errorf("slice size (%d bytes) exceeds the maximum allowed (%d bytes)", size, limits.MaxSize)
+	}
+	return nil
+}
+
+// sizeUnits maps the accepted size suffixes to their multipliers. Single
+// letters and the SI-style "KB" forms are binary, matching the suffixes the
+// action has always accepted.
+var sizeUnits = map[string]float64{
+	"":    1,
+	"b":   1,
+	"k":   1 << 10,
+	"kb":  1 << 10,
+	"kib": 1 << 10,
+	"m":   1 << 20,
+	"mb":  1 << 20,
+	"mib": 1 << 20,
+	"g":   1 << 30,
+	"gb":  1 << 30,
+	"gib": 1 << 30,
+	"t":   1 << 40,
+	"tb":  1 << 40,
+	"tib": 1 << 40,
+}
+
+// ParseSize converts a human-readable size such as "100M", "1.5GiB" or
+// "4096" into bytes. Suffixes are case-insensitive.
+func ParseSize(s string) (int64, error) {
+	trimmed := strings.TrimSpace(s)
+	i := strings.IndexFunc(trimmed, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	if i < 0 {
+		i = len(trimmed)
+	}
+	number, unit := trimmed[:i], strings.ToLower(strings.TrimSpace(trimmed[i:]))
+
+	multiplier, ok := sizeUnits[unit]
+	if !ok {
+		return 0, fmt.Errorf("size '%s' has an unknown unit '%s'", s, trimmed[i:])
+	}
+	value, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return 0, fmt.Errorf("size '%s' is not a number followed by an optional unit", s)
+	}
+	// MaxInt64 is not representable as a float64 and rounds up to 2^63, which
+	// would overflow to a negative size, so the bound is exclusive.
+	bytes := value * multiplier
+	if math.IsNaN(bytes) || bytes >= 1<<63 {
+		return 0, fmt.Errorf("size '%s' is too large", s)
+	}
+	return int64(bytes), nil
+}
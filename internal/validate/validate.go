@@ -8,6 +8,8 @@ import (
 	"fmt"
 	"io/fs"
 	"os"
+
+	"github.com/AlienHeadwars/repo-slice/internal/filter"
 )
 
 // FS defines an interface for file system operations, allowing for mock
@@ -27,13 +29,20 @@ func (l LiveFS) Stat(name string) (fs.FileInfo, error) {
 
 // Config represents the input configuration that needs validation.
 type Config struct {
-	ManifestPath string
-	SourcePath   string
+	ManifestPath   string
+	SourcePath     string
+	ManifestFormat string // Optional; empty means the manifest header decides.
 }
 
-// ValidateInputs checks that the source and manifest paths are valid.
+// ValidateInputs checks that the source and manifest paths are valid and that
+// the manifest format, if given, is one the tool understands.
 // It depends on the FS interface, not a concrete file system.
 func ValidateInputs(cfg Config, fsys FS) error {
+	// Validate Manifest Format
+	if !filter.Format(cfg.ManifestFormat).Valid() {
+		return fmt.Errorf("manifest format '%s' is not recognised: must be %s or %s", cfg.ManifestFormat, filter.FormatRsync, filter.FormatGitignore)
+	}
+
 	// Validate Source Path
 	sourceInfo, err := fsys.Stat(cfg.SourcePath)
 	if err != nil {
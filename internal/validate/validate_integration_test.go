@@ -8,6 +8,7 @@ package validate
 
 import (
 	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -40,3 +41,24 @@ func TestLiveFSStat(t *testing.T) {
 		}
 	})
 }
+
+// TestValidateSliceLiveFS verifies that file sizes are summed from a real
+// directory tree.
+func TestValidateSliceLiveFS(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "pkg"), 0755); err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+	for _, name := range []string{"a.txt", filepath.Join("pkg", "b.txt")} {
+		if err := os.WriteFile(filepath.Join(dir, name), make([]byte, 600), 0644); err != nil {
+			t.Fatalf("failed to create %s: %v", name, err)
+		}
+	}
+
+	if err := ValidateSlice(dir, Limits{MaxFiles: 2, MaxSize: 1200}, LiveFS{}); err != nil {
+		t.Errorf("ValidateSlice() returned an unexpected error: %v", err)
+	}
+	if err := ValidateSlice(dir, Limits{MaxSize: 1024}, LiveFS{}); err == nil {
+		t.Error("ValidateSlice() did not report a slice over its size limit")
+	}
+}
@@ -2,6 +2,7 @@
 package validate
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/AlienHeadwars/repo-slice/internal/mocks"
@@ -37,6 +38,8 @@ func TestValidateInputs(t *testing.T) {
 		{"Source is a file", Config{SourcePath: sourceAsFile, ManifestPath: validManifest}, true},
 		{"Manifest does not exist", Config{SourcePath: validSource, ManifestPath: nonExistentTxt}, true},
 		{"Manifest is a directory", Config{SourcePath: validSource, ManifestPath: manifestAsDir}, true},
+		{"Gitignore manifest format", Config{SourcePath: validSource, ManifestPath: validManifest, ManifestFormat: "gitignore"}, false},
+		{"Unknown manifest format", Config{SourcePath: validSource, ManifestPath: validManifest, ManifestFormat: "ant"}, true},
 	}
 
 	for _, tc := range testCases {
@@ -48,3 +51,123 @@ func TestValidateInputs(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateSliceFileCount(t *testing.T) {
+	fsys := &mocks.MockFS{
+		Files: map[string]bool{
+			"out":          true,
+			"out/a.go":     false,
+			"out/b.go":     false,
+			"out/pkg":      true,
+			"out/pkg/c.go": false,
+		},
+	}
+
+	testCases := []struct {
+		name    string
+		limits  Limits
+		wantErr bool
+	}{
+		{"No limits", Limits{}, false},
+		{"Within the limit", Limits{MaxFiles: 3}, false},
+		{"Over the limit", Limits{MaxFiles: 2}, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateSlice("out", tc.limits, fsys)
+			if (err != nil) != tc.wantErr {
+				t.Errorf("ValidateSlice() error = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+
+	t.Run("Walk fails", func(t *testing.T) {
+		failing := &mocks.MockFS{Files: fsys.Files, WalkErr: errors.New("walk failed")}
+		if err := ValidateSlice("out", Limits{MaxFiles: 10}, failing); err == nil {
+			t.Error("ValidateSlice() did not return an error when the walk failed")
+		}
+	})
+}
+
+func TestValidateSliceSize(t *testing.T) {
+	fsys := &mocks.MockFS{
+		Files: map[string]bool{
+			"out":          true,
+			"out/a.go":     false,
+			"out/pkg":      true,
+			"out/pkg/b.go": false,
+		},
+		Sizes: map[string]int64{
+			"out":          4096, // Directory sizes are not counted.
+			"out/a.go":     600,
+			"out/pkg/b.go": 424,
+		},
+	}
+
+	testCases := []struct {
+		name    string
+		limits  Limits
+		wantErr string
+	}{
+		{"Exactly at the limit", Limits{MaxSize: 1024}, ""},
+		{"Over the limit", Limits{MaxSize: 1023}, "slice size (1024 bytes) exceeds the maximum allowed (1023 bytes)"},
+		{"Files checked first", Limits{MaxFiles: 1, MaxSize: 1}, "file count (2) exceeds the maximum allowed (1)"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateSlice("out", tc.limits, fsys)
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Errorf("ValidateSlice() returned an unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tc.wantErr {
+				t.Errorf("ValidateSlice() error = %v, want %q", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	testCases := []struct {
+		input   string
+		want    int64
+		wantErr bool
+	}{
+		{"4096", 4096, false},
+		{"512B", 512, false},
+		{"1K", 1024, false},
+		{"1k", 1024, false},
+		{"2KB", 2048, false},
+		{"1KiB", 1024, false},
+		{"100M", 100 << 20, false},
+		{"1.5MiB", 3 << 19, false},
+		{"2G", 2 << 30, false},
+		{" 1 TiB ", 1 << 40, false},
+		{"", 0, true},
+		{"M", 0, true},
+		{"10X", 0, true},
+		{"1.2.3K", 0, true},
+		{"-5M", 0, true},
+		{"99999999999T", 0, true},
+		{"8388607T", 8388607 << 40, false},
+		{"9223372036854774784", 1<<63 - 1024, false},
+		{"8388608T", 0, true},
+		{"9223372036854775807", 0, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			got, err := ParseSize(tc.input)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("ParseSize(%q) error = %v, wantErr %v", tc.input, err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("ParseSize(%q) = %d, want %d", tc.input, got, tc.want)
+			}
+		})
+	}
+}